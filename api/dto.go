@@ -22,13 +22,255 @@ type OrderListResponse struct {
 	UploadedAt string  `json:"uploaded_at"`
 }
 
+type OrderStatusChangeResponse struct {
+	At      string  `json:"at"`
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Accrual float64 `json:"accrual,omitempty"`
+	Attempt int     `json:"attempt"`
+}
+
+type OrderDetailResponse struct {
+	Number     string                      `json:"number"`
+	Status     string                      `json:"status"`
+	Accrual    float64                     `json:"accrual,omitempty"`
+	UploadedAt string                      `json:"uploaded_at"`
+	Attempts   int                         `json:"attempts"`
+	History    []OrderStatusChangeResponse `json:"history"`
+}
+
+// UserBalanceResponse shows the current balance split into the available
+// and the held points.
 type UserBalanceResponse struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current      float64                  `json:"current"`
+	Withdrawn    float64                  `json:"withdrawn"`
+	Available    float64                  `json:"available"`
+	Held         float64                  `json:"held"`
+	ExpiringSoon []ExpiringPointsResponse `json:"expiring_soon,omitempty"`
 }
 
+type ExpiringPointsResponse struct {
+	Sum       float64 `json:"sum"`
+	ExpiresAt string  `json:"expires_at"`
+}
+
+type WithdrawalHoldRequest struct {
+	Order string  `json:"order"`
+	Sum   float64 `json:"sum"`
+}
+
+type WithdrawalHoldResponse struct {
+	ID         string  `json:"id"`
+	Order      string  `json:"order"`
+	Sum        float64 `json:"sum"`
+	Status     string  `json:"status"`
+	CreatedAt  string  `json:"created_at"`
+	ExpiresAt  string  `json:"expires_at"`
+	ResolvedAt string  `json:"resolved_at,omitempty"`
+}
+
+// WithdrawalListResponse carries the refund fields only once a part of the
+// withdrawal was refunded.
 type WithdrawalListResponse struct {
-	Order       string  `json:"order"`
-	Sum         float64 `json:"sum"`
-	ProcessedAt string  `json:"processed_at"`
+	Order        string  `json:"order"`
+	Sum          float64 `json:"sum"`
+	ProcessedAt  string  `json:"processed_at"`
+	Refunded     float64 `json:"refunded,omitempty"`
+	RefundStatus string  `json:"refund_status,omitempty"`
+}
+
+type AdminWithdrawalResponse struct {
+	ID           string  `json:"id"`
+	UserID       string  `json:"user_id"`
+	Order        string  `json:"order"`
+	Sum          float64 `json:"sum"`
+	Refunded     float64 `json:"refunded"`
+	RefundStatus string  `json:"refund_status,omitempty"`
+	ProcessedAt  string  `json:"processed_at"`
+}
+
+// WithdrawalRefundRequest carries either the sum to refund or full to refund
+// all of the withdrawal not refunded yet.
+type WithdrawalRefundRequest struct {
+	Sum    *float64 `json:"sum,omitempty"`
+	Full   bool     `json:"full,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+type WithdrawalRefundResponse struct {
+	ID         string                  `json:"id"`
+	Sum        float64                 `json:"sum"`
+	Reason     string                  `json:"reason,omitempty"`
+	CreatedAt  string                  `json:"created_at"`
+	Withdrawal AdminWithdrawalResponse `json:"withdrawal"`
+}
+
+type PointsTransferRequest struct {
+	Recipient string  `json:"recipient"`
+	Sum       float64 `json:"sum"`
+	Note      string  `json:"note,omitempty"`
+}
+
+// PointsTransferResponse describes a transfer from the side of the user:
+// Counterparty is the login of the other side.
+type PointsTransferResponse struct {
+	ID           string  `json:"id"`
+	Direction    string  `json:"direction"`
+	Counterparty string  `json:"counterparty"`
+	Sum          float64 `json:"sum"`
+	Note         string  `json:"note,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// AccrualAdjustmentRequest carries the corrected accrual of an order.
+type AccrualAdjustmentRequest struct {
+	Accrual *float64 `json:"accrual"`
+}
+
+// AccrualAdjustmentResponse has a negative delta when the accrual was cut.
+type AccrualAdjustmentResponse struct {
+	Number  string  `json:"number"`
+	Accrual float64 `json:"accrual"`
+	Delta   float64 `json:"delta"`
+}
+
+type StaleOrderResponse struct {
+	Number     string `json:"number"`
+	UserID     string `json:"user_id"`
+	Status     string `json:"status"`
+	UploadedAt string `json:"uploaded_at"`
+	FlaggedAt  string `json:"flagged_at"`
+	Age        string `json:"age"`
+}
+
+type StaleOrdersReportResponse struct {
+	GeneratedAt string               `json:"generated_at"`
+	Total       int                  `json:"total"`
+	Expired     int                  `json:"expired"`
+	Orders      []StaleOrderResponse `json:"orders"`
+}
+
+type ImportJobLinks struct {
+	Self   string `json:"self"`
+	Errors string `json:"errors"`
+}
+
+type ImportJobResponse struct {
+	ID         string         `json:"id"`
+	Mode       string         `json:"mode"`
+	Status     string         `json:"status"`
+	Rows       int            `json:"rows"`
+	Imported   int            `json:"imported"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	CreatedAt  string         `json:"created_at"`
+	FinishedAt string         `json:"finished_at,omitempty"`
+	Links      ImportJobLinks `json:"links"`
+}
+
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"event_types"`
+}
+
+// WebhookSubscriptionResponse carries the secret only when the subscription
+// is created.
+type WebhookSubscriptionResponse struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+type WebhookAttemptResponse struct {
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int    `json:"duration_ms"`
+	At         string `json:"at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID            string                   `json:"id"`
+	EventID       string                   `json:"event_id"`
+	EventType     string                   `json:"event_type"`
+	Status        string                   `json:"status"`
+	Attempts      int                      `json:"attempts"`
+	NextAttemptAt string                   `json:"next_attempt_at,omitempty"`
+	DeliveredAt   string                   `json:"delivered_at,omitempty"`
+	CreatedAt     string                   `json:"created_at"`
+	Log           []WebhookAttemptResponse `json:"log"`
+}
+
+type HealthCheckResponse struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                `json:"status"`
+	Checks []HealthCheckResponse `json:"checks,omitempty"`
+}
+
+// Problem is an RFC 7807 error body. Code is stable and meant for clients to
+// branch on; Detail is for humans and may change.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type BulkUploadResult struct {
+	Number  string `json:"number"`
+	Outcome string `json:"outcome"`
+}
+
+// BulkUploadResponse lists the outcome of every uploaded number in request
+// order. Summary counts numbers per outcome.
+type BulkUploadResponse struct {
+	Summary map[string]int     `json:"summary"`
+	Results []BulkUploadResult `json:"results"`
+}
+
+// Envelope wraps every /api/v2 response body.
+type Envelope[T any] struct {
+	Data T         `json:"data"`
+	Meta *ListMeta `json:"meta,omitempty"`
+}
+
+type ListMeta struct {
+	Count int `json:"count"`
+}
+
+type OrderUploadRequest struct {
+	Number string `json:"number"`
+}
+
+type OrderLinks struct {
+	Self string `json:"self"`
+}
+
+type OrderResource struct {
+	Number     string     `json:"number"`
+	Status     string     `json:"status"`
+	Accrual    float64    `json:"accrual"`
+	UploadedAt string     `json:"uploaded_at"`
+	Links      OrderLinks `json:"links"`
+}
+
+// OrderUploadResult is the outcome for one order of a list upload: created,
+// exists or rejected. Rejected orders carry the error instead of the order.
+type OrderUploadResult struct {
+	Number  string         `json:"number"`
+	Outcome string         `json:"outcome"`
+	Order   *OrderResource `json:"order,omitempty"`
+	Error   *Problem       `json:"error,omitempty"`
 }
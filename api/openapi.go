@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OpenAPI is the OpenAPI 3 document describing every route of the HTTP API.
+// Keep it in sync with server.SetupMux and the DTOs in this package; the
+// server tests fail when they drift apart.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// DocsPage renders OpenAPI in the browser without external assets.
+//
+//go:embed docs.html
+var DocsPage []byte
+
+// LoadOpenAPI parses and validates OpenAPI.
+func LoadOpenAPI(ctx context.Context) (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(OpenAPI)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -6,14 +6,21 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/mrhyman/gophermart/internal/auth"
 	"github.com/mrhyman/gophermart/internal/client"
 	"github.com/mrhyman/gophermart/internal/config"
+	"github.com/mrhyman/gophermart/internal/events"
 	"github.com/mrhyman/gophermart/internal/handler"
+	"github.com/mrhyman/gophermart/internal/health"
 	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/metrics"
 	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/outbox"
 	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/scheduler"
 	"github.com/mrhyman/gophermart/internal/server"
 	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/mrhyman/gophermart/internal/tracing"
 	"github.com/mrhyman/gophermart/internal/worker"
 	"golang.org/x/sync/errgroup"
 )
@@ -23,27 +30,94 @@ func main() {
 	log := logger.New()
 	ctx = logger.WithinContext(ctx, log)
 
+	cfg := config.Load(ctx)
+
+	// The development logger above only covers configuration loading.
+	log, logCloser, err := logger.Build(cfg.LoggerOptions())
+	if err != nil {
+		logger.FromContext(ctx).With("err", err.Error()).Fatal("failed to set up logger")
+	}
+	if logCloser != nil {
+		defer logCloser.Close()
+	}
 	defer log.Sync()
 
-	cfg := config.Load(ctx)
+	logger.SetDefault(log)
+	ctx = logger.WithinContext(ctx, log)
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		log.With("err", err.Error()).Fatal("failed to set up tracing")
+	}
 
 	repos := initRepos(ctx, cfg)
 	defer repos.Close()
 
-	svc := service.New(repos)
-	h := handler.New(*svc, cfg.HashKey)
-	s := server.New(cfg, *h)
+	keyring, err := auth.NewKeyring(cfg.HashKey, cfg.AuthKeys...)
+	if err != nil {
+		log.With("err", err.Error()).Fatal("failed to create auth keyring")
+	}
 
-	accrualClient := client.NewAccrualClient(cfg.AccrualAddress)
+	accrualClient := client.NewAccrualClient(cfg.AccrualAddress, cfg.AccrualRequestTimeout, cfg.AccrualRateLimit)
 	w := worker.NewAccrualWorker(
 		repos.Order,
 		repos.User,
+		repos.Event,
 		accrualClient,
-		config.WorkerPollInterval,
-		config.WorkerBatchSize,
-		config.WorkerPoolSize,
+		cfg.WorkerPollInterval,
+		cfg.WorkerBatchSize,
+		cfg.WorkerPoolSize,
+		cfg.WorkerConcurrency,
+	)
+
+	dispatcher := worker.NewWebhookDispatcher(
+		repos.Webhook,
+		cfg.WebhookPollInterval,
+		cfg.WebhookTimeout,
+		worker.WebhookBatchSize,
+		cfg.WebhookMaxAttempts,
 	)
 
+	publisher, err := outbox.NewPublisher(cfg)
+	if err != nil {
+		log.With("err", err.Error()).Fatal("failed to set up events publisher")
+	}
+
+	checker := initHealth(ctx, repos, accrualClient, w)
+
+	metrics.RegisterDB(repos.DB())
+	metrics.Registry.MustRegister(metrics.NewBacklogCollector(
+		repos.Order,
+		model.OrderStatusNew,
+		model.OrderStatusProcessing,
+	))
+
+	broker := events.NewBroker(events.DefaultHistorySize)
+	listener := events.NewListener(cfg.DBURI, broker)
+
+	svc := service.New(repos, cfg)
+	h := handler.New(*svc, keyring, checker, broker)
+	s, err := server.New(cfg, *h, checker)
+	if err != nil {
+		log.With("err", err.Error()).Fatal("failed to set up HTTP server")
+	}
+	// Event streams never end on their own, close them once shutdown starts.
+	s.Instance.RegisterOnShutdown(broker.Close)
+	sweeper := worker.NewStaleOrderSweeper(
+		repos.Order,
+		cfg.StaleOrderAge,
+		cfg.ExpireStaleOrders,
+	)
+
+	// Runs even when POINTS_TTL is 0, lots credited under an earlier TTL still expire.
+	expirer := worker.NewPointsExpirer(repos.Balance, worker.PointsExpiryBatchSize)
+	holdExpirer := worker.NewHoldExpirer(repos.Balance, worker.HoldExpiryBatchSize)
+
+	sched := scheduler.New(repos.Lock, config.LeaderElectionInterval)
+	sched.Register("stale-order-sweeper", config.StaleSweepInterval, sweeper.Sweep)
+	sched.Register("points-expirer", config.PointsExpiryInterval, expirer.Expire)
+	sched.Register("withdrawal-hold-expirer", config.HoldExpiryInterval, holdExpirer.Expire)
+
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	defer stop()
 
@@ -53,10 +127,41 @@ func main() {
 		return w.Start(ctx)
 	})
 
+	g.Go(func() error {
+		return sched.Start(ctx)
+	})
+
+	g.Go(func() error {
+		return dispatcher.Start(ctx)
+	})
+
+	g.Go(func() error {
+		return listener.Start(ctx)
+	})
+
+	// Events stay in the outbox until a sink is configured.
+	if publisher != nil {
+		relay := outbox.NewRelay(repos.Outbox, publisher, config.EventsRelayInterval, outbox.DefaultBatchSize)
+		g.Go(func() error {
+			return relay.Start(ctx)
+		})
+	}
+
 	g.Go(func() error {
 		return s.Start(ctx)
 	})
 
+	if cfg.MetricsAddress != "" {
+		ms := server.NewMetrics(cfg)
+		g.Go(func() error {
+			return ms.Start(ctx)
+		})
+	}
+
+	g.Go(func() error {
+		return watchReload(ctx, cfg, w, accrualClient, keyring)
+	})
+
 	log.Info("Application started")
 
 	if err := g.Wait(); err != nil {
@@ -64,25 +169,29 @@ func main() {
 	}
 	log.Info("Shutting down gracefully...")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := s.Shutdown(shutdownCtx); err != nil {
 		log.With("err", err.Error()).Error(model.ErrServerCrash)
 	}
 
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.With("err", err.Error()).Error("failed to flush traces")
+	}
+
 	log.Info("Application stopped")
 }
 
 func initRepos(ctx context.Context, cfg config.AppConfig) *repository.Repos {
 	log := logger.FromContext(ctx)
 
-	repos, err := repository.NewRepos(cfg.DBURI)
+	repos, err := repository.NewRepos(cfg.DBURI, cfg.DBMaxOpenConns, cfg.PointsTTL)
 	if err != nil {
 		log.With("err", err.Error()).Fatal("failed to create repos")
 	}
 
-	err = repos.MigrateUp("migrations", cfg.DBURI)
+	err = repos.MigrateUp(config.MigrationsDir, cfg.DBURI)
 	if err != nil {
 		log.With("err", err.Error()).Fatal("failed to apply migrations")
 	}
@@ -90,3 +199,27 @@ func initRepos(ctx context.Context, cfg config.AppConfig) *repository.Repos {
 
 	return repos
 }
+
+func initHealth(
+	ctx context.Context,
+	repos *repository.Repos,
+	accrualClient *client.AccrualClient,
+	w *worker.AccrualWorker,
+) *health.Checker {
+	log := logger.FromContext(ctx)
+
+	expected, err := repository.LatestMigration(config.MigrationsDir)
+	if err != nil {
+		log.With("err", err.Error()).Fatal("failed to read migrations")
+	}
+
+	checker := health.New(config.HealthCheckTimeout)
+	checker.Register("database", true, health.Ping(repos))
+	checker.Register("migrations", true, health.Migrations(repos, expected))
+	// the API keeps working without the accrual system or a stalled worker,
+	// orders just wait longer
+	checker.Register("accrual_circuit", false, health.Circuit(accrualClient))
+	checker.Register("accrual_worker", false, health.Heartbeat(w, config.WorkerHeartbeatTimeout))
+
+	return checker
+}
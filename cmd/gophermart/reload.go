@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mrhyman/gophermart/internal/auth"
+	"github.com/mrhyman/gophermart/internal/client"
+	"github.com/mrhyman/gophermart/internal/config"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/worker"
+)
+
+// watchReload re-reads the configuration on SIGHUP and applies the settings
+// that can change at runtime. An invalid configuration is rejected as a whole
+// and the running one is kept.
+func watchReload(
+	ctx context.Context,
+	cfg config.AppConfig,
+	w *worker.AccrualWorker,
+	accrualClient *client.AccrualClient,
+	keyring *auth.Keyring,
+) error {
+	log := logger.FromContext(ctx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+		}
+
+		log.Info("reloading configuration")
+
+		next, err := config.Reread()
+		if err != nil {
+			log.With("err", err.Error()).Error("configuration reload rejected")
+			continue
+		}
+
+		merged, restart := cfg.Reload(next)
+		if len(restart) > 0 {
+			log.With("settings", restart).Warn("changed settings require a restart and were not applied")
+		}
+
+		if err := keyring.SetKeys(merged.HashKey, merged.AuthKeys...); err != nil {
+			log.With("err", err.Error()).Error("configuration reload rejected")
+			continue
+		}
+
+		if err := logger.SetLevel(merged.LogLevel); err != nil {
+			log.With("err", err.Error()).Error("failed to set log level")
+		}
+		accrualClient.Reconfigure(merged.AccrualAddress, merged.AccrualRequestTimeout, merged.AccrualRateLimit)
+		w.Reconfigure(
+			merged.WorkerPollInterval,
+			merged.WorkerBatchSize,
+			merged.WorkerPoolSize,
+			merged.WorkerConcurrency,
+		)
+
+		cfg = merged
+		log.With(cfg.Fields()...).Info("configuration reloaded")
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// Keyring encodes auth cookies with the primary key and accepts cookies
+// encoded with any of its keys, which allows rotating keys without logging
+// users out. Keys can be replaced at runtime.
+type Keyring struct {
+	encoders atomic.Pointer[[]*CookieEncoder]
+}
+
+func NewKeyring(primary string, extra ...string) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.SetKeys(primary, extra...); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// SetKeys atomically replaces all keys. On error the old keys stay in place.
+func (k *Keyring) SetKeys(primary string, extra ...string) error {
+	encoders := make([]*CookieEncoder, 0, len(extra)+1)
+
+	for _, key := range append([]string{primary}, extra...) {
+		ce, err := NewCookieEncoder(key)
+		if err != nil {
+			return err
+		}
+		encoders = append(encoders, ce)
+	}
+
+	k.encoders.Store(&encoders)
+	return nil
+}
+
+func (k *Keyring) EncodeUserID(userID string) (string, error) {
+	return (*k.encoders.Load())[0].EncodeUserID(userID)
+}
+
+func (k *Keyring) DecodeUserID(c *http.Cookie) (string, error) {
+	var errs []error
+
+	for _, ce := range *k.encoders.Load() {
+		userID, err := ce.DecodeUserID(c)
+		if err == nil {
+			return userID, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return "", errors.Join(errs...)
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	t.Run("encode with primary and decode", func(t *testing.T) {
+		k, err := NewKeyring("primary")
+		require.NoError(t, err)
+
+		encoded, err := k.EncodeUserID("user-1")
+		require.NoError(t, err)
+
+		userID, err := k.DecodeUserID(&http.Cookie{Value: encoded})
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+	})
+
+	t.Run("decode cookie encoded with extra key", func(t *testing.T) {
+		old, err := NewCookieEncoder("old-key")
+		require.NoError(t, err)
+		encoded, err := old.EncodeUserID("user-2")
+		require.NoError(t, err)
+
+		k, err := NewKeyring("new-key", "old-key")
+		require.NoError(t, err)
+
+		userID, err := k.DecodeUserID(&http.Cookie{Value: encoded})
+		require.NoError(t, err)
+		assert.Equal(t, "user-2", userID)
+	})
+
+	t.Run("reject cookie encoded with unknown key", func(t *testing.T) {
+		other, err := NewCookieEncoder("other-key")
+		require.NoError(t, err)
+		encoded, err := other.EncodeUserID("user-3")
+		require.NoError(t, err)
+
+		k, err := NewKeyring("primary", "old-key")
+		require.NoError(t, err)
+
+		_, err = k.DecodeUserID(&http.Cookie{Value: encoded})
+		assert.Error(t, err)
+	})
+
+	t.Run("keys added at runtime are accepted", func(t *testing.T) {
+		added, err := NewCookieEncoder("added-key")
+		require.NoError(t, err)
+		encoded, err := added.EncodeUserID("user-4")
+		require.NoError(t, err)
+
+		k, err := NewKeyring("primary")
+		require.NoError(t, err)
+
+		_, err = k.DecodeUserID(&http.Cookie{Value: encoded})
+		require.Error(t, err)
+
+		require.NoError(t, k.SetKeys("primary", "added-key"))
+
+		userID, err := k.DecodeUserID(&http.Cookie{Value: encoded})
+		require.NoError(t, err)
+		assert.Equal(t, "user-4", userID)
+	})
+}
@@ -3,36 +3,141 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mrhyman/gophermart/api"
-	"github.com/mrhyman/gophermart/internal/config"
 	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/metrics"
 	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
+// defaultRetryAfter is used when a 429 response carries no usable Retry-After header.
+const defaultRetryAfter = 60 * time.Second
+
+// maxRetryAfter caps the pause a 429 response can impose, so a misbehaving
+// accrual system cannot stall the worker for long.
+const maxRetryAfter = 60 * time.Second
+
+// AccrualClient is safe for concurrent use. All callers share one rate
+// limiter, so the request rate does not grow with the number of workers.
 type AccrualClient struct {
+	mu         sync.RWMutex
 	baseURL    string
+	timeout    time.Duration
 	httpClient *http.Client
+	limiter    *rate.Limiter
+	breaker    *breaker
+	// retryAt holds the unix nano time until which requests are paused after a 429.
+	retryAt atomic.Int64
+}
+
+// NewAccrualClient creates a client limited to rps requests per second.
+// Non-positive rps disables the limit.
+func NewAccrualClient(baseURL string, timeout time.Duration, rps int) *AccrualClient {
+	c := &AccrualClient{
+		// the transport starts a client span and propagates the trace context
+		httpClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		limiter:    rate.NewLimiter(rate.Inf, 1),
+		breaker:    newBreaker(defaultFailureThreshold, defaultOpenTimeout),
+	}
+	c.Reconfigure(baseURL, timeout, rps)
+
+	return c
 }
 
-func NewAccrualClient(baseURL string) *AccrualClient {
-	return &AccrualClient{
-		baseURL: normalizeBaseURL(baseURL),
-		httpClient: &http.Client{
-			Timeout: config.AccuralRequestTimeout,
-		},
+// Reconfigure changes the accrual system address, request timeout and rate
+// limit. Requests already in flight finish with the old settings.
+func (c *AccrualClient) Reconfigure(baseURL string, timeout time.Duration, rps int) {
+	limit := rate.Inf
+	if rps > 0 {
+		limit = rate.Limit(rps)
 	}
+
+	c.mu.Lock()
+	c.baseURL = normalizeBaseURL(baseURL)
+	c.timeout = timeout
+	c.mu.Unlock()
+
+	c.limiter.SetLimit(limit)
+	c.limiter.SetBurst(max(rps, 1))
+}
+
+// CircuitState reports the state of the circuit breaker guarding the accrual system.
+func (c *AccrualClient) CircuitState() CircuitState {
+	return c.breaker.state()
+}
+
+// Ready reports whether a request could be sent now: not during a pause
+// requested via Retry-After and not while the circuit is open. A half-open
+// circuit is ready for its trial request.
+func (c *AccrualClient) Ready() bool {
+	return time.Now().UnixNano() >= c.retryAt.Load() && c.breaker.state() != CircuitOpen
 }
 
 func (c *AccrualClient) GetOrderAccrual(ctx context.Context, orderNumber string) (*api.AccrualResponse, error) {
+	ctx, span := tracing.Start(ctx, "AccrualClient.GetOrderAccrual", trace.WithAttributes(
+		attribute.String("order.number", orderNumber),
+	))
+	defer span.End()
+
+	log := logger.FromContext(ctx)
+
+	if err := c.wait(ctx); err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	if !c.breaker.allow() {
+		metrics.CountAccrualCircuitOpen()
+		tracing.Fail(span, model.ErrAccrualCircuitOpen)
+		return nil, model.ErrAccrualCircuitOpen
+	}
+
+	resp, err := c.getOrderAccrual(ctx, orderNumber)
+	switch {
+	case err == nil, errors.Is(err, model.ErrOrderNotRegistered), errors.Is(err, model.ErrAccrualTooManyRequests):
+		c.breaker.success()
+	case ctx.Err() != nil:
+		// the caller gave up, this says nothing about the accrual system
+		c.breaker.release()
+	default:
+		if c.breaker.failure() {
+			log.With("err", err.Error()).Warn("accrual circuit opened")
+		}
+	}
+
+	if !errors.Is(err, model.ErrOrderNotRegistered) {
+		tracing.Fail(span, err)
+	}
+
+	return resp, err
+}
+
+func (c *AccrualClient) getOrderAccrual(ctx context.Context, orderNumber string) (*api.AccrualResponse, error) {
 	log := logger.FromContext(ctx)
 
-	url := fmt.Sprintf("%s/api/orders/%s", c.baseURL, orderNumber)
+	c.mu.RLock()
+	baseURL, timeout := c.baseURL, c.timeout
+	c.mu.RUnlock()
 
-	log.With("url", url).Warn()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/api/orders/%s", baseURL, orderNumber)
+
+	log.With("order", orderNumber).Debug("requesting accrual")
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -40,13 +145,17 @@ func (c *AccrualClient) GetOrderAccrual(ctx context.Context, orderNumber string)
 		return nil, err
 	}
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		metrics.ObserveAccrualRequest("error", time.Since(start))
 		log.With("err", model.ErrAccrualRequestSendFailed.Error()).Error()
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	metrics.ObserveAccrualRequest(strconv.Itoa(resp.StatusCode), time.Since(start))
+
 	switch resp.StatusCode {
 	case http.StatusOK:
 		var accrualResp api.AccrualResponse
@@ -60,7 +169,9 @@ func (c *AccrualClient) GetOrderAccrual(ctx context.Context, orderNumber string)
 		return nil, model.ErrOrderNotRegistered
 
 	case http.StatusTooManyRequests:
-		log.With("err", model.ErrAccrualTooManyRequests.Error()).Warn()
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		c.pause(retryAfter)
+		log.With("err", model.ErrAccrualTooManyRequests.Error(), "retry_after", retryAfter.String()).Warn()
 		return nil, model.ErrAccrualTooManyRequests
 
 	case http.StatusInternalServerError:
@@ -71,6 +182,42 @@ func (c *AccrualClient) GetOrderAccrual(ctx context.Context, orderNumber string)
 	}
 }
 
+// wait blocks until the shared limiter allows a request and any pause
+// requested by the accrual system via Retry-After is over.
+func (c *AccrualClient) wait(ctx context.Context) error {
+	if d := time.Until(time.Unix(0, c.retryAt.Load())); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return c.limiter.Wait(ctx)
+}
+
+func (c *AccrualClient) pause(d time.Duration) {
+	until := time.Now().Add(d).UnixNano()
+	for {
+		current := c.retryAt.Load()
+		if current >= until || c.retryAt.CompareAndSwap(current, until) {
+			return
+		}
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return defaultRetryAfter
+	}
+
+	return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+}
+
 func normalizeBaseURL(baseURL string) string {
 	if !strings.Contains(baseURL, "://") {
 		baseURL = "http://" + baseURL
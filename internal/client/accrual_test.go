@@ -4,11 +4,17 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestAccrualClient_GetOrderAccrual_Success(t *testing.T) {
@@ -21,14 +27,14 @@ func TestAccrualClient_GetOrderAccrual_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewAccrualClient(server.URL)
+	client := NewAccrualClient(server.URL, time.Second, 0)
 
 	resp, err := client.GetOrderAccrual(context.Background(), "12345")
 
 	require.NoError(t, err)
 	assert.Equal(t, "12345", resp.Order)
 	assert.Equal(t, model.AccrualStatusProcessed, resp.Status)
-	assert.Equal(t, 500, *resp.Accrual)
+	assert.Equal(t, 500.0, *resp.Accrual)
 }
 
 func TestAccrualClient_GetOrderAccrual_OrderNotRegistered(t *testing.T) {
@@ -37,7 +43,7 @@ func TestAccrualClient_GetOrderAccrual_OrderNotRegistered(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewAccrualClient(server.URL)
+	client := NewAccrualClient(server.URL, time.Second, 0)
 
 	resp, err := client.GetOrderAccrual(context.Background(), "99999")
 
@@ -52,7 +58,7 @@ func TestAccrualClient_GetOrderAccrual_TooManyRequests(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewAccrualClient(server.URL)
+	client := NewAccrualClient(server.URL, time.Second, 0)
 
 	resp, err := client.GetOrderAccrual(context.Background(), "12345")
 
@@ -66,7 +72,7 @@ func TestAccrualClient_GetOrderAccrual_InternalError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewAccrualClient(server.URL)
+	client := NewAccrualClient(server.URL, time.Second, 0)
 
 	resp, err := client.GetOrderAccrual(context.Background(), "12345")
 
@@ -81,7 +87,7 @@ func TestAccrualClient_GetOrderAccrual_InvalidJSON(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewAccrualClient(server.URL)
+	client := NewAccrualClient(server.URL, time.Second, 0)
 
 	resp, err := client.GetOrderAccrual(context.Background(), "12345")
 
@@ -108,3 +114,142 @@ func TestNormalizeBaseURL(t *testing.T) {
 		})
 	}
 }
+
+func TestAccrualClient_GetOrderAccrual_RetryAfterPausesRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewAccrualClient(server.URL, time.Second, 0)
+	require.True(t, client.Ready())
+
+	_, err := client.GetOrderAccrual(context.Background(), "12345")
+	require.ErrorIs(t, err, model.ErrAccrualTooManyRequests)
+	assert.False(t, client.Ready(), "not ready during the pause")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = client.GetOrderAccrual(ctx, "12345")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestAccrualClient_GetOrderAccrual_RateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewAccrualClient(server.URL, time.Second, 20)
+
+	start := time.Now()
+	for range 25 {
+		_, err := client.GetOrderAccrual(context.Background(), "12345")
+		require.ErrorIs(t, err, model.ErrOrderNotRegistered)
+	}
+
+	// 20 requests fit into the initial burst, 5 more need ~250ms at 20 rps.
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30"))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter(""))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("soon"))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("-1"))
+	assert.Equal(t, maxRetryAfter, parseRetryAfter("300"))
+}
+
+func TestAccrualClient_Reconfigure(t *testing.T) {
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer first.Close()
+
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer second.Close()
+
+	client := NewAccrualClient(first.URL, time.Second, 0)
+
+	_, err := client.GetOrderAccrual(context.Background(), "12345")
+	require.ErrorIs(t, err, model.ErrOrderNotRegistered)
+
+	client.Reconfigure(second.URL, 10*time.Millisecond, 0)
+
+	_, err = client.GetOrderAccrual(context.Background(), "12345")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAccrualClient_CircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewAccrualClient(server.URL, time.Second, 0)
+
+	for range defaultFailureThreshold {
+		_, err := client.GetOrderAccrual(context.Background(), "12345")
+		assert.ErrorIs(t, err, model.ErrAccrualInternalError)
+	}
+	assert.Equal(t, CircuitOpen, client.CircuitState())
+	assert.False(t, client.Ready(), "not ready while the circuit is open")
+
+	_, err := client.GetOrderAccrual(context.Background(), "12345")
+	assert.ErrorIs(t, err, model.ErrAccrualCircuitOpen)
+	assert.Equal(t, int32(defaultFailureThreshold), calls.Load())
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.failure())
+	assert.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, b.state())
+	assert.True(t, b.allow(), "one trial request is let through")
+	assert.False(t, b.allow())
+
+	b.success()
+	assert.Equal(t, CircuitClosed, b.state())
+	assert.True(t, b.allow())
+}
+
+func TestAccrualClient_PropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	tp := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewAccrualClient(server.URL, time.Second, 0)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	_, err := client.GetOrderAccrual(ctx, "12345")
+	span.End()
+
+	assert.ErrorIs(t, err, model.ErrOrderNotRegistered)
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState describes whether requests to the accrual system are allowed.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+const (
+	// defaultFailureThreshold is the number of consecutive failures that opens the circuit.
+	defaultFailureThreshold = 5
+	// defaultOpenTimeout is how long the circuit stays open before a trial request is let through.
+	defaultOpenTimeout = 30 * time.Second
+)
+
+// breaker is a consecutive failure circuit breaker. While open, requests fail
+// fast; after openTimeout a single trial request decides whether to close it.
+type breaker struct {
+	mu          sync.Mutex
+	threshold   int
+	openTimeout time.Duration
+	failures    int
+	openedAt    time.Time
+	trial       bool
+	now         func() time.Time
+}
+
+func newBreaker(threshold int, openTimeout time.Duration) *breaker {
+	return &breaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		now:         time.Now,
+	}
+}
+
+// allow reports whether a request may be sent.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.stateLocked() {
+	case CircuitClosed:
+		return true
+	case CircuitHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	b.openedAt = time.Time{}
+}
+
+// release gives back a trial request that ended without a verdict.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// failure records a failed request and reports whether it opened the circuit.
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := !b.openedAt.IsZero()
+	b.failures++
+	b.trial = false
+
+	if wasOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		return !wasOpen
+	}
+
+	return false
+}
+
+func (b *breaker) state() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stateLocked()
+}
+
+func (b *breaker) stateLocked() CircuitState {
+	switch {
+	case b.openedAt.IsZero():
+		return CircuitClosed
+	case b.now().Sub(b.openedAt) >= b.openTimeout:
+		return CircuitHalfOpen
+	default:
+		return CircuitOpen
+	}
+}
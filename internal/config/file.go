@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile overlays settings from a YAML or JSON file onto cfg. Keys missing
+// from the file keep their current values, unknown keys are rejected.
+func loadFile(path string, cfg *AppConfig) error {
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q, use .yaml, .yml or .json", path, ext)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	// JSON is a subset of YAML, so one decoder serves both formats.
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import "slices"
+
+// Reload merges a freshly parsed configuration into the running one. Only
+// settings that are safe to change at runtime are taken from next; the names
+// of other settings that differ are returned since they need a restart.
+func (c AppConfig) Reload(next AppConfig) (AppConfig, []string) {
+	var restart []string
+
+	check := func(name string, changed bool) {
+		if changed {
+			restart = append(restart, name)
+		}
+	}
+
+	check("run_address", c.RunAddress != next.RunAddress)
+	check("metrics_address", c.MetricsAddress != next.MetricsAddress)
+	check("database_uri", c.DBURI != next.DBURI)
+	check("hash_key", c.HashKey != next.HashKey)
+	check("admin_token", c.AdminToken != next.AdminToken)
+	check("stale_order_age", c.StaleOrderAge != next.StaleOrderAge)
+	check("expire_stale_orders", c.ExpireStaleOrders != next.ExpireStaleOrders)
+	check("shutdown_timeout", c.ShutdownTimeout != next.ShutdownTimeout)
+	check("shutdown_drain_delay", c.ShutdownDrainDelay != next.ShutdownDrainDelay)
+	check("db_max_open_conns", c.DBMaxOpenConns != next.DBMaxOpenConns)
+	check("openapi_validation", c.OpenAPIValidation != next.OpenAPIValidation)
+	check("log_format", c.LogFormat != next.LogFormat)
+	check("log_sampling", c.LogSampling != next.LogSampling)
+	check("log_output", c.LogOutput != next.LogOutput)
+	check("log_max_size_mb", c.LogMaxSizeMB != next.LogMaxSizeMB)
+	check("log_max_backups", c.LogMaxBackups != next.LogMaxBackups)
+	check("log_max_age_days", c.LogMaxAgeDays != next.LogMaxAgeDays)
+	check("trace_exporter", c.TraceExporter != next.TraceExporter)
+	check("trace_endpoint", c.TraceEndpoint != next.TraceEndpoint)
+	check("trace_file", c.TraceFile != next.TraceFile)
+	check("trace_sample_ratio", c.TraceSampleRatio != next.TraceSampleRatio)
+	check("webhook_poll_interval", c.WebhookPollInterval != next.WebhookPollInterval)
+	check("webhook_timeout", c.WebhookTimeout != next.WebhookTimeout)
+	check("webhook_max_attempts", c.WebhookMaxAttempts != next.WebhookMaxAttempts)
+	check("events_sink", c.EventsSink != next.EventsSink)
+	check("events_file", c.EventsFile != next.EventsFile)
+	check("events_url", c.EventsURL != next.EventsURL)
+	check("events_topic", c.EventsTopic != next.EventsTopic)
+	check("points_ttl", c.PointsTTL != next.PointsTTL)
+	check("withdrawal_hold_ttl", c.WithdrawalHoldTTL != next.WithdrawalHoldTTL)
+	check("transfer_daily_limit", c.TransferDailyLimit != next.TransferDailyLimit)
+
+	c.LogLevel = next.LogLevel
+	c.AuthKeys = slices.Clone(next.AuthKeys)
+	c.AccrualAddress = next.AccrualAddress
+	c.AccrualRequestTimeout = next.AccrualRequestTimeout
+	c.AccrualRateLimit = next.AccrualRateLimit
+	c.WorkerConcurrency = next.WorkerConcurrency
+	c.WorkerPollInterval = next.WorkerPollInterval
+	c.WorkerBatchSize = next.WorkerBatchSize
+	c.WorkerPoolSize = next.WorkerPoolSize
+
+	return c, restart
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppConfig_Reload(t *testing.T) {
+	t.Run("applies runtime settings", func(t *testing.T) {
+		cur := Default()
+		next := Default()
+		next.LogLevel = "warn"
+		next.AuthKeys = []string{"old-key"}
+		next.AccrualAddress = "http://accrual:9090"
+		next.AccrualRateLimit = 50
+		next.WorkerPollInterval = 5 * time.Second
+		next.WorkerPoolSize = 8
+
+		merged, restart := cur.Reload(next)
+
+		assert.Empty(t, restart)
+		assert.Equal(t, next, merged)
+	})
+
+	t.Run("keeps restart only settings and reports them", func(t *testing.T) {
+		cur := Default()
+		next := Default()
+		next.RunAddress = "localhost:9999"
+		next.HashKey = "new-key"
+		next.WorkerBatchSize = 50
+
+		merged, restart := cur.Reload(next)
+
+		assert.ElementsMatch(t, []string{"run_address", "hash_key"}, restart)
+		assert.Equal(t, cur.RunAddress, merged.RunAddress)
+		assert.Equal(t, cur.HashKey, merged.HashKey)
+		assert.Equal(t, 50, merged.WorkerBatchSize)
+	})
+}
@@ -0,0 +1,159 @@
+// Package events streams order and balance changes to connected users.
+//
+// Changes are published with Postgres NOTIFY (see repository.EventRepository)
+// and every replica feeds them from its Listener into its in-process Broker,
+// which fans them out to the user's open streams.
+package events
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// subscriberBuffer bounds the events queued for one stream. A client that
+// falls further behind is disconnected and resumes with Last-Event-ID.
+const subscriberBuffer = 64
+
+// DefaultHistorySize is the number of recent events kept for resuming streams.
+const DefaultHistorySize = 1024
+
+// Subscription is one open stream of a user. Events is closed when the
+// stream is dropped by the broker.
+type Subscription struct {
+	userID uuid.UUID
+	events chan model.Event
+}
+
+func (s *Subscription) Events() <-chan model.Event {
+	return s.events
+}
+
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[uuid.UUID]map[*Subscription]struct{}
+	closed bool
+
+	// history holds the latest events of all users in delivery order, which
+	// is the same on every replica.
+	history     []model.Event
+	historySize int
+}
+
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		subs:        make(map[uuid.UUID]map[*Subscription]struct{}),
+		historySize: historySize,
+	}
+}
+
+// Subscribe opens a stream for the user. If lastEventID is set, the user's
+// events published after it are returned for replay. ok is false when
+// lastEventID is no longer in the history, in which case events may have
+// been missed and the client has to reload its state.
+func (b *Broker) Subscribe(userID uuid.UUID, lastEventID int64) (sub *Subscription, replay []model.Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{
+		userID: userID,
+		events: make(chan model.Event, subscriberBuffer),
+	}
+
+	if b.closed {
+		close(sub.events)
+		return sub, nil, true
+	}
+
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*Subscription]struct{})
+	}
+	b.subs[userID][sub] = struct{}{}
+
+	if lastEventID == 0 {
+		return sub, nil, true
+	}
+
+	for i := len(b.history) - 1; i >= 0; i-- {
+		if b.history[i].ID != lastEventID {
+			continue
+		}
+		for _, e := range b.history[i+1:] {
+			if e.UserID == userID {
+				replay = append(replay, e)
+			}
+		}
+		return sub, replay, true
+	}
+
+	return sub, nil, false
+}
+
+// Unsubscribe closes the stream unless the broker already dropped it.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.drop(sub)
+}
+
+// Publish records the event and delivers it to the user's streams without
+// blocking. Streams that can not keep up are dropped.
+func (b *Broker) Publish(e model.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	if len(b.history) >= b.historySize {
+		b.history = b.history[1:]
+	}
+	b.history = append(b.history, e)
+
+	for sub := range b.subs[e.UserID] {
+		select {
+		case sub.events <- e:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Reset forgets the history, e.g. after notifications may have been lost.
+// Streams resuming from an earlier event are then told to reload.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history = nil
+}
+
+// Close drops every stream, so that handlers return and the server can shut
+// down. Streams opened afterwards are closed straight away.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.drop(sub)
+		}
+	}
+}
+
+func (b *Broker) drop(sub *Subscription) {
+	subs := b.subs[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subs, sub.userID)
+	}
+	close(sub.events)
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(id int64, userID uuid.UUID) model.Event {
+	return model.Event{ID: id, UserID: userID, Type: model.EventBalance, Data: json.RawMessage(`{}`)}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	b := NewBroker(10)
+
+	sub, _, _ := b.Subscribe(alice, 0)
+	defer b.Unsubscribe(sub)
+
+	b.Publish(event(1, bob))
+	b.Publish(event(2, alice))
+
+	got := <-sub.Events()
+	assert.Equal(t, int64(2), got.ID)
+	assert.Empty(t, sub.Events(), "events of other users must not be delivered")
+}
+
+func TestBroker_Resume(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	b := NewBroker(3)
+
+	b.Publish(event(5, alice))
+	b.Publish(event(7, bob))
+	b.Publish(event(6, alice))
+
+	t.Run("replays events after the last seen one", func(t *testing.T) {
+		sub, replay, ok := b.Subscribe(alice, 5)
+		defer b.Unsubscribe(sub)
+
+		require.True(t, ok)
+		require.Len(t, replay, 1)
+		assert.Equal(t, int64(6), replay[0].ID, "delivery order wins over ID order")
+	})
+
+	t.Run("evicted event asks for a resync", func(t *testing.T) {
+		b.Publish(event(8, alice))
+
+		sub, replay, ok := b.Subscribe(alice, 5)
+		defer b.Unsubscribe(sub)
+
+		assert.False(t, ok)
+		assert.Empty(t, replay)
+	})
+
+	t.Run("reset forgets the history", func(t *testing.T) {
+		b.Reset()
+
+		sub, _, ok := b.Subscribe(alice, 8)
+		defer b.Unsubscribe(sub)
+
+		assert.False(t, ok)
+	})
+}
+
+func TestBroker_SlowSubscriberIsDropped(t *testing.T) {
+	alice := uuid.New()
+	b := NewBroker(DefaultHistorySize)
+
+	sub, _, _ := b.Subscribe(alice, 0)
+	for i := range subscriberBuffer + 1 {
+		b.Publish(event(int64(i+1), alice))
+	}
+
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+
+	// Unsubscribing a dropped stream is a no-op.
+	b.Unsubscribe(sub)
+}
+
+func TestBroker_Close(t *testing.T) {
+	alice := uuid.New()
+	b := NewBroker(DefaultHistorySize)
+
+	sub, _, _ := b.Subscribe(alice, 0)
+	b.Close()
+
+	_, open := <-sub.Events()
+	assert.False(t, open)
+
+	late, _, _ := b.Subscribe(alice, 0)
+	_, open = <-late.Events()
+	assert.False(t, open)
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository"
+)
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	// pingInterval detects a dead connection when no notifications arrive.
+	pingInterval = 90 * time.Second
+)
+
+// Listener feeds the events published by any replica into the broker.
+type Listener struct {
+	dsn    string
+	broker *Broker
+}
+
+func NewListener(dsn string, broker *Broker) *Listener {
+	return &Listener{dsn: dsn, broker: broker}
+}
+
+// Start listens until ctx is done. The connection is re-established on
+// failure; since notifications sent meanwhile are lost, the broker history
+// is reset so resuming streams reload their state.
+func (l *Listener) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	pl := pq.NewListener(l.dsn, minReconnectInterval, maxReconnectInterval, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.With("err", err.Error()).Warn("event listener connection failed")
+		}
+	})
+	defer pl.Close()
+
+	if err := pl.Listen(repository.EventsChannel); err != nil {
+		return err
+	}
+
+	log.Info("event listener started")
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("event listener stopped")
+			return nil
+
+		case n := <-pl.Notify:
+			if n == nil {
+				log.Info("event listener reconnected")
+				l.broker.Reset()
+				continue
+			}
+
+			var e model.Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.With("err", err.Error()).Warn("malformed event")
+				continue
+			}
+			l.broker.Publish(e)
+
+		case <-ticker.C:
+			go pl.Ping()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/service"
+)
+
+type AdminHandler struct {
+	as *service.AdminService
+	ws *service.WebhookService
+	bs *service.BalanceService
+	os *service.OrderService
+}
+
+func NewAdminHandler(svc *service.Service) *AdminHandler {
+	return &AdminHandler{
+		as: svc.Admin,
+		ws: svc.Webhook,
+		bs: svc.Balance,
+		os: svc.Order,
+	}
+}
+
+func (h *AdminHandler) GetStaleOrders(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
+		return
+	}
+
+	orders, err := h.as.GetStaleOrders(r.Context())
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, model.ErrWentWrong)
+		return
+	}
+
+	now := time.Now()
+	resp := api.StaleOrdersReportResponse{
+		GeneratedAt: now.Format(time.RFC3339),
+		Total:       len(orders),
+		Orders:      make([]api.StaleOrderResponse, 0, len(orders)),
+	}
+
+	for _, o := range orders {
+		if o.Status == model.OrderStatusExpired {
+			resp.Expired++
+		}
+
+		item := api.StaleOrderResponse{
+			Number:     o.Number,
+			UserID:     o.UserID.String(),
+			Status:     string(o.Status),
+			UploadedAt: o.CreatedAt.Format(time.RFC3339),
+			Age:        now.Sub(o.CreatedAt).Truncate(time.Second).String(),
+		}
+		if o.FlaggedAt != nil {
+			item.FlaggedAt = o.FlaggedAt.Format(time.RFC3339)
+		}
+
+		resp.Orders = append(resp.Orders, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(resp); err != nil {
+		log.With("err", err.Error())
+		httperr.Write(w, r, model.ErrResponseEncoding)
+		return
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/util"
+)
+
+// AdjustAccrual sets the corrected accrual of a processed order, e.g. when
+// the accrual system reports another amount than was credited. The
+// difference is credited to or debited from the user.
+func (h *AdminHandler) AdjustAccrual(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	number := chi.URLParam(r, "number")
+
+	var req api.AccrualAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Accrual == nil {
+		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+
+	amount := int(math.Round(*req.Accrual * 100))
+	delta, err := h.os.AdjustAccrual(r.Context(), number, amount)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	log.With("order", number, "accrual", amount, "delta", delta).Info("order accrual adjusted")
+
+	writeJSON(w, r, http.StatusOK, api.AccrualAdjustmentResponse{
+		Number:  number,
+		Accrual: util.RoundToTwoDecimals(float64(amount) / 100),
+		Delta:   util.RoundToTwoDecimals(float64(delta) / 100),
+	})
+}
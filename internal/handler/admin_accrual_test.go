@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newAccrualAdjustmentRequest(number, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/orders/"+number+"/accrual", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("number", number)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestAdminHandler_AdjustAccrual(t *testing.T) {
+	orderID := uuid.New()
+
+	newHandler := func(t *testing.T) (*AdminHandler, *mocks.MockOrderRepository) {
+		repo := mocks.NewMockOrderRepository(gomock.NewController(t))
+		return NewAdminHandler(&service.Service{Order: service.NewOrderService(repo)}), repo
+	}
+
+	t.Run("adjusted", func(t *testing.T) {
+		h, repo := newHandler(t)
+		repo.EXPECT().GetByNumber(gomock.Any(), validNumber).
+			Return(&model.Order{ID: orderID, Number: validNumber, Status: model.OrderStatusProcessed, Accrual: 50000}, nil)
+		repo.EXPECT().AdjustAccrual(gomock.Any(), orderID, 45050).Return(-4950, nil)
+
+		rr := httptest.NewRecorder()
+		h.AdjustAccrual(rr, newAccrualAdjustmentRequest(validNumber, `{"accrual":450.5}`))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp api.AccrualAdjustmentResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, api.AccrualAdjustmentResponse{Number: validNumber, Accrual: 450.5, Delta: -49.5}, resp)
+	})
+
+	t.Run("accrual is required", func(t *testing.T) {
+		h, _ := newHandler(t)
+
+		rr := httptest.NewRecorder()
+		h.AdjustAccrual(rr, newAccrualAdjustmentRequest(validNumber, `{}`))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("not credited", func(t *testing.T) {
+		h, repo := newHandler(t)
+		repo.EXPECT().GetByNumber(gomock.Any(), validNumber).
+			Return(&model.Order{ID: orderID, Number: validNumber, Status: model.OrderStatusNew}, nil)
+
+		rr := httptest.NewRecorder()
+		h.AdjustAccrual(rr, newAccrualAdjustmentRequest(validNumber, `{"accrual":10}`))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		h, repo := newHandler(t)
+		repo.EXPECT().GetByNumber(gomock.Any(), validNumber).Return(nil, model.ErrNotFound)
+
+		rr := httptest.NewRecorder()
+		h.AdjustAccrual(rr, newAccrualAdjustmentRequest(validNumber, `{"accrual":10}`))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// ImportsPath is where import jobs are created and looked up.
+const ImportsPath = "/api/admin/imports"
+
+// maxImportBodyBytes bounds the size of an uploaded import file.
+const maxImportBodyBytes = 32 << 20
+
+// ImportOrders runs an import of legacy orders from a CSV body. The mode
+// query parameter is dry-run (the default) or apply.
+func (h *AdminHandler) ImportOrders(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	contentType := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "text/csv") && !strings.HasPrefix(contentType, "text/plain") {
+		log.With("err", model.ErrInvalidContentType.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidContentType)
+		return
+	}
+
+	mode, err := model.ParseImportMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	defer body.Close()
+
+	job, err := h.as.ImportOrders(r.Context(), body, mode)
+	if job == nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+	if err != nil {
+		log.With("err", err.Error(), "job_id", job.ID).Error("import stopped")
+	}
+
+	log.With(
+		"job_id", job.ID,
+		"mode", job.Mode,
+		"status", job.Status,
+		"imported", job.Imported,
+		"skipped", job.Skipped,
+		"failed", job.Failed,
+	).Info("import finished")
+
+	resp := importJobResponse(job)
+	w.Header().Set("Location", resp.Links.Self)
+	writeJSON(w, r, http.StatusCreated, resp)
+}
+
+// GetImport reports the summary of an import job.
+func (h *AdminHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.importJob(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, importJobResponse(job))
+}
+
+// GetImportErrors downloads the CSV error report of an import job.
+func (h *AdminHandler) GetImportErrors(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.importJob(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-errors.csv"`, job.ID))
+	write(w, r, "text/csv; charset=utf-8", []byte(job.Report))
+}
+
+func (h *AdminHandler) importJob(w http.ResponseWriter, r *http.Request) (*model.ImportJob, bool) {
+	log := logger.FromContext(r.Context())
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.as.GetImport(r.Context(), id)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return nil, false
+	}
+
+	return job, true
+}
+
+func importJobResponse(job *model.ImportJob) api.ImportJobResponse {
+	self := ImportsPath + "/" + job.ID.String()
+	resp := api.ImportJobResponse{
+		ID:        job.ID.String(),
+		Mode:      string(job.Mode),
+		Status:    string(job.Status),
+		Rows:      job.TotalRows,
+		Imported:  job.Imported,
+		Skipped:   job.Skipped,
+		Failed:    job.Failed,
+		CreatedAt: job.CreatedAt.Format(time.RFC3339),
+		Links: api.ImportJobLinks{
+			Self:   self,
+			Errors: self + "/errors",
+		},
+	}
+	if job.FinishedAt != nil {
+		resp.FinishedAt = job.FinishedAt.Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.FromContext(r.Context()).With("err", err.Error()).Warn()
+	}
+}
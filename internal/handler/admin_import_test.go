@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAdminHandler_ImportOrders(t *testing.T) {
+	t.Run("dry run by default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockImportRepository(ctrl)
+		h := NewAdminHandler(&service.Service{Admin: service.NewAdminService(nil, repo)})
+
+		var jobID uuid.UUID
+		repo.EXPECT().CreateJob(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, job *model.ImportJob) error {
+			assert.Equal(t, model.ImportModeDryRun, job.Mode)
+			jobID = job.ID
+			return nil
+		})
+		repo.EXPECT().Preview(gomock.Any(), gomock.Len(1)).Return([]model.ImportRowResult{
+			{Line: 1, Outcome: model.ImportImported},
+		}, nil)
+		repo.EXPECT().FinishJob(gomock.Any(), gomock.Any()).Return(nil)
+
+		body := "alice," + validNumber + ",PROCESSED,10,2024-03-01T10:00:00Z\n"
+		req := httptest.NewRequest(http.MethodPost, ImportsPath, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		rr := httptest.NewRecorder()
+
+		h.ImportOrders(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, ImportsPath+"/"+jobID.String(), rr.Header().Get("Location"))
+
+		var resp api.ImportJobResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, string(model.ImportStatusCompleted), resp.Status)
+		assert.Equal(t, 1, resp.Imported)
+		assert.Equal(t, ImportsPath+"/"+jobID.String()+"/errors", resp.Links.Errors)
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		url         string
+	}{
+		{"json body", "application/json", ImportsPath},
+		{"unknown mode", "text/csv", ImportsPath + "?mode=force"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAdminHandler(&service.Service{Admin: service.NewAdminService(nil, nil)})
+
+			req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader("x"))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+
+			h.ImportOrders(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
+
+func TestAdminHandler_GetImportErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockImportRepository(ctrl)
+	h := NewAdminHandler(&service.Service{Admin: service.NewAdminService(nil, repo)})
+
+	jobID := uuid.New()
+	report := "line,login,number,reason\n3,bob,79927398713,unknown login\n"
+	repo.EXPECT().GetJob(gomock.Any(), jobID).Return(&model.ImportJob{ID: jobID, Report: report}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, ImportsPath+"/"+jobID.String()+"/errors", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", jobID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	h.GetImportErrors(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, report, rr.Body.String())
+}
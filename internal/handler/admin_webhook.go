@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// WebhooksPath is where webhook subscriptions are managed.
+const WebhooksPath = "/api/admin/webhooks"
+
+// CreateWebhook subscribes a receiver to events. The response is the only
+// place the secret is shown.
+func (h *AdminHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	var req api.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+
+	sub, err := h.ws.CreateSubscription(r.Context(), req.URL, req.Secret, req.EventTypes)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	log.With("subscription_id", sub.ID, "url", sub.URL, "events", sub.EventTypes).Info("webhook subscription created")
+
+	resp := webhookSubscriptionResponse(sub)
+	resp.Secret = sub.Secret
+	w.Header().Set("Location", WebhooksPath+"/"+sub.ID.String())
+	writeJSON(w, r, http.StatusCreated, resp)
+}
+
+func (h *AdminHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	subs, err := h.ws.ListSubscriptions(r.Context())
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, model.ErrWentWrong)
+		return
+	}
+
+	resp := make([]api.WebhookSubscriptionResponse, 0, len(subs))
+	for i := range subs {
+		resp = append(resp, webhookSubscriptionResponse(&subs[i]))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// DeleteWebhook unsubscribes the receiver and drops its pending deliveries.
+func (h *AdminHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.ws.DeleteSubscription(r.Context(), id); err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	log.With("subscription_id", id).Info("webhook subscription deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists the latest deliveries of a subscription with
+// the log of their attempts.
+func (h *AdminHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.ws.GetDeliveries(r.Context(), id)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	resp := make([]api.WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		resp = append(resp, webhookDeliveryResponse(&deliveries[i]))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// ReplayWebhookDelivery queues the event of a delivery to be sent again.
+func (h *AdminHandler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.ws.ReplayDelivery(r.Context(), id)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	log.With("delivery_id", id, "replay_id", delivery.ID).Info("webhook delivery replayed")
+	writeJSON(w, r, http.StatusAccepted, webhookDeliveryResponse(delivery))
+}
+
+// uuidParam parses the id URL parameter. Malformed IDs can not exist, so
+// they are reported as not found.
+func uuidParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		logger.FromContext(r.Context()).With("err", err.Error()).Warn()
+		httperr.Write(w, r, model.ErrNotFound)
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+func webhookSubscriptionResponse(sub *model.WebhookSubscription) api.WebhookSubscriptionResponse {
+	types := make([]string, len(sub.EventTypes))
+	for i, t := range sub.EventTypes {
+		types[i] = string(t)
+	}
+
+	return api.WebhookSubscriptionResponse{
+		ID:         sub.ID.String(),
+		URL:        sub.URL,
+		EventTypes: types,
+		CreatedAt:  sub.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func webhookDeliveryResponse(d *model.WebhookDelivery) api.WebhookDeliveryResponse {
+	resp := api.WebhookDeliveryResponse{
+		ID:        d.ID.String(),
+		EventID:   d.EventID.String(),
+		EventType: string(d.EventType),
+		Status:    string(d.Status),
+		Attempts:  d.Attempts,
+		CreatedAt: d.CreatedAt.Format(time.RFC3339),
+		Log:       make([]api.WebhookAttemptResponse, 0, len(d.Log)),
+	}
+	if d.Status == model.WebhookDeliveryPending {
+		resp.NextAttemptAt = d.NextAttemptAt.Format(time.RFC3339)
+	}
+	if d.DeliveredAt != nil {
+		resp.DeliveredAt = d.DeliveredAt.Format(time.RFC3339)
+	}
+
+	for _, a := range d.Log {
+		entry := api.WebhookAttemptResponse{
+			Attempt:    a.Attempt,
+			Error:      a.Error,
+			DurationMs: a.DurationMs,
+			At:         a.CreatedAt.Format(time.RFC3339),
+		}
+		if a.StatusCode != nil {
+			entry.StatusCode = *a.StatusCode
+		}
+		resp.Log = append(resp.Log, entry)
+	}
+
+	return resp
+}
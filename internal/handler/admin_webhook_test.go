@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newWebhookTestHandler(ctrl *gomock.Controller) (*AdminHandler, *mocks.MockWebhookRepository) {
+	repo := mocks.NewMockWebhookRepository(ctrl)
+	return NewAdminHandler(&service.Service{Webhook: service.NewWebhookService(repo)}), repo
+}
+
+func withIDParam(req *http.Request, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestAdminHandler_CreateWebhook(t *testing.T) {
+	t.Run("secret is shown once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newWebhookTestHandler(ctrl)
+
+		repo.EXPECT().CreateSubscription(gomock.Any(), gomock.Any()).Return(nil)
+
+		body := `{"url":"https://crm.example.com/hooks","event_types":["order.processed"]}`
+		rr := httptest.NewRecorder()
+		h.CreateWebhook(rr, httptest.NewRequest(http.MethodPost, WebhooksPath, strings.NewReader(body)))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var resp api.WebhookSubscriptionResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.NotEmpty(t, resp.Secret)
+		assert.Equal(t, []string{"order.processed"}, resp.EventTypes)
+		assert.Equal(t, WebhooksPath+"/"+resp.ID, rr.Header().Get("Location"))
+	})
+
+	t.Run("unknown event type", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, _ := newWebhookTestHandler(ctrl)
+
+		body := `{"url":"https://crm.example.com/hooks","event_types":["order.created"]}`
+		rr := httptest.NewRecorder()
+		h.CreateWebhook(rr, httptest.NewRequest(http.MethodPost, WebhooksPath, strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAdminHandler_GetWebhookDeliveries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	h, repo := newWebhookTestHandler(ctrl)
+
+	subID := uuid.New()
+	at := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	code := http.StatusBadGateway
+	repo.EXPECT().GetDeliveries(gomock.Any(), subID, gomock.Any()).Return([]model.WebhookDelivery{{
+		ID:            uuid.New(),
+		EventID:       uuid.New(),
+		EventType:     model.WebhookWithdrawalCreated,
+		Status:        model.WebhookDeliveryPending,
+		Attempts:      1,
+		NextAttemptAt: at.Add(30 * time.Second),
+		CreatedAt:     at,
+		Log: []model.WebhookAttempt{
+			{Attempt: 1, StatusCode: &code, Error: "unexpected status 502", DurationMs: 12, CreatedAt: at},
+		},
+	}}, nil)
+
+	req := withIDParam(httptest.NewRequest(http.MethodGet, WebhooksPath+"/"+subID.String()+"/deliveries", nil), subID.String())
+	rr := httptest.NewRecorder()
+	h.GetWebhookDeliveries(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp []api.WebhookDeliveryResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp, 1)
+	assert.Equal(t, "2025-01-01T10:00:30Z", resp[0].NextAttemptAt)
+	assert.Equal(t, []api.WebhookAttemptResponse{
+		{Attempt: 1, StatusCode: 502, Error: "unexpected status 502", DurationMs: 12, At: "2025-01-01T10:00:00Z"},
+	}, resp[0].Log)
+}
+
+func TestAdminHandler_ReplayWebhookDelivery(t *testing.T) {
+	t.Run("queued", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newWebhookTestHandler(ctrl)
+
+		id := uuid.New()
+		repo.EXPECT().Replay(gomock.Any(), id).Return(&model.WebhookDelivery{
+			ID:     uuid.New(),
+			Status: model.WebhookDeliveryPending,
+		}, nil)
+
+		req := withIDParam(httptest.NewRequest(http.MethodPost, "/", nil), id.String())
+		rr := httptest.NewRecorder()
+		h.ReplayWebhookDelivery(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	})
+
+	t.Run("unknown delivery", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newWebhookTestHandler(ctrl)
+
+		id := uuid.New()
+		repo.EXPECT().Replay(gomock.Any(), id).Return(nil, model.ErrNotFound)
+
+		req := withIDParam(httptest.NewRequest(http.MethodPost, "/", nil), id.String())
+		rr := httptest.NewRecorder()
+		h.ReplayWebhookDelivery(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, _ := newWebhookTestHandler(ctrl)
+
+		req := withIDParam(httptest.NewRequest(http.MethodPost, "/", nil), "42")
+		rr := httptest.NewRecorder()
+		h.ReplayWebhookDelivery(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
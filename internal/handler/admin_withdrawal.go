@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/util"
+)
+
+// WithdrawalsPath is where support finds and refunds withdrawals.
+const WithdrawalsPath = "/api/admin/withdrawals"
+
+// ListWithdrawals finds the withdrawals paying for the shop order given in
+// the order query parameter.
+func (h *AdminHandler) ListWithdrawals(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	withdrawals, err := h.bs.GetWithdrawalsByOrder(r.Context(), r.URL.Query().Get("order"))
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	resp := make([]api.AdminWithdrawalResponse, 0, len(withdrawals))
+	for i := range withdrawals {
+		resp = append(resp, adminWithdrawalResponse(&withdrawals[i]))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// RefundWithdrawal returns points of the withdrawal to the user, e.g. when
+// the shop order paid with them was cancelled.
+func (h *AdminHandler) RefundWithdrawal(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req api.WithdrawalRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+
+	var sum int
+	if req.Sum != nil {
+		sum = int(math.Round(*req.Sum * 100))
+	}
+	if (req.Sum != nil) == req.Full {
+		log.With("err", "either sum or full is required").Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+
+	refund, withdrawal, err := h.bs.RefundWithdrawal(r.Context(), id, sum, req.Full, req.Reason)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	log.With(
+		"withdrawal_id", id,
+		"refund_id", refund.ID,
+		"user_id", refund.UserID,
+		"sum", refund.Sum,
+	).Info("withdrawal refunded")
+
+	writeJSON(w, r, http.StatusCreated, api.WithdrawalRefundResponse{
+		ID:         refund.ID.String(),
+		Sum:        util.RoundToTwoDecimals(float64(refund.Sum) / 100),
+		Reason:     refund.Reason,
+		CreatedAt:  refund.CreatedAt.Format(time.RFC3339),
+		Withdrawal: adminWithdrawalResponse(withdrawal),
+	})
+}
+
+func adminWithdrawalResponse(w *model.Withdrawal) api.AdminWithdrawalResponse {
+	return api.AdminWithdrawalResponse{
+		ID:           w.ID.String(),
+		UserID:       w.UserID.String(),
+		Order:        w.OrderID,
+		Sum:          util.RoundToTwoDecimals(float64(w.Sum) / 100),
+		Refunded:     util.RoundToTwoDecimals(float64(w.Refunded) / 100),
+		RefundStatus: string(w.RefundStatus()),
+		ProcessedAt:  w.ProcessedAt.Format(time.RFC3339),
+	}
+}
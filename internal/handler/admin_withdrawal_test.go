@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newRefundTestHandler(ctrl *gomock.Controller) (*AdminHandler, *mocks.MockBalanceRepository) {
+	repo := mocks.NewMockBalanceRepository(ctrl)
+	return NewAdminHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, 0)}), repo
+}
+
+func TestAdminHandler_RefundWithdrawal(t *testing.T) {
+	withdrawalID := uuid.New()
+	userID := uuid.New()
+	processedAt := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	path := WithdrawalsPath + "/" + withdrawalID.String() + "/refunds"
+
+	newRequest := func(body string) *http.Request {
+		return withIDParam(httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)), withdrawalID.String())
+	}
+
+	t.Run("partial refund", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newRefundTestHandler(ctrl)
+
+		refund := &model.WithdrawalRefund{
+			ID:           uuid.New(),
+			WithdrawalID: withdrawalID,
+			UserID:       userID,
+			Sum:          2550,
+			Reason:       "order cancelled",
+			CreatedAt:    processedAt.Add(time.Hour),
+		}
+		withdrawal := &model.Withdrawal{
+			ID:          withdrawalID,
+			UserID:      userID,
+			OrderID:     validNumber,
+			Sum:         10000,
+			ProcessedAt: processedAt,
+			Refunded:    2550,
+		}
+		repo.EXPECT().RefundWithdrawal(gomock.Any(), withdrawalID, 2550, false, "order cancelled").Return(refund, withdrawal, nil)
+
+		rr := httptest.NewRecorder()
+		h.RefundWithdrawal(rr, newRequest(`{"sum":25.5,"reason":" order cancelled "}`))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var resp api.WithdrawalRefundResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, api.WithdrawalRefundResponse{
+			ID:        refund.ID.String(),
+			Sum:       25.5,
+			Reason:    "order cancelled",
+			CreatedAt: "2026-02-01T13:00:00Z",
+			Withdrawal: api.AdminWithdrawalResponse{
+				ID:           withdrawalID.String(),
+				UserID:       userID.String(),
+				Order:        validNumber,
+				Sum:          100,
+				Refunded:     25.5,
+				RefundStatus: "PARTIALLY_REFUNDED",
+				ProcessedAt:  "2026-02-01T12:00:00Z",
+			},
+		}, resp)
+	})
+
+	t.Run("full refunds the rest", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newRefundTestHandler(ctrl)
+
+		refund := &model.WithdrawalRefund{ID: uuid.New(), WithdrawalID: withdrawalID, UserID: userID, Sum: 7450}
+		withdrawal := &model.Withdrawal{ID: withdrawalID, UserID: userID, Sum: 10000, Refunded: 10000}
+		repo.EXPECT().RefundWithdrawal(gomock.Any(), withdrawalID, 0, true, "").Return(refund, withdrawal, nil)
+
+		rr := httptest.NewRecorder()
+		h.RefundWithdrawal(rr, newRequest(`{"full":true}`))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var resp api.WithdrawalRefundResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, 74.5, resp.Sum)
+		assert.Equal(t, "REFUNDED", resp.Withdrawal.RefundStatus)
+	})
+
+	t.Run("exceeds the withdrawn sum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newRefundTestHandler(ctrl)
+
+		repo.EXPECT().RefundWithdrawal(gomock.Any(), withdrawalID, 20000, false, "").Return(nil, nil, model.ErrRefundExceedsWithdrawal)
+
+		rr := httptest.NewRecorder()
+		h.RefundWithdrawal(rr, newRequest(`{"sum":200}`))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(), httperr.CodeRefundExceeds)
+	})
+
+	t.Run("invalid bodies", func(t *testing.T) {
+		bodies := map[string]string{
+			"negative sum":      `{"sum":-1}`,
+			"zero sum":          `{"sum":0}`,
+			"sum rounds to 0":   `{"sum":0.004}`,
+			"sum omitted":       `{}`,
+			"full not set":      `{"full":false}`,
+			"both sum and full": `{"sum":10,"full":true}`,
+			"sum too large":     `{"sum":21474836.48}`,
+		}
+
+		for name, body := range bodies {
+			t.Run(name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				h, _ := newRefundTestHandler(ctrl)
+
+				rr := httptest.NewRecorder()
+				h.RefundWithdrawal(rr, newRequest(body))
+
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+			})
+		}
+	})
+
+	t.Run("unknown withdrawal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newRefundTestHandler(ctrl)
+
+		repo.EXPECT().RefundWithdrawal(gomock.Any(), withdrawalID, 0, true, "").Return(nil, nil, model.ErrNotFound)
+
+		rr := httptest.NewRecorder()
+		h.RefundWithdrawal(rr, newRequest(`{"full":true}`))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestAdminHandler_ListWithdrawals(t *testing.T) {
+	t.Run("by order", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, repo := newRefundTestHandler(ctrl)
+
+		repo.EXPECT().GetWithdrawalsByOrder(gomock.Any(), validNumber).Return([]model.Withdrawal{
+			{ID: uuid.New(), UserID: uuid.New(), OrderID: validNumber, Sum: 500},
+		}, nil)
+
+		rr := httptest.NewRecorder()
+		h.ListWithdrawals(rr, httptest.NewRequest(http.MethodGet, WithdrawalsPath+"?order="+validNumber, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp []api.AdminWithdrawalResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, 5.0, resp[0].Sum)
+		assert.Empty(t, resp[0].RefundStatus)
+	})
+
+	t.Run("order is required", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		h, _ := newRefundTestHandler(ctrl)
+
+		rr := httptest.NewRecorder()
+		h.ListWithdrawals(rr, httptest.NewRequest(http.MethodGet, WithdrawalsPath, nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
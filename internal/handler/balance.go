@@ -2,11 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
 	"github.com/mrhyman/gophermart/internal/logger"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/service"
@@ -28,42 +30,46 @@ func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodGet {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusMethodNotAllowed)
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
 		return
 	}
 
 	userIDStr, ok := r.Context().Value(model.UserIDKey).(string)
 	if !ok || userIDStr == "" {
 		log.With("err", model.ErrUnknownUser).Warn()
-		http.Error(w, model.ErrUnknownUser.Error(), http.StatusUnauthorized)
+		httperr.Write(w, r, model.ErrUnknownUser)
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
-	current, withdrawn, err := h.bs.GetUserBalance(r.Context(), userID)
+	balance, err := h.bs.GetUserBalance(r.Context(), userID)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
-	resp := api.UserBalanceResponse{
-		Current:   util.RoundToTwoDecimals(float64(current) / 100),
-		Withdrawn: util.RoundToTwoDecimals(float64(withdrawn) / 100),
+	expiring, err := h.bs.GetExpiringPoints(r.Context(), userID)
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, model.ErrWentWrong)
+		return
 	}
 
+	resp := balanceResponse(balance, expiring)
+
 	w.Header().Set("Content-Type", "application/json")
 
 	enc := json.NewEncoder(w)
 	if err := enc.Encode(resp); err != nil {
 		log.With("err", err.Error())
-		http.Error(w, model.ErrResponseEncoding.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrResponseEncoding)
 		return
 	}
 }
@@ -73,41 +79,45 @@ func (h *BalanceHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusMethodNotAllowed)
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
 		return
 	}
 
 	var req api.WithdrawRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
 		return
 	}
 
 	userIDStr, ok := r.Context().Value(model.UserIDKey).(string)
 	if !ok || userIDStr == "" {
 		log.With("err", model.ErrUnknownUser).Warn()
-		http.Error(w, model.ErrUnknownUser.Error(), http.StatusUnauthorized)
+		httperr.Write(w, r, model.ErrUnknownUser)
 		return
 	}
 
 	if !util.ValidateLuhn(req.Order) {
 		log.With("err", model.ErrInvalidOrderNumber).Warn()
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		httperr.Write(w, r, model.ErrInvalidOrderNumber)
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
 	err = h.bs.Withdraw(r.Context(), userID, req.Order, int(req.Sum*100))
 	if err != nil {
-		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		if errors.Is(err, model.ErrInsufficientFunds) {
+			log.With("err", err.Error()).Warn()
+		} else {
+			log.With("err", err.Error()).Error()
+		}
+		httperr.Write(w, r, err)
 		return
 	}
 
@@ -119,28 +129,28 @@ func (h *BalanceHandler) GetWithdrawals(w http.ResponseWriter, r *http.Request)
 
 	if r.Method != http.MethodGet {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusMethodNotAllowed)
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
 		return
 	}
 
 	userIDStr, ok := r.Context().Value(model.UserIDKey).(string)
 	if !ok || userIDStr == "" {
 		log.With("err", model.ErrUnknownUser).Warn()
-		http.Error(w, model.ErrUnknownUser.Error(), http.StatusUnauthorized)
+		httperr.Write(w, r, model.ErrUnknownUser)
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
 	withdrawals, err := h.bs.GetWithdrawals(r.Context(), userID)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
@@ -151,11 +161,7 @@ func (h *BalanceHandler) GetWithdrawals(w http.ResponseWriter, r *http.Request)
 
 	var resp = make([]api.WithdrawalListResponse, 0, len(withdrawals))
 	for _, withdrawal := range withdrawals {
-		resp = append(resp, api.WithdrawalListResponse{
-			Order:       withdrawal.OrderID,
-			Sum:         util.RoundToTwoDecimals(float64(withdrawal.Sum) / 100),
-			ProcessedAt: withdrawal.ProcessedAt.Format(time.RFC3339),
-		})
+		resp = append(resp, withdrawalResponse(withdrawal))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -164,7 +170,35 @@ func (h *BalanceHandler) GetWithdrawals(w http.ResponseWriter, r *http.Request)
 	enc := json.NewEncoder(w)
 	if err := enc.Encode(resp); err != nil {
 		log.With("err", err.Error())
-		http.Error(w, model.ErrResponseEncoding.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrResponseEncoding)
 		return
 	}
 }
+
+func withdrawalResponse(w model.Withdrawal) api.WithdrawalListResponse {
+	return api.WithdrawalListResponse{
+		Order:        w.OrderID,
+		Sum:          util.RoundToTwoDecimals(float64(w.Sum) / 100),
+		ProcessedAt:  w.ProcessedAt.Format(time.RFC3339),
+		Refunded:     util.RoundToTwoDecimals(float64(w.Refunded) / 100),
+		RefundStatus: string(w.RefundStatus()),
+	}
+}
+
+func balanceResponse(balance *model.Balance, expiring []model.ExpiringPoints) api.UserBalanceResponse {
+	resp := api.UserBalanceResponse{
+		Current:   util.RoundToTwoDecimals(float64(balance.Current) / 100),
+		Withdrawn: util.RoundToTwoDecimals(float64(balance.Withdrawn) / 100),
+		Available: util.RoundToTwoDecimals(float64(balance.Available()) / 100),
+		Held:      util.RoundToTwoDecimals(float64(balance.Held) / 100),
+	}
+
+	for _, e := range expiring {
+		resp.ExpiringSoon = append(resp.ExpiringSoon, api.ExpiringPointsResponse{
+			Sum:       util.RoundToTwoDecimals(float64(e.Amount) / 100),
+			ExpiresAt: e.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+
+	return resp
+}
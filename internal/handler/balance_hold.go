@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/util"
+)
+
+// HoldsPath is where the user reserves points before withdrawing them.
+const HoldsPath = "/api/user/balance/holds"
+
+// CreateHold reserves points for an order, e.g. while the checkout is in
+// progress. The hold is confirmed or released later.
+func (h *BalanceHandler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	var req api.WithdrawalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+
+	if !util.ValidateLuhn(req.Order) {
+		log.With("err", model.ErrInvalidOrderNumber).Warn()
+		httperr.Write(w, r, model.ErrInvalidOrderNumber)
+		return
+	}
+
+	hold, err := h.bs.CreateHold(r.Context(), userID, req.Order, int(math.Round(req.Sum*100)))
+	if err != nil {
+		writeHoldError(w, r, err)
+		return
+	}
+
+	log.With("hold_id", hold.ID, "order", hold.Order, "sum", hold.Sum).Info("withdrawal hold created")
+
+	w.Header().Set("Location", HoldsPath+"/"+hold.ID.String())
+	writeJSON(w, r, http.StatusCreated, holdResponse(hold))
+}
+
+func (h *BalanceHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.bs.GetHold(r.Context(), userID, id)
+	if err != nil {
+		writeHoldError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, holdResponse(hold))
+}
+
+// ConfirmHold withdraws the held points.
+func (h *BalanceHandler) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.bs.ConfirmHold(r.Context(), userID, id)
+	if err != nil {
+		writeHoldError(w, r, err)
+		return
+	}
+
+	log.With("hold_id", hold.ID, "order", hold.Order, "sum", hold.Sum).Info("withdrawal hold confirmed")
+	writeJSON(w, r, http.StatusOK, holdResponse(hold))
+}
+
+// ReleaseHold gives the held points back to the available balance.
+func (h *BalanceHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.bs.ReleaseHold(r.Context(), userID, id)
+	if err != nil {
+		writeHoldError(w, r, err)
+		return
+	}
+
+	log.With("hold_id", hold.ID, "order", hold.Order).Info("withdrawal hold released")
+	writeJSON(w, r, http.StatusOK, holdResponse(hold))
+}
+
+// writeHoldError logs expected outcomes such as a short balance as warnings.
+func writeHoldError(w http.ResponseWriter, r *http.Request, err error) {
+	log := logger.FromContext(r.Context()).With("err", err.Error())
+
+	if status, _ := httperr.Resolve(err); status >= http.StatusInternalServerError {
+		log.Error()
+	} else {
+		log.Warn()
+	}
+
+	httperr.Write(w, r, err)
+}
+
+func holdResponse(hold *model.WithdrawalHold) api.WithdrawalHoldResponse {
+	resp := api.WithdrawalHoldResponse{
+		ID:        hold.ID.String(),
+		Order:     hold.Order,
+		Sum:       util.RoundToTwoDecimals(float64(hold.Sum) / 100),
+		Status:    string(hold.Status),
+		CreatedAt: hold.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: hold.ExpiresAt.Format(time.RFC3339),
+	}
+	if hold.ResolvedAt != nil {
+		resp.ResolvedAt = hold.ResolvedAt.Format(time.RFC3339)
+	}
+
+	return resp
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newHoldRequest(userID uuid.UUID, path, body, holdID string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), model.UserIDKey, userID.String())
+	if holdID != "" {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", holdID)
+		ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	}
+	return req.WithContext(ctx)
+}
+
+func TestBalanceHandler_CreateHold(t *testing.T) {
+	userID := uuid.New()
+	holdTTL := 15 * time.Minute
+
+	t.Run("created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockBalanceRepository(ctrl)
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, holdTTL, 0)})
+
+		createdAt := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+		hold := &model.WithdrawalHold{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Order:     validNumber,
+			Sum:       2910,
+			Status:    model.HoldStatusHeld,
+			CreatedAt: createdAt,
+			ExpiresAt: createdAt.Add(holdTTL),
+		}
+		repo.EXPECT().CreateHold(gomock.Any(), userID, validNumber, 2910, holdTTL).Return(hold, nil)
+
+		rr := httptest.NewRecorder()
+		h.CreateHold(rr, newHoldRequest(userID, HoldsPath, `{"order":"`+validNumber+`","sum":29.1}`, ""))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, HoldsPath+"/"+hold.ID.String(), rr.Header().Get("Location"))
+
+		var resp api.WithdrawalHoldResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, api.WithdrawalHoldResponse{
+			ID:        hold.ID.String(),
+			Order:     validNumber,
+			Sum:       29.1,
+			Status:    "HELD",
+			CreatedAt: "2026-01-10T09:00:00Z",
+			ExpiresAt: "2026-01-10T09:15:00Z",
+		}, resp)
+	})
+
+	t.Run("insufficient available points", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockBalanceRepository(ctrl)
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, holdTTL, 0)})
+
+		repo.EXPECT().CreateHold(gomock.Any(), userID, validNumber, 100000, holdTTL).Return(nil, model.ErrInsufficientFunds)
+
+		rr := httptest.NewRecorder()
+		h.CreateHold(rr, newHoldRequest(userID, HoldsPath, `{"order":"`+validNumber+`","sum":1000}`, ""))
+
+		assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	})
+
+	t.Run("invalid order number", func(t *testing.T) {
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(nil, holdTTL, 0)})
+
+		rr := httptest.NewRecorder()
+		h.CreateHold(rr, newHoldRequest(userID, HoldsPath, `{"order":"`+invalidNumber+`","sum":10}`, ""))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("sum must be positive", func(t *testing.T) {
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(nil, holdTTL, 0)})
+
+		rr := httptest.NewRecorder()
+		h.CreateHold(rr, newHoldRequest(userID, HoldsPath, `{"order":"`+validNumber+`","sum":0}`, ""))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("sum too large to store", func(t *testing.T) {
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(nil, holdTTL, 0)})
+
+		rr := httptest.NewRecorder()
+		h.CreateHold(rr, newHoldRequest(userID, HoldsPath, `{"order":"`+validNumber+`","sum":21474836.48}`, ""))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestBalanceHandler_ResolveHold(t *testing.T) {
+	userID := uuid.New()
+	holdID := uuid.New()
+	resolvedAt := time.Now()
+	hold := &model.WithdrawalHold{ID: holdID, UserID: userID, Order: validNumber, Sum: 500, ResolvedAt: &resolvedAt}
+
+	t.Run("confirm", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockBalanceRepository(ctrl)
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, 0)})
+
+		confirmed := *hold
+		confirmed.Status = model.HoldStatusConfirmed
+		repo.EXPECT().ConfirmHold(gomock.Any(), userID, holdID).Return(&confirmed, nil)
+
+		rr := httptest.NewRecorder()
+		h.ConfirmHold(rr, newHoldRequest(userID, HoldsPath+"/"+holdID.String()+"/confirm", "", holdID.String()))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp api.WithdrawalHoldResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, "CONFIRMED", resp.Status)
+		assert.Equal(t, resolvedAt.Format(time.RFC3339), resp.ResolvedAt)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockBalanceRepository(ctrl)
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, 0)})
+
+		released := *hold
+		released.Status = model.HoldStatusReleased
+		repo.EXPECT().ReleaseHold(gomock.Any(), userID, holdID).Return(&released, nil)
+
+		rr := httptest.NewRecorder()
+		h.ReleaseHold(rr, newHoldRequest(userID, HoldsPath+"/"+holdID.String()+"/release", "", holdID.String()))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("confirm of a resolved or expired hold", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockBalanceRepository(ctrl)
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, 0)})
+
+		repo.EXPECT().ConfirmHold(gomock.Any(), userID, holdID).Return(nil, model.ErrHoldNotActive)
+
+		rr := httptest.NewRecorder()
+		h.ConfirmHold(rr, newHoldRequest(userID, HoldsPath+"/"+holdID.String()+"/confirm", "", holdID.String()))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		var problem api.Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+		assert.Equal(t, httperr.CodeHoldNotActive, problem.Code)
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(nil, time.Minute, 0)})
+
+		rr := httptest.NewRecorder()
+		h.ReleaseHold(rr, newHoldRequest(userID, HoldsPath+"/nope/release", "", "nope"))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newBalanceRequest(userID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
+	return req.WithContext(context.WithValue(req.Context(), model.UserIDKey, userID.String()))
+}
+
+func TestBalanceHandler_GetBalance(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("shows held and expiring points", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockBalanceRepository(ctrl)
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, 0)})
+
+		expiresAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		repo.EXPECT().GetUserBalance(gomock.Any(), userID).Return(&model.Balance{Current: 72950, Withdrawn: 4200, Held: 2000}, nil)
+		repo.EXPECT().
+			GetExpiringPoints(gomock.Any(), userID, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ uuid.UUID, until time.Time) ([]model.ExpiringPoints, error) {
+				assert.WithinDuration(t, time.Now().Add(service.ExpiringSoonWindow), until, time.Second)
+				return []model.ExpiringPoints{{Amount: 1050, ExpiresAt: expiresAt}}, nil
+			})
+
+		rr := httptest.NewRecorder()
+		h.GetBalance(rr, newBalanceRequest(userID))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp api.UserBalanceResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, api.UserBalanceResponse{
+			Current:   729.5,
+			Withdrawn: 42,
+			Available: 709.5,
+			Held:      20,
+			ExpiringSoon: []api.ExpiringPointsResponse{
+				{Sum: 10.5, ExpiresAt: "2026-03-01T12:00:00Z"},
+			},
+		}, resp)
+	})
+
+	t.Run("nothing expiring", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockBalanceRepository(ctrl)
+		h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, 0)})
+
+		repo.EXPECT().GetUserBalance(gomock.Any(), userID).Return(&model.Balance{Current: 500}, nil)
+		repo.EXPECT().GetExpiringPoints(gomock.Any(), userID, gomock.Any()).Return(nil, nil)
+
+		rr := httptest.NewRecorder()
+		h.GetBalance(rr, newBalanceRequest(userID))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"current":5,"withdrawn":0,"available":5,"held":0}`, rr.Body.String())
+	})
+}
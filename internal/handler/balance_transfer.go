@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/util"
+)
+
+const (
+	// TransferPath is where the user sends points to another user.
+	TransferPath = "/api/user/balance/transfer"
+	// TransfersPath lists the transfers the user sent and received.
+	TransfersPath = "/api/user/balance/transfers"
+	// IdempotencyKeyHeader identifies a transfer across retries of the request.
+	IdempotencyKeyHeader = "Idempotency-Key"
+)
+
+// Transfer sends points to another user. A retry with the same
+// Idempotency-Key returns the first transfer with 200 instead of 201.
+func (h *BalanceHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	var req api.PointsTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+
+	transfer, created, err := h.bs.Transfer(
+		r.Context(),
+		userID,
+		req.Recipient,
+		int(math.Round(req.Sum*100)),
+		req.Note,
+		r.Header.Get(IdempotencyKeyHeader),
+	)
+	if err != nil {
+		writeHoldError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		log.With(
+			"transfer_id", transfer.ID,
+			"recipient_id", transfer.RecipientID,
+			"sum", transfer.Sum,
+		).Info("points transferred")
+	}
+
+	writeJSON(w, r, status, transferResponse(transfer, userID))
+}
+
+// GetTransfers returns the transfers the user sent and received, latest
+// first.
+func (h *BalanceHandler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	transfers, err := h.bs.GetTransfers(r.Context(), userID)
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, model.ErrWentWrong)
+		return
+	}
+
+	resp := make([]api.PointsTransferResponse, 0, len(transfers))
+	for i := range transfers {
+		resp = append(resp, transferResponse(&transfers[i], userID))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+func transferResponse(t *model.PointsTransfer, userID uuid.UUID) api.PointsTransferResponse {
+	return api.PointsTransferResponse{
+		ID:           t.ID.String(),
+		Direction:    string(t.Direction(userID)),
+		Counterparty: t.Counterparty(userID),
+		Sum:          util.RoundToTwoDecimals(float64(t.Sum) / 100),
+		Note:         t.Note,
+		CreatedAt:    t.CreatedAt.Format(time.RFC3339),
+	}
+}
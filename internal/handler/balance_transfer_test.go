@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTransferRequest(userID uuid.UUID, body, key string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, TransferPath, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req.WithContext(context.WithValue(req.Context(), model.UserIDKey, userID.String()))
+}
+
+func TestBalanceHandler_Transfer(t *testing.T) {
+	senderID := uuid.New()
+	recipientID := uuid.New()
+	dailyLimit := 100000
+	body := `{"recipient":"alice","sum":25.5,"note":" for the trip "}`
+
+	newHandler := func(t *testing.T) (*BalanceHandler, *mocks.MockBalanceRepository) {
+		repo := mocks.NewMockBalanceRepository(gomock.NewController(t))
+		return NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, dailyLimit)}), repo
+	}
+
+	transfer := &model.PointsTransfer{
+		ID:             uuid.New(),
+		SenderID:       senderID,
+		RecipientID:    recipientID,
+		RecipientLogin: "alice",
+		Sum:            2550,
+		Note:           "for the trip",
+		IdempotencyKey: "key-1",
+		CreatedAt:      time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("transferred", func(t *testing.T) {
+		h, repo := newHandler(t)
+		repo.EXPECT().
+			Transfer(gomock.Any(), senderID, "alice", 2550, "for the trip", "key-1", dailyLimit).
+			Return(transfer, true, nil)
+
+		rr := httptest.NewRecorder()
+		h.Transfer(rr, newTransferRequest(senderID, body, "key-1"))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var resp api.PointsTransferResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, api.PointsTransferResponse{
+			ID:           transfer.ID.String(),
+			Direction:    "SENT",
+			Counterparty: "alice",
+			Sum:          25.5,
+			Note:         "for the trip",
+			CreatedAt:    "2026-03-01T10:00:00Z",
+		}, resp)
+	})
+
+	t.Run("retry returns the first transfer", func(t *testing.T) {
+		h, repo := newHandler(t)
+		repo.EXPECT().
+			Transfer(gomock.Any(), senderID, "alice", 2550, "for the trip", "key-1", dailyLimit).
+			Return(transfer, false, nil)
+
+		rr := httptest.NewRecorder()
+		h.Transfer(rr, newTransferRequest(senderID, body, "key-1"))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("idempotency key is required", func(t *testing.T) {
+		h, _ := newHandler(t)
+
+		rr := httptest.NewRecorder()
+		h.Transfer(rr, newTransferRequest(senderID, body, ""))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("non-positive sum", func(t *testing.T) {
+		h, _ := newHandler(t)
+
+		rr := httptest.NewRecorder()
+		h.Transfer(rr, newTransferRequest(senderID, `{"recipient":"alice","sum":0}`, "key-1"))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("sum too large to store", func(t *testing.T) {
+		h, _ := newHandler(t)
+
+		for _, sum := range []string{"21474836.48", "1e300"} {
+			rr := httptest.NewRecorder()
+			h.Transfer(rr, newTransferRequest(senderID, `{"recipient":"alice","sum":`+sum+`}`, "key-1"))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, sum)
+		}
+	})
+
+	t.Run("largest storable sum", func(t *testing.T) {
+		h, repo := newHandler(t)
+		repo.EXPECT().
+			Transfer(gomock.Any(), senderID, "alice", model.MaxPointsAmount, "", "key-1", dailyLimit).
+			Return(transfer, true, nil)
+
+		rr := httptest.NewRecorder()
+		h.Transfer(rr, newTransferRequest(senderID, `{"recipient":"alice","sum":21474836.47}`, "key-1"))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("repository errors", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			err    error
+			status int
+		}{
+			{"unknown recipient", model.ErrNotFound, http.StatusNotFound},
+			{"insufficient funds", model.ErrInsufficientFunds, http.StatusPaymentRequired},
+			{"daily limit", model.ErrTransferLimitExceeded, http.StatusUnprocessableEntity},
+			{"key reused", model.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				h, repo := newHandler(t)
+				repo.EXPECT().Transfer(gomock.Any(), senderID, "alice", 2550, "for the trip", "key-1", dailyLimit).Return(nil, false, tt.err)
+
+				rr := httptest.NewRecorder()
+				h.Transfer(rr, newTransferRequest(senderID, body, "key-1"))
+
+				assert.Equal(t, tt.status, rr.Code)
+			})
+		}
+	})
+
+	t.Run("daily limit code", func(t *testing.T) {
+		h, repo := newHandler(t)
+		repo.EXPECT().Transfer(gomock.Any(), senderID, "alice", 2550, "for the trip", "key-1", dailyLimit).Return(nil, false, model.ErrTransferLimitExceeded)
+
+		rr := httptest.NewRecorder()
+		h.Transfer(rr, newTransferRequest(senderID, body, "key-1"))
+
+		assert.Contains(t, rr.Body.String(), httperr.CodeTransferLimit)
+	})
+}
+
+func TestBalanceHandler_GetTransfers(t *testing.T) {
+	userID := uuid.New()
+	otherID := uuid.New()
+
+	repo := mocks.NewMockBalanceRepository(gomock.NewController(t))
+	h := NewBalanceHandler(&service.Service{Balance: service.NewBalanceService(repo, time.Minute, 0)})
+
+	repo.EXPECT().GetTransfers(gomock.Any(), userID).Return([]model.PointsTransfer{
+		{ID: uuid.New(), SenderID: otherID, SenderLogin: "bob", RecipientID: userID, RecipientLogin: "alice", Sum: 1000},
+		{ID: uuid.New(), SenderID: userID, SenderLogin: "alice", RecipientID: otherID, RecipientLogin: "bob", Sum: 250},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, TransfersPath, nil)
+	req = req.WithContext(context.WithValue(req.Context(), model.UserIDKey, userID.String()))
+	rr := httptest.NewRecorder()
+	h.GetTransfers(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp []api.PointsTransferResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp, 2)
+	assert.Equal(t, "RECEIVED", resp[0].Direction)
+	assert.Equal(t, "bob", resp[0].Counterparty)
+	assert.Equal(t, 10.0, resp[0].Sum)
+	assert.Equal(t, "SENT", resp[1].Direction)
+	assert.Equal(t, "bob", resp[1].Counterparty)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+)
+
+func (h *BalanceHandler) GetBalanceV2(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	balance, err := h.bs.GetUserBalance(r.Context(), userID)
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	expiring, err := h.bs.GetExpiringPoints(r.Context(), userID)
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	writeEnvelope(w, r, http.StatusOK, balanceResponse(balance, expiring), nil)
+}
+
+// ListWithdrawalsV2 returns the user's withdrawals, an empty list when there
+// are none.
+func (h *BalanceHandler) ListWithdrawalsV2(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	withdrawals, err := h.bs.GetWithdrawals(r.Context(), userID)
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	resp := make([]api.WithdrawalListResponse, 0, len(withdrawals))
+	for _, withdrawal := range withdrawals {
+		resp = append(resp, withdrawalResponse(withdrawal))
+	}
+
+	writeEnvelope(w, r, http.StatusOK, resp, &api.ListMeta{Count: len(resp)})
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/logger"
+)
+
+type DocsHandler struct{}
+
+func NewDocsHandler() *DocsHandler {
+	return &DocsHandler{}
+}
+
+// Spec serves the OpenAPI document.
+func (h *DocsHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	write(w, r, "application/json", api.OpenAPI)
+}
+
+// Page serves the HTML page rendering the OpenAPI document.
+func (h *DocsHandler) Page(w http.ResponseWriter, r *http.Request) {
+	write(w, r, "text/html; charset=utf-8", api.DocsPage)
+}
+
+func write(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logger.FromContext(r.Context()).With("err", err.Error()).Warn()
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/events"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+const (
+	// HeartbeatInterval keeps idle streams from being closed by proxies.
+	HeartbeatInterval = 15 * time.Second
+	// retryMillis is how long browsers wait before reconnecting.
+	retryMillis = 3000
+	// eventResync tells the client that events may have been missed and it
+	// has to reload its orders and balance.
+	eventResync = "resync"
+)
+
+type EventsHandler struct {
+	broker    *events.Broker
+	heartbeat time.Duration
+}
+
+func NewEventsHandler(broker *events.Broker) *EventsHandler {
+	return &EventsHandler{
+		broker:    broker,
+		heartbeat: HeartbeatInterval,
+	}
+}
+
+// Stream sends the user's order and balance changes as server-sent events.
+// A reconnecting client gets the events it missed after Last-Event-ID.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// Streams outlive any server write timeout.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.With("err", err.Error()).Warn()
+	}
+
+	var lastEventID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		// An unparsable ID is unknown to the broker and leads to a resync.
+		lastEventID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || lastEventID == 0 {
+			lastEventID = -1
+		}
+	}
+
+	sub, replay, ok := h.broker.Subscribe(userID, lastEventID)
+	defer h.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+	if !ok {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventResync)
+	}
+	for _, e := range replay {
+		writeEvent(w, e)
+	}
+	if err := rc.Flush(); err != nil {
+		log.With("err", err.Error()).Warn("streaming not supported")
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case e, open := <-sub.Events():
+			if !open {
+				// Dropped by the broker: too slow or shutting down.
+				return
+			}
+			writeEvent(w, e)
+
+		case <-ticker.C:
+			io.WriteString(w, ": ping\n\n")
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, e model.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/events"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsHandler_Stream(t *testing.T) {
+	userID := uuid.New()
+	broker := events.NewBroker(events.DefaultHistorySize)
+	h := NewEventsHandler(broker)
+	h.heartbeat = 20 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), model.UserIDKey, userID.String())
+		h.Stream(w, r.WithContext(ctx))
+	}))
+	defer srv.Close()
+	defer broker.Close()
+
+	broker.Publish(model.Event{ID: 1, UserID: userID, Type: model.EventOrderStatus, Data: json.RawMessage(`{"status":"NEW"}`)})
+	broker.Publish(model.Event{ID: 2, UserID: userID, Type: model.EventOrderStatus, Data: json.RawMessage(`{"status":"PROCESSING"}`)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() string {
+		t.Helper()
+		require.True(t, lines.Scan())
+		return lines.Text()
+	}
+
+	assert.Equal(t, "retry: 3000", next())
+	assert.Empty(t, next())
+
+	// Missed event is replayed.
+	assert.Equal(t, "id: 2", next())
+	assert.Equal(t, "event: order.status", next())
+	assert.Equal(t, `data: {"status":"PROCESSING"}`, next())
+	assert.Empty(t, next())
+
+	// Heartbeat keeps the idle stream open.
+	assert.True(t, strings.HasPrefix(next(), ":"))
+	assert.Empty(t, next())
+
+	broker.Publish(model.Event{ID: 3, UserID: userID, Type: model.EventBalance, Data: json.RawMessage(`{"current":5}`)})
+	for line := next(); line != "id: 3"; line = next() {
+		assert.True(t, line == "" || strings.HasPrefix(line, ":"), "unexpected line %q", line)
+	}
+	assert.Equal(t, "event: balance", next())
+	assert.Equal(t, `data: {"current":5}`, next())
+}
+
+func TestEventsHandler_StreamResync(t *testing.T) {
+	userID := uuid.New()
+	broker := events.NewBroker(events.DefaultHistorySize)
+	h := NewEventsHandler(broker)
+
+	// The client is gone, so the stream ends right after the preamble.
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), model.UserIDKey, userID.String()))
+	cancel()
+
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/user/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	rr := httptest.NewRecorder()
+
+	h.Stream(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "event: resync\n")
+}
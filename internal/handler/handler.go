@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"github.com/mrhyman/gophermart/internal/auth"
+	"github.com/mrhyman/gophermart/internal/events"
+	"github.com/mrhyman/gophermart/internal/health"
 	"github.com/mrhyman/gophermart/internal/service"
 )
 
@@ -8,13 +11,22 @@ type HTTPHandler struct {
 	User    *UserHandler
 	Order   *OrderHandler
 	Balance *BalanceHandler
-	Secret  string
+	Admin   *AdminHandler
+	Health  *HealthHandler
+	Docs    *DocsHandler
+	Events  *EventsHandler
+	Keyring *auth.Keyring
 }
 
-func New(svc service.Service, secret string) *HTTPHandler {
+func New(svc service.Service, keyring *auth.Keyring, checker *health.Checker, broker *events.Broker) *HTTPHandler {
 	return &HTTPHandler{
-		User:    NewUserHandler(&svc, secret),
+		Keyring: keyring,
+		Health:  NewHealthHandler(checker),
+		Docs:    NewDocsHandler(),
+		Events:  NewEventsHandler(broker),
+		User:    NewUserHandler(&svc, keyring),
 		Order:   NewOrderHandler(&svc),
 		Balance: NewBalanceHandler(&svc),
+		Admin:   NewAdminHandler(&svc),
 	}
 }
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/health"
+	"github.com/mrhyman/gophermart/internal/logger"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Live reports that the process is up and serving HTTP.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, http.StatusOK, api.HealthResponse{Status: string(health.StatusOK)})
+}
+
+// Ready runs the dependency checks. Degraded checks keep the service ready.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Check(r.Context())
+
+	resp := api.HealthResponse{
+		Status: string(report.Status),
+		Checks: make([]api.HealthCheckResponse, 0, len(report.Checks)),
+	}
+	for _, res := range report.Checks {
+		item := api.HealthCheckResponse{
+			Name:      res.Name,
+			Status:    string(res.Status),
+			LatencyMs: float64(res.Latency) / float64(time.Millisecond),
+		}
+		if res.Err != nil {
+			item.Error = res.Err.Error()
+		}
+		resp.Checks = append(resp.Checks, item)
+	}
+
+	code := http.StatusOK
+	if report.Status == health.StatusFail {
+		code = http.StatusServiceUnavailable
+	}
+
+	writeHealth(w, r, code, resp)
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, code int, resp api.HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.FromContext(r.Context()).With("err", err.Error()).Error()
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
 	"github.com/mrhyman/gophermart/internal/logger"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/service"
@@ -31,21 +33,21 @@ func (h *OrderHandler) UploadOrder(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusMethodNotAllowed)
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
 		return
 	}
 
 	contentType := r.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "text/plain") {
-		log.With("err", "invalid content type").Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		log.With("err", model.ErrInvalidContentType.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidContentType)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
 		return
 	}
 	defer r.Body.Close()
@@ -53,21 +55,21 @@ func (h *OrderHandler) UploadOrder(w http.ResponseWriter, r *http.Request) {
 	orderNumber := strings.TrimSpace(string(body))
 	if orderNumber == "" {
 		log.With("err", "empty order number").Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
 		return
 	}
 
 	userIDStr, ok := r.Context().Value(model.UserIDKey).(string)
 	if !ok || userIDStr == "" {
 		log.With("err", model.ErrUnknownUser).Warn()
-		http.Error(w, model.ErrUnknownUser.Error(), http.StatusUnauthorized)
+		httperr.Write(w, r, model.ErrUnknownUser)
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
@@ -76,7 +78,7 @@ func (h *OrderHandler) UploadOrder(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.Is(err, model.ErrInvalidOrderNumber):
 			log.With("err", err.Error()).Warn()
-			w.WriteHeader(http.StatusUnprocessableEntity)
+			httperr.Write(w, r, err)
 			return
 
 		case errors.Is(err, model.ErrOrderAlreadyUploaded):
@@ -86,12 +88,12 @@ func (h *OrderHandler) UploadOrder(w http.ResponseWriter, r *http.Request) {
 
 		case errors.Is(err, model.ErrOrderUploadedByAnotherUser):
 			log.With("err", err.Error()).Warn()
-			w.WriteHeader(http.StatusConflict)
+			httperr.Write(w, r, err)
 			return
 
 		default:
 			log.With("err", err.Error()).Error()
-			http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+			httperr.Write(w, r, model.ErrWentWrong)
 			return
 		}
 	}
@@ -104,28 +106,28 @@ func (h *OrderHandler) GetOrderList(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodGet {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusMethodNotAllowed)
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
 		return
 	}
 
 	userIDStr, ok := r.Context().Value(model.UserIDKey).(string)
 	if !ok || userIDStr == "" {
 		log.With("err", model.ErrUnknownUser).Warn()
-		http.Error(w, model.ErrUnknownUser.Error(), http.StatusUnauthorized)
+		httperr.Write(w, r, model.ErrUnknownUser)
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
 	orders, err := h.os.GetUserOrders(r.Context(), userID)
 	if err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
@@ -149,9 +151,55 @@ func (h *OrderHandler) GetOrderList(w http.ResponseWriter, r *http.Request) {
 	enc := json.NewEncoder(w)
 	if err := enc.Encode(resp); err != nil {
 		log.With("err", err.Error())
-		http.Error(w, model.ErrResponseEncoding.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrResponseEncoding)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// GetOrder returns one order of the user with its status history, so support
+// can tell why an order is still being processed.
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	number := chi.URLParam(r, "number")
+
+	order, history, err := h.os.GetUserOrderHistory(r.Context(), userID, number)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			log.With("order", number).Info("order not found")
+		} else {
+			log.With("err", err.Error()).Error()
+		}
+		httperr.Write(w, r, err)
+		return
+	}
+
+	resp := api.OrderDetailResponse{
+		Number:     order.Number,
+		Status:     string(order.Status),
+		Accrual:    util.RoundToTwoDecimals(float64(order.Accrual) / 100),
+		UploadedAt: order.CreatedAt.Format(time.RFC3339),
+		Attempts:   order.Attempts,
+		History:    make([]api.OrderStatusChangeResponse, 0, len(history)),
+	}
+	for _, c := range history {
+		resp.History = append(resp.History, api.OrderStatusChangeResponse{
+			At:      c.ChangedAt.Format(time.RFC3339),
+			From:    string(c.OldStatus),
+			To:      string(c.NewStatus),
+			Accrual: util.RoundToTwoDecimals(float64(c.Accrual) / 100),
+			Attempt: c.Attempt,
+		})
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
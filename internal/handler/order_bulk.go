@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// maxBulkBodyBytes bounds the request body of a bulk upload.
+const maxBulkBodyBytes = 1 << 20
+
+// UploadOrdersBulk accepts up to MaxOrdersPerUpload numbers as a JSON array of
+// strings or as newline separated text and reports the outcome of each.
+func (h *OrderHandler) UploadOrdersBulk(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	numbers, err := readNumbers(http.MaxBytesReader(w, r.Body, maxBulkBodyBytes), r.Header.Get("Content-Type"))
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(numbers) == 0 || len(numbers) > MaxOrdersPerUpload {
+		err := fmt.Errorf("%w: expected 1 to %d orders, got %d", model.ErrInvalidRequestParams, MaxOrdersPerUpload, len(numbers))
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	results, err := h.os.CreateOrders(r.Context(), userID, numbers)
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	resp := api.BulkUploadResponse{
+		Summary: make(map[string]int),
+		Results: make([]api.BulkUploadResult, 0, len(results)),
+	}
+	for _, res := range results {
+		resp.Summary[string(res.Outcome)]++
+		resp.Results = append(resp.Results, api.BulkUploadResult{
+			Number:  res.Number,
+			Outcome: string(res.Outcome),
+		})
+	}
+
+	log.With("count", len(results), "accepted", resp.Summary[string(model.UploadAccepted)]).Info("bulk upload processed")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.With("err", err.Error()).Warn()
+	}
+}
+
+// readNumbers parses a JSON array of strings or newline separated text,
+// skipping blank lines.
+func readNumbers(body io.Reader, contentType string) ([]string, error) {
+	var raw []string
+
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		if err := json.NewDecoder(body).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%w: %s", model.ErrInvalidRequestParams, "expected a JSON array of strings")
+		}
+
+	case strings.HasPrefix(contentType, "text/plain"):
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			raw = append(raw, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %s", model.ErrInvalidRequestParams, err.Error())
+		}
+
+	default:
+		return nil, model.ErrInvalidContentType
+	}
+
+	numbers := make([]string, 0, len(raw))
+	for _, number := range raw {
+		if number = strings.TrimSpace(number); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+
+	return numbers, nil
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestOrderHandler_UploadOrdersBulk(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"json array", "application/json", `["` + validNumber + `", "` + invalidNumber + `"]`},
+		{"newline separated text", "text/plain", validNumber + "\n\n  " + invalidNumber + "  \n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mocks.NewMockOrderRepository(ctrl)
+			h := NewOrderHandler(&service.Service{Order: service.NewOrderService(repo)})
+
+			repo.EXPECT().CreateBatch(gomock.Any(), userID, []string{validNumber}).Return([]model.BatchOrder{
+				{Order: model.Order{Number: validNumber, UserID: userID}, Created: true},
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/orders/bulk", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req = req.WithContext(context.WithValue(req.Context(), model.UserIDKey, userID.String()))
+			rr := httptest.NewRecorder()
+
+			h.UploadOrdersBulk(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+
+			var resp api.BulkUploadResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			assert.Equal(t, []api.BulkUploadResult{
+				{Number: validNumber, Outcome: string(model.UploadAccepted)},
+				{Number: invalidNumber, Outcome: string(model.UploadInvalid)},
+			}, resp.Results)
+			assert.Equal(t, map[string]int{"accepted": 1, "invalid": 1}, resp.Summary)
+		})
+	}
+
+	badRequests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"empty list", "application/json", `[]`, http.StatusBadRequest},
+		{"blank text", "text/plain", "\n \n", http.StatusBadRequest},
+		{"not an array", "application/json", `{"number":"1"}`, http.StatusBadRequest},
+		{"too many", "text/plain", strings.Repeat(validNumber+"\n", MaxOrdersPerUpload+1), http.StatusBadRequest},
+		{"unsupported content type", "application/xml", `<orders/>`, http.StatusBadRequest},
+	}
+
+	for _, tt := range badRequests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewOrderHandler(&service.Service{Order: service.NewOrderService(nil)})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/orders/bulk", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req = req.WithContext(context.WithValue(req.Context(), model.UserIDKey, userID.String()))
+			rr := httptest.NewRecorder()
+
+			h.UploadOrdersBulk(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
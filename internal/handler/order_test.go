@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newOrderDetailRequest(userID uuid.UUID, number string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/user/orders/"+number, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("number", number)
+
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, model.UserIDKey, userID.String())
+
+	return req.WithContext(ctx)
+}
+
+func TestOrderHandler_GetOrder(t *testing.T) {
+	userID := uuid.New()
+	uploadedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("order with status history", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(repo)})
+
+		order := &model.Order{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Number:    validNumber,
+			Status:    model.OrderStatusProcessed,
+			Accrual:   50050,
+			CreatedAt: uploadedAt,
+			Attempts:  4,
+		}
+		repo.EXPECT().GetByNumber(gomock.Any(), validNumber).Return(order, nil)
+		repo.EXPECT().GetStatusHistory(gomock.Any(), order.ID).Return([]model.OrderStatusChange{
+			{OldStatus: model.OrderStatusNew, NewStatus: model.OrderStatusProcessing, Attempt: 1, ChangedAt: uploadedAt.Add(time.Minute)},
+			{OldStatus: model.OrderStatusProcessing, NewStatus: model.OrderStatusProcessed, Accrual: 50050, Attempt: 4, ChangedAt: uploadedAt.Add(time.Hour)},
+		}, nil)
+
+		rr := httptest.NewRecorder()
+		h.GetOrder(rr, newOrderDetailRequest(userID, validNumber))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp api.OrderDetailResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, api.OrderDetailResponse{
+			Number:     validNumber,
+			Status:     "PROCESSED",
+			Accrual:    500.5,
+			UploadedAt: "2024-03-01T10:00:00Z",
+			Attempts:   4,
+			History: []api.OrderStatusChangeResponse{
+				{At: "2024-03-01T10:01:00Z", From: "NEW", To: "PROCESSING", Attempt: 1},
+				{At: "2024-03-01T11:00:00Z", From: "PROCESSING", To: "PROCESSED", Accrual: 500.5, Attempt: 4},
+			},
+		}, resp)
+	})
+
+	t.Run("order of another user is not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(repo)})
+
+		repo.EXPECT().GetByNumber(gomock.Any(), validNumber).Return(&model.Order{UserID: uuid.New(), Number: validNumber}, nil)
+
+		rr := httptest.NewRecorder()
+		h.GetOrder(rr, newOrderDetailRequest(userID, validNumber))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/util"
+)
+
+// Outcomes of an order in a v2 list upload.
+const (
+	OutcomeCreated  = "created"
+	OutcomeExists   = "exists"
+	OutcomeRejected = "rejected"
+)
+
+// MaxOrdersPerUpload caps the number of orders in one bulk or v2 list upload.
+const MaxOrdersPerUpload = 1000
+
+// UploadOrderV2 accepts {"number": "..."} or a list of such objects. A single
+// order is answered with the order resource, 201 when it was created and 200
+// when the user had already uploaded it. A list is answered with 200 and the
+// outcome of every order.
+func (h *OrderHandler) UploadOrderV2(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		log.With("err", model.ErrInvalidContentType.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidContentType)
+		return
+	}
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+	defer r.Body.Close()
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []api.OrderUploadRequest
+		if err := json.Unmarshal(body, &reqs); err != nil || len(reqs) == 0 {
+			log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
+			httperr.Write(w, r, model.ErrInvalidRequestParams)
+			return
+		}
+		if len(reqs) > MaxOrdersPerUpload {
+			err := fmt.Errorf("%w: at most %d orders per upload", model.ErrInvalidRequestParams, MaxOrdersPerUpload)
+			log.With("err", err.Error()).Warn()
+			httperr.Write(w, r, err)
+			return
+		}
+
+		numbers := make([]string, 0, len(reqs))
+		for _, req := range reqs {
+			numbers = append(numbers, strings.TrimSpace(req.Number))
+		}
+
+		uploaded, err := h.os.CreateOrders(r.Context(), userID, numbers)
+		if err != nil {
+			log.With("err", err.Error()).Error()
+			httperr.Write(w, r, err)
+			return
+		}
+
+		results := make([]api.OrderUploadResult, 0, len(uploaded))
+		for _, res := range uploaded {
+			results = append(results, uploadResultV2(r, res))
+		}
+
+		writeEnvelope(w, r, http.StatusOK, results, &api.ListMeta{Count: len(results)})
+		return
+	}
+
+	var req api.OrderUploadRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
+		return
+	}
+
+	res := h.uploadOne(r, userID, strings.TrimSpace(req.Number))
+	switch res.Outcome {
+	case OutcomeCreated:
+		w.Header().Set("Location", res.Order.Links.Self)
+		writeEnvelope(w, r, http.StatusCreated, res.Order, nil)
+	case OutcomeExists:
+		writeEnvelope(w, r, http.StatusOK, res.Order, nil)
+	default:
+		httperr.WriteProblem(w, *res.Error)
+	}
+}
+
+func (h *OrderHandler) uploadOne(r *http.Request, userID uuid.UUID, number string) api.OrderUploadResult {
+	log := logger.FromContext(r.Context())
+
+	if number == "" {
+		problem := httperr.New(r, model.ErrInvalidRequestParams)
+		return api.OrderUploadResult{Number: number, Outcome: OutcomeRejected, Error: &problem}
+	}
+
+	order, err := h.os.CreateOrder(r.Context(), userID, number)
+	switch {
+	case err == nil:
+		return api.OrderUploadResult{Number: number, Outcome: OutcomeCreated, Order: orderResource(order)}
+
+	case errors.Is(err, model.ErrOrderAlreadyUploaded):
+		log.With("order", number).Info("order already uploaded by this user")
+		return api.OrderUploadResult{Number: number, Outcome: OutcomeExists, Order: orderResource(order)}
+
+	case errors.Is(err, model.ErrInvalidOrderNumber), errors.Is(err, model.ErrOrderUploadedByAnotherUser):
+		log.With("order", number, "err", err.Error()).Warn()
+
+	default:
+		log.With("order", number, "err", err.Error()).Error()
+	}
+
+	problem := httperr.New(r, err)
+	return api.OrderUploadResult{Number: number, Outcome: OutcomeRejected, Error: &problem}
+}
+
+// ListOrdersV2 returns the user's orders, an empty list when there are none.
+func (h *OrderHandler) ListOrdersV2(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	orders, err := h.os.GetUserOrders(r.Context(), userID)
+	if err != nil {
+		log.With("err", err.Error()).Error()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	resp := make([]api.OrderResource, 0, len(orders))
+	for _, o := range orders {
+		resp = append(resp, *orderResource(o))
+	}
+
+	writeEnvelope(w, r, http.StatusOK, resp, &api.ListMeta{Count: len(resp)})
+}
+
+// GetOrderV2 returns one order of the user.
+func (h *OrderHandler) GetOrderV2(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.With("err", err.Error()).Warn()
+		httperr.Write(w, r, err)
+		return
+	}
+
+	number := chi.URLParam(r, "number")
+
+	order, err := h.os.GetUserOrder(r.Context(), userID, number)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			log.With("order", number).Info("order not found")
+		} else {
+			log.With("err", err.Error()).Error()
+		}
+		httperr.Write(w, r, err)
+		return
+	}
+
+	writeEnvelope(w, r, http.StatusOK, orderResource(order), nil)
+}
+
+func uploadResultV2(r *http.Request, res model.UploadResult) api.OrderUploadResult {
+	switch res.Outcome {
+	case model.UploadAccepted:
+		return api.OrderUploadResult{Number: res.Number, Outcome: OutcomeCreated, Order: orderResource(res.Order)}
+	case model.UploadAlreadyYours:
+		return api.OrderUploadResult{Number: res.Number, Outcome: OutcomeExists, Order: orderResource(res.Order)}
+	default:
+		problem := httperr.New(r, res.Err())
+		return api.OrderUploadResult{Number: res.Number, Outcome: OutcomeRejected, Error: &problem}
+	}
+}
+
+func orderResource(o *model.Order) *api.OrderResource {
+	return &api.OrderResource{
+		Number:     o.Number,
+		Status:     string(o.Status),
+		Accrual:    util.RoundToTwoDecimals(float64(o.Accrual) / 100),
+		UploadedAt: o.CreatedAt.Format(time.RFC3339),
+		Links: api.OrderLinks{
+			Self: V2Prefix + "/orders/" + o.Number,
+		},
+	}
+}
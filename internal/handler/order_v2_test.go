@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	validNumber   = "12345678903"
+	invalidNumber = "12345678900"
+)
+
+func newOrderV2Request(t *testing.T, userID uuid.UUID, body string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, V2Prefix+"/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req.WithContext(context.WithValue(req.Context(), model.UserIDKey, userID.String()))
+}
+
+func TestOrderHandler_UploadOrderV2(t *testing.T) {
+	userID := uuid.New()
+	created := &model.Order{ID: uuid.New(), UserID: userID, Number: validNumber, Status: model.OrderStatusNew, CreatedAt: time.Now()}
+
+	t.Run("single order created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(repo)})
+
+		repo.EXPECT().GetByNumber(gomock.Any(), validNumber).Return(nil, model.ErrNotFound)
+		repo.EXPECT().Create(gomock.Any(), userID, validNumber).Return(created, nil)
+
+		rr := httptest.NewRecorder()
+		h.UploadOrderV2(rr, newOrderV2Request(t, userID, `{"number":"`+validNumber+`"}`))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, V2Prefix+"/orders/"+validNumber, rr.Header().Get("Location"))
+
+		var resp api.Envelope[api.OrderResource]
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, validNumber, resp.Data.Number)
+		assert.Equal(t, string(model.OrderStatusNew), resp.Data.Status)
+		assert.Equal(t, V2Prefix+"/orders/"+validNumber, resp.Data.Links.Self)
+		assert.Nil(t, resp.Meta)
+	})
+
+	t.Run("single order already uploaded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(repo)})
+
+		repo.EXPECT().GetByNumber(gomock.Any(), validNumber).Return(created, nil)
+
+		rr := httptest.NewRecorder()
+		h.UploadOrderV2(rr, newOrderV2Request(t, userID, `{"number":"`+validNumber+`"}`))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("single invalid order", func(t *testing.T) {
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(nil)})
+
+		rr := httptest.NewRecorder()
+		h.UploadOrderV2(rr, newOrderV2Request(t, userID, `{"number":"`+invalidNumber+`"}`))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, httperr.ContentType, rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("list reports every order", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(repo)})
+
+		other := model.Order{ID: uuid.New(), UserID: uuid.New(), Number: "79927398713"}
+		repo.EXPECT().CreateBatch(gomock.Any(), userID, []string{validNumber, other.Number}).Return([]model.BatchOrder{
+			{Order: *created, Created: true},
+			{Order: other},
+		}, nil)
+
+		body := `[{"number":"` + validNumber + `"},{"number":"` + invalidNumber + `"},{"number":"` + other.Number + `"}]`
+		rr := httptest.NewRecorder()
+		h.UploadOrderV2(rr, newOrderV2Request(t, userID, body))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp api.Envelope[[]api.OrderUploadResult]
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Len(t, resp.Data, 3)
+		require.NotNil(t, resp.Meta)
+		assert.Equal(t, 3, resp.Meta.Count)
+
+		assert.Equal(t, OutcomeCreated, resp.Data[0].Outcome)
+		require.NotNil(t, resp.Data[0].Order)
+
+		assert.Equal(t, OutcomeRejected, resp.Data[1].Outcome)
+		require.NotNil(t, resp.Data[1].Error)
+		assert.Equal(t, httperr.CodeInvalidOrderNumber, resp.Data[1].Error.Code)
+
+		assert.Equal(t, OutcomeRejected, resp.Data[2].Outcome)
+		require.NotNil(t, resp.Data[2].Error)
+		assert.Equal(t, httperr.CodeOrderConflict, resp.Data[2].Error.Code)
+	})
+
+	t.Run("rejects text body", func(t *testing.T) {
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(nil)})
+
+		req := newOrderV2Request(t, userID, validNumber)
+		req.Header.Set("Content-Type", "text/plain")
+		rr := httptest.NewRecorder()
+		h.UploadOrderV2(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("rejects too many orders", func(t *testing.T) {
+		h := NewOrderHandler(&service.Service{Order: service.NewOrderService(nil)})
+
+		items := make([]string, MaxOrdersPerUpload+1)
+		for i := range items {
+			items[i] = `{"number":"` + validNumber + `"}`
+		}
+		rr := httptest.NewRecorder()
+		h.UploadOrderV2(rr, newOrderV2Request(t, userID, "["+strings.Join(items, ",")+"]"))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestOrderHandler_ListOrdersV2(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockOrderRepository(ctrl)
+	h := NewOrderHandler(&service.Service{Order: service.NewOrderService(repo)})
+
+	userID := uuid.New()
+	repo.EXPECT().GetUserOrders(gomock.Any(), userID).Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, V2Prefix+"/orders", nil)
+	req = req.WithContext(context.WithValue(req.Context(), model.UserIDKey, userID.String()))
+	rr := httptest.NewRecorder()
+
+	h.ListOrdersV2(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"count":0}}`, rr.Body.String())
+}
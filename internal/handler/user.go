@@ -7,20 +7,21 @@ import (
 
 	"github.com/mrhyman/gophermart/api"
 	"github.com/mrhyman/gophermart/internal/auth"
+	"github.com/mrhyman/gophermart/internal/httperr"
 	"github.com/mrhyman/gophermart/internal/logger"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/service"
 )
 
 type UserHandler struct {
-	us     *service.UserService
-	secret string
+	us      *service.UserService
+	keyring *auth.Keyring
 }
 
-func NewUserHandler(svc *service.Service, secret string) *UserHandler {
+func NewUserHandler(svc *service.Service, keyring *auth.Keyring) *UserHandler {
 	return &UserHandler{
-		us:     svc.User,
-		secret: secret,
+		us:      svc.User,
+		keyring: keyring,
 	}
 }
 
@@ -31,19 +32,19 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusMethodNotAllowed)
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
 		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
 		return
 	}
 
 	if req.Login == "" || req.Password == "" {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
 		return
 	}
 
@@ -53,19 +54,20 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 		switch {
 		case errors.As(err, &existsErr):
-			w.WriteHeader(http.StatusConflict)
+			log.Warn("login already taken")
+			httperr.Write(w, r, err)
 			return
 
 		default:
 			log.With("err", err.Error()).Error()
-			http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+			httperr.Write(w, r, model.ErrWentWrong)
 			return
 		}
 	}
 
 	if err := h.setAuthCookie(w, userID); err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
@@ -79,19 +81,19 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusMethodNotAllowed)
+		httperr.Write(w, r, model.ErrMethodNotAllowed)
 		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
 		return
 	}
 
 	if req.Login == "" || req.Password == "" {
 		log.With("err", model.ErrInvalidRequestParams.Error()).Warn()
-		http.Error(w, model.ErrInvalidRequestParams.Error(), http.StatusBadRequest)
+		httperr.Write(w, r, model.ErrInvalidRequestParams)
 		return
 	}
 
@@ -100,18 +102,19 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.Is(err, model.ErrInvalidCredentials):
 			log.With("err", err.Error()).Warn()
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			httperr.Write(w, r, err)
+			return
 
 		default:
 			log.With("err", err.Error()).Error()
-			http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+			httperr.Write(w, r, model.ErrWentWrong)
 			return
 		}
 	}
 
 	if err := h.setAuthCookie(w, userID); err != nil {
 		log.With("err", err.Error()).Error()
-		http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
+		httperr.Write(w, r, model.ErrWentWrong)
 		return
 	}
 
@@ -119,12 +122,7 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) setAuthCookie(w http.ResponseWriter, userID string) error {
-	ce, err := auth.NewCookieEncoder(h.secret)
-	if err != nil {
-		return err
-	}
-
-	encodedUserID, err := ce.EncodeUserID(userID)
+	encodedUserID, err := h.keyring.EncodeUserID(userID)
 	if err != nil {
 		return err
 	}
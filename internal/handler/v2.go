@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// V2Prefix is the route group of the second API version.
+const V2Prefix = "/api/v2"
+
+// userIDFromRequest returns the user authenticated by the auth middleware.
+func userIDFromRequest(r *http.Request) (uuid.UUID, error) {
+	userIDStr, ok := r.Context().Value(model.UserIDKey).(string)
+	if !ok || userIDStr == "" {
+		return uuid.Nil, model.ErrUnknownUser
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("parse user id: %w", err)
+	}
+
+	return userID, nil
+}
+
+// writeEnvelope replies with data wrapped in the v2 envelope. meta is
+// omitted for single resources.
+func writeEnvelope[T any](w http.ResponseWriter, r *http.Request, status int, data T, meta *api.ListMeta) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(api.Envelope[T]{Data: data, Meta: meta}); err != nil {
+		logger.FromContext(r.Context()).With("err", err.Error()).Warn()
+	}
+}
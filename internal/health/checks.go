@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/client"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type MigrationVersioner interface {
+	MigrationVersion(ctx context.Context) (uint, bool, error)
+}
+
+type CircuitReporter interface {
+	CircuitState() client.CircuitState
+}
+
+type Heartbeater interface {
+	LastHeartbeat() time.Time
+}
+
+// Ping checks that the database is reachable.
+func Ping(p Pinger) CheckFunc {
+	return p.PingContext
+}
+
+// Migrations checks that the schema is at least at the expected version and
+// not left dirty by a failed migration. A newer schema is accepted so that
+// old replicas stay ready during a rolling deploy.
+func Migrations(v MigrationVersioner, expected uint) CheckFunc {
+	return func(ctx context.Context) error {
+		version, dirty, err := v.MigrationVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", model.ErrMigrationsDirty, version)
+		}
+		if version < expected {
+			return fmt.Errorf("%w: version %d, expected %d", model.ErrMigrationsOutdated, version, expected)
+		}
+		return nil
+	}
+}
+
+// Circuit fails while the circuit breaker to the accrual system is open.
+func Circuit(c CircuitReporter) CheckFunc {
+	return func(ctx context.Context) error {
+		if state := c.CircuitState(); state == client.CircuitOpen {
+			return model.ErrAccrualCircuitOpen
+		}
+		return nil
+	}
+}
+
+// Heartbeat fails when the last heartbeat is older than maxAge.
+func Heartbeat(h Heartbeater, maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		if age := time.Since(h.LastHeartbeat()); age > maxAge {
+			return fmt.Errorf("%w: last beat %s ago", model.ErrWorkerStalled, age.Truncate(time.Second))
+		}
+		return nil
+	}
+}
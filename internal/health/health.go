@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+type Status string
+
+const (
+	StatusOK Status = "ok"
+	// StatusDegraded is reported by non critical checks and keeps the service ready.
+	StatusDegraded Status = "degraded"
+	StatusFail     Status = "fail"
+)
+
+// CheckFunc reports a problem with a dependency by returning an error.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+type Result struct {
+	Name    string
+	Status  Status
+	Latency time.Duration
+	Err     error
+}
+
+type Report struct {
+	Status Status
+	Checks []Result
+}
+
+// Checker runs readiness checks. Once Shutdown is called it reports the
+// service as not ready regardless of the checks, so that load balancers stop
+// routing traffic while in-flight requests are drained.
+type Checker struct {
+	timeout      time.Duration
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+// New creates a checker that gives every check at most timeout to finish.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a named check. A failing critical check makes the service not
+// ready, a failing non critical one only degrades it. Register is not safe to
+// call concurrently with Check.
+func (c *Checker) Register(name string, critical bool, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, critical: critical, fn: fn})
+}
+
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Check runs all registered checks concurrently and aggregates their results
+// in registration order.
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+
+	if c.ShuttingDown() {
+		report.Checks = append(report.Checks, Result{
+			Name:   "shutdown",
+			Status: StatusFail,
+			Err:    model.ErrShuttingDown,
+		})
+	}
+
+	for _, res := range report.Checks {
+		switch {
+		case res.Status == StatusFail:
+			report.Status = StatusFail
+		case res.Status == StatusDegraded && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, chk check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := chk.fn(ctx)
+	res := Result{Name: chk.name, Status: StatusOK, Latency: time.Since(start), Err: err}
+
+	if err != nil {
+		res.Status = StatusDegraded
+		if chk.critical {
+			res.Status = StatusFail
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/client"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(context.Context) error { return nil }
+
+func failing(context.Context) error { return errors.New("boom") }
+
+func TestChecker_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		register   func(c *Checker)
+		wantStatus Status
+	}{
+		{
+			name: "all checks pass",
+			register: func(c *Checker) {
+				c.Register("a", true, ok)
+				c.Register("b", false, ok)
+			},
+			wantStatus: StatusOK,
+		},
+		{
+			name: "non critical failure degrades",
+			register: func(c *Checker) {
+				c.Register("a", true, ok)
+				c.Register("b", false, failing)
+			},
+			wantStatus: StatusDegraded,
+		},
+		{
+			name: "critical failure fails",
+			register: func(c *Checker) {
+				c.Register("a", true, failing)
+				c.Register("b", false, failing)
+			},
+			wantStatus: StatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(time.Second)
+			tt.register(c)
+
+			report := c.Check(context.Background())
+
+			assert.Equal(t, tt.wantStatus, report.Status)
+			require.Len(t, report.Checks, 2)
+			assert.Equal(t, "a", report.Checks[0].Name)
+			assert.Equal(t, "b", report.Checks[1].Name)
+		})
+	}
+
+	t.Run("slow check times out", func(t *testing.T) {
+		c := New(10 * time.Millisecond)
+		c.Register("slow", true, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		report := c.Check(context.Background())
+
+		assert.Equal(t, StatusFail, report.Status)
+		assert.ErrorIs(t, report.Checks[0].Err, context.DeadlineExceeded)
+	})
+
+	t.Run("shutdown fails readiness", func(t *testing.T) {
+		c := New(time.Second)
+		c.Register("a", true, ok)
+
+		c.Shutdown()
+		report := c.Check(context.Background())
+
+		assert.Equal(t, StatusFail, report.Status)
+		assert.ErrorIs(t, report.Checks[len(report.Checks)-1].Err, model.ErrShuttingDown)
+	})
+}
+
+type fakeVersioner struct {
+	version uint
+	dirty   bool
+}
+
+func (f fakeVersioner) MigrationVersion(context.Context) (uint, bool, error) {
+	return f.version, f.dirty, nil
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, Migrations(fakeVersioner{version: 5}, 5)(ctx))
+	assert.NoError(t, Migrations(fakeVersioner{version: 6}, 5)(ctx), "newer schema is accepted")
+	assert.ErrorIs(t, Migrations(fakeVersioner{version: 4}, 5)(ctx), model.ErrMigrationsOutdated)
+	assert.ErrorIs(t, Migrations(fakeVersioner{version: 5, dirty: true}, 5)(ctx), model.ErrMigrationsDirty)
+}
+
+type fakeCircuit client.CircuitState
+
+func (f fakeCircuit) CircuitState() client.CircuitState { return client.CircuitState(f) }
+
+type fakeHeartbeat time.Time
+
+func (f fakeHeartbeat) LastHeartbeat() time.Time { return time.Time(f) }
+
+func TestCircuitAndHeartbeat(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, Circuit(fakeCircuit(client.CircuitClosed))(ctx))
+	assert.NoError(t, Circuit(fakeCircuit(client.CircuitHalfOpen))(ctx))
+	assert.ErrorIs(t, Circuit(fakeCircuit(client.CircuitOpen))(ctx), model.ErrAccrualCircuitOpen)
+
+	assert.NoError(t, Heartbeat(fakeHeartbeat(time.Now()), time.Minute)(ctx))
+	assert.ErrorIs(t, Heartbeat(fakeHeartbeat(time.Now().Add(-2*time.Minute)), time.Minute)(ctx), model.ErrWorkerStalled)
+}
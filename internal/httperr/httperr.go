@@ -0,0 +1,120 @@
+package httperr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+const ContentType = "application/problem+json"
+
+// Stable error codes returned in the code member of problem bodies.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeInvalidContentType = "invalid_content_type"
+	CodeInvalidEncoding    = "invalid_encoding"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeNotFound           = "not_found"
+	CodeAlreadyExists      = "already_exists"
+	CodeInvalidOrderNumber = "invalid_order_number"
+	CodeOrderConflict      = "order_uploaded_by_another_user"
+	CodeInsufficientFunds  = "insufficient_funds"
+	CodeHoldNotActive      = "hold_not_active"
+	CodeRefundExceeds      = "refund_exceeds_withdrawal"
+	CodeTransferLimit      = "transfer_limit_exceeded"
+	CodeIdempotencyReused  = "idempotency_key_reused"
+	CodeAccrualNotCredited = "accrual_not_credited"
+	CodeUnavailable        = "service_unavailable"
+	CodeInternal           = "internal_error"
+)
+
+type mapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// mappings is checked in order with errors.Is; the first match wins.
+var mappings = []mapping{
+	{model.ErrInvalidRequestParams, http.StatusBadRequest, CodeInvalidRequest},
+	{model.ErrInvalidContentType, http.StatusBadRequest, CodeInvalidContentType},
+	{model.ErrCompressReading, http.StatusBadRequest, CodeInvalidEncoding},
+	{model.ErrMethodNotAllowed, http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+	{model.ErrUnknownUser, http.StatusUnauthorized, CodeUnauthenticated},
+	{model.ErrCookieDecoding, http.StatusUnauthorized, CodeUnauthenticated},
+	{model.ErrInvalidCredentials, http.StatusUnauthorized, CodeInvalidCredentials},
+	{model.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{model.ErrInvalidOrderNumber, http.StatusUnprocessableEntity, CodeInvalidOrderNumber},
+	{model.ErrOrderUploadedByAnotherUser, http.StatusConflict, CodeOrderConflict},
+	{model.ErrInsufficientFunds, http.StatusPaymentRequired, CodeInsufficientFunds},
+	{model.ErrHoldNotActive, http.StatusConflict, CodeHoldNotActive},
+	{model.ErrRefundExceedsWithdrawal, http.StatusConflict, CodeRefundExceeds},
+	{model.ErrTransferLimitExceeded, http.StatusUnprocessableEntity, CodeTransferLimit},
+	{model.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyReused},
+	{model.ErrAccrualNotCredited, http.StatusConflict, CodeAccrualNotCredited},
+	{model.ErrShuttingDown, http.StatusServiceUnavailable, CodeUnavailable},
+}
+
+// Resolve maps a domain error to an HTTP status and a stable error code.
+// Unknown errors are internal server errors.
+func Resolve(err error) (int, string) {
+	var existsErr *model.AlreadyExistsError
+	if errors.As(err, &existsErr) {
+		return http.StatusConflict, CodeAlreadyExists
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return m.status, m.code
+		}
+	}
+
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// New describes err as a problem of the request r. The request ID is
+// included so it can be quoted in support tickets. Details of internal errors
+// are not exposed.
+func New(r *http.Request, err error) api.Problem {
+	status, code := Resolve(err)
+
+	detail := err.Error()
+	if status >= http.StatusInternalServerError {
+		detail = model.ErrWentWrong.Error()
+	}
+
+	problem := api.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+	if id, ok := r.Context().Value(model.RequestIDKey).(string); ok {
+		problem.RequestID = id
+	}
+
+	return problem
+}
+
+// Write replies with an application/problem+json body describing err.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, New(r, err))
+}
+
+// WriteProblem replies with the problem as it is.
+func WriteProblem(w http.ResponseWriter, problem api.Problem) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", ContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+
+	_ = json.NewEncoder(w).Encode(problem)
+}
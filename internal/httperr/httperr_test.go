@@ -0,0 +1,89 @@
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid params", model.ErrInvalidRequestParams, http.StatusBadRequest, CodeInvalidRequest},
+		{"method", model.ErrMethodNotAllowed, http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"unknown user", model.ErrUnknownUser, http.StatusUnauthorized, CodeUnauthenticated},
+		{"credentials", model.ErrInvalidCredentials, http.StatusUnauthorized, CodeInvalidCredentials},
+		{"invalid order", model.ErrInvalidOrderNumber, http.StatusUnprocessableEntity, CodeInvalidOrderNumber},
+		{"order conflict", model.ErrOrderUploadedByAnotherUser, http.StatusConflict, CodeOrderConflict},
+		{"wrapped insufficient funds", fmt.Errorf("withdraw: %w", model.ErrInsufficientFunds), http.StatusPaymentRequired, CodeInsufficientFunds},
+		{"already exists", model.NewAlreadyExistsError("user", "alice", errors.New("duplicate")), http.StatusConflict, CodeAlreadyExists},
+		{"not found", model.ErrNotFound, http.StatusNotFound, CodeNotFound},
+		{"hold not active", model.ErrHoldNotActive, http.StatusConflict, CodeHoldNotActive},
+		{"refund exceeds withdrawal", model.ErrRefundExceedsWithdrawal, http.StatusConflict, CodeRefundExceeds},
+		{"transfer limit exceeded", model.ErrTransferLimitExceeded, http.StatusUnprocessableEntity, CodeTransferLimit},
+		{"idempotency key reused", model.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyReused},
+		{"accrual not credited", model.ErrAccrualNotCredited, http.StatusConflict, CodeAccrualNotCredited},
+		{"unknown error", errors.New("connection reset"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := Resolve(tt.err)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("client error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/orders", nil)
+		req = req.WithContext(context.WithValue(req.Context(), model.RequestIDKey, "req-1"))
+		rr := httptest.NewRecorder()
+
+		Write(rr, req, model.ErrInvalidOrderNumber)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, ContentType, rr.Header().Get("Content-Type"))
+
+		var problem api.Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+		assert.Equal(t, api.Problem{
+			Type:      "about:blank",
+			Title:     "Unprocessable Entity",
+			Status:    http.StatusUnprocessableEntity,
+			Detail:    model.ErrInvalidOrderNumber.Error(),
+			Instance:  "/api/user/orders",
+			Code:      CodeInvalidOrderNumber,
+			RequestID: "req-1",
+		}, problem)
+	})
+
+	t.Run("internal error hides details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
+		rr := httptest.NewRecorder()
+
+		Write(rr, req, errors.New("pq: relation does not exist"))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "pq:")
+
+		var problem api.Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+		assert.Equal(t, CodeInternal, problem.Code)
+		assert.Equal(t, model.ErrWentWrong.Error(), problem.Detail)
+	})
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// Sampling keeps the first samplingInitial entries with the same level and
+// message per second, then every samplingThereafter-th one.
+const (
+	samplingTick       = time.Second
+	samplingInitial    = 100
+	samplingThereafter = 100
+)
+
+var ErrUnknownFormat = errors.New("unknown log format")
+
+// Options describe how Build sets up the logger.
+type Options struct {
+	// Format is console for development or json for production.
+	Format string
+	Level  string
+	// Sampling drops repeated entries under load.
+	Sampling bool
+	// Output is stdout, stderr or a path to a file rotated by size.
+	Output     string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+}
+
+// Build creates a logger from opts. Sensitive fields are redacted in every
+// mode. The returned closer releases the log file and is nil for stdout and
+// stderr.
+func Build(opts Options) (*zap.SugaredLogger, io.Closer, error) {
+	if err := SetLevel(opts.Level); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		enc     zapcore.Encoder
+		zapOpts = []zap.Option{zap.AddCaller()}
+	)
+	switch opts.Format {
+	case FormatJSON:
+		encCfg := zap.NewProductionEncoderConfig()
+		encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		enc = zapcore.NewJSONEncoder(encCfg)
+		zapOpts = append(zapOpts, zap.AddStacktrace(zapcore.ErrorLevel))
+	case FormatConsole, "":
+		enc = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+		zapOpts = append(zapOpts, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	default:
+		return nil, nil, ErrUnknownFormat
+	}
+
+	ws, closer := output(opts)
+
+	core := redact(zapcore.NewCore(enc, ws, level))
+	if opts.Sampling {
+		core = zapcore.NewSamplerWithOptions(core, samplingTick, samplingInitial, samplingThereafter)
+	}
+
+	return zap.New(core, zapOpts...).Sugar(), closer, nil
+}
+
+func output(opts Options) (zapcore.WriteSyncer, io.Closer) {
+	switch opts.Output {
+	case OutputStdout:
+		return zapcore.Lock(os.Stdout), nil
+	case OutputStderr, "":
+		return zapcore.Lock(os.Stderr), nil
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   opts.Output,
+		MaxSize:    opts.MaxSizeMB,
+		MaxBackups: opts.MaxBackups,
+		MaxAge:     opts.MaxAgeDays,
+		Compress:   true,
+	}
+	return zapcore.AddSync(file), file
+}
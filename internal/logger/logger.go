@@ -2,16 +2,24 @@ package logger
 
 import (
 	"context"
-
 	"log/slog"
+	"sync/atomic"
 
 	"github.com/mrhyman/gophermart/internal/model"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 type ctxKey struct{}
 
+// level is shared by all loggers built with New and Build so it can be
+// changed at runtime.
+var level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+// defaultLogger is returned by FromContext when the context carries none.
+var defaultLogger atomic.Pointer[zap.SugaredLogger]
+
 func WithinContext(ctx context.Context, logger *zap.SugaredLogger) context.Context {
 	return context.WithValue(ctx, ctxKey{}, logger)
 }
@@ -20,11 +28,29 @@ func FromContext(ctx context.Context) *zap.SugaredLogger {
 	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok && l != nil {
 		return l
 	}
-	return New()
+	return Default()
+}
+
+// WithTraceContext puts a logger carrying the trace and span IDs of the span
+// in ctx into ctx, so log lines can be matched with traces.
+func WithTraceContext(ctx context.Context) context.Context {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ctx
+	}
+
+	return WithinContext(ctx, FromContext(ctx).With(
+		"trace_id", sc.TraceID().String(),
+		"span_id", sc.SpanID().String(),
+	))
 }
 
+// New builds a development logger writing human readable lines to stderr.
 func New() *zap.SugaredLogger {
-	logger, err := zap.NewDevelopment()
+	cfg := zap.NewDevelopmentConfig()
+	cfg.Level = level
+
+	logger, err := cfg.Build(zap.WrapCore(redact))
 	if err != nil {
 		slog.ErrorContext(context.Background(), model.ErrLoggerSetup.Error(), slog.String("err", err.Error()))
 	}
@@ -35,3 +61,24 @@ func New() *zap.SugaredLogger {
 func NewWithCore(core zapcore.Core) *zap.SugaredLogger {
 	return zap.New(core).Sugar()
 }
+
+// Default returns the logger set with SetDefault, building a development
+// logger on first use if none was set.
+func Default() *zap.SugaredLogger {
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+	defaultLogger.CompareAndSwap(nil, New())
+	return defaultLogger.Load()
+}
+
+// SetDefault makes l the logger used when a context carries none.
+func SetDefault(l *zap.SugaredLogger) {
+	defaultLogger.Store(l)
+}
+
+// SetLevel changes the level of every logger built with New.
+// The current level is kept when lvl is not a valid zap level.
+func SetLevel(lvl string) error {
+	return level.UnmarshalText([]byte(lvl))
+}
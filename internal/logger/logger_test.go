@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
 )
@@ -139,3 +140,29 @@ func TestContextIntegration(t *testing.T) {
 		assert.Equal(t, "from ctx2", recorded2.All()[0].Message)
 	})
 }
+
+func TestWithTraceContext(t *testing.T) {
+	t.Run("adds trace fields", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		ctx := WithinContext(context.Background(), NewWithCore(core))
+
+		traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceID,
+			SpanID:  spanID,
+		}))
+
+		FromContext(WithTraceContext(ctx)).Info("hello")
+
+		require.Equal(t, 1, logs.Len())
+		fields := logs.All()[0].ContextMap()
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields["trace_id"])
+		assert.Equal(t, "00f067aa0ba902b7", fields["span_id"])
+	})
+
+	t.Run("no span keeps context", func(t *testing.T) {
+		ctx := context.Background()
+		assert.Equal(t, ctx, WithTraceContext(ctx))
+	})
+}
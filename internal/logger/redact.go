@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// visibleOrderDigits is how many trailing digits of an order number are
+	// kept so support can still tell orders apart.
+	visibleOrderDigits = 4
+)
+
+// secretKeys are fields whose values are never logged.
+var secretKeys = map[string]bool{
+	"login":         true,
+	"password":      true,
+	"cookie":        true,
+	"token":         true,
+	"authorization": true,
+}
+
+// orderKeys are fields holding order numbers, logged masked.
+var orderKeys = map[string]bool{
+	"order":       true,
+	"ordernumber": true,
+	"number":      true,
+}
+
+// orderListKeys are fields holding lists of order numbers, each logged masked.
+var orderListKeys = map[string]bool{
+	"orders":  true,
+	"numbers": true,
+}
+
+// redactCore masks sensitive fields before they reach the wrapped core.
+type redactCore struct {
+	zapcore.Core
+}
+
+func redact(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		masked, ok := redactField(f)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, len(fields))
+			copy(out, fields)
+		}
+		out[i] = masked
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func redactField(f zapcore.Field) (zapcore.Field, bool) {
+	key := strings.ToLower(strings.ReplaceAll(f.Key, "_", ""))
+
+	switch {
+	case secretKeys[key]:
+		return zap.String(f.Key, redacted), true
+	case orderKeys[key]:
+		return zap.String(f.Key, maskOrder(fieldString(f))), true
+	case orderListKeys[key]:
+		return maskOrderList(f), true
+	}
+	return f, false
+}
+
+// maskOrderList masks every element of an array field. Anything else is
+// masked as a single order number.
+func maskOrderList(f zapcore.Field) zapcore.Field {
+	if f.Type != zapcore.ArrayMarshalerType {
+		return zap.String(f.Key, maskOrder(fieldString(f)))
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	items, _ := enc.Fields[f.Key].([]any)
+
+	masked := make([]string, len(items))
+	for i, item := range items {
+		masked[i] = maskOrder(fmt.Sprint(item))
+	}
+	return zap.Strings(f.Key, masked)
+}
+
+func maskOrder(number string) string {
+	if len(number) <= visibleOrderDigits {
+		return strings.Repeat("*", len(number))
+	}
+	cut := len(number) - visibleOrderDigits
+	return strings.Repeat("*", cut) + number[cut:]
+}
+
+func fieldString(f zapcore.Field) string {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return strconv.FormatInt(f.Integer, 10)
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+		return strconv.FormatUint(uint64(f.Integer), 10)
+	case zapcore.StringerType:
+		return f.Interface.(fmt.Stringer).String()
+	}
+	if f.Interface != nil {
+		return fmt.Sprint(f.Interface)
+	}
+	return ""
+}
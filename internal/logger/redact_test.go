@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value any
+		want  any
+	}{
+		{name: "login", key: "login", value: "alice", want: redacted},
+		{name: "password", key: "password", value: "secret", want: redacted},
+		{name: "cookie", key: "Cookie", value: "X-AUTH-TOKEN=abc", want: redacted},
+		{name: "order number keeps last digits", key: "order", value: "12345678903", want: "*******8903"},
+		{name: "short order number", key: "order_number", value: "123", want: "***"},
+		{name: "numeric order number", key: "number", value: 79927398713, want: "*******8713"},
+		{name: "order number list", key: "orders", value: []string{"12345678903", "79927398713"}, want: []any{"*******8903", "*******8713"}},
+		{name: "numeric order number list", key: "numbers", value: []int64{12345678903}, want: []any{"*******8903"}},
+		{name: "other fields untouched", key: "status", value: "NEW", want: "NEW"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			log := zap.New(redact(core)).Sugar()
+
+			log.With(tt.key, tt.value).Info("with")
+			log.Infow("inline", tt.key, tt.value)
+
+			require.Equal(t, 2, logs.Len())
+			for _, entry := range logs.All() {
+				assert.Equal(t, tt.want, entry.ContextMap()[tt.key])
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Run("unknown format", func(t *testing.T) {
+		_, _, err := Build(Options{Format: "xml", Level: "info"})
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		_, _, err := Build(Options{Format: FormatJSON, Level: "verbose"})
+		assert.Error(t, err)
+	})
+
+	t.Run("json to file", func(t *testing.T) {
+		path := t.TempDir() + "/app.log"
+
+		log, closer, err := Build(Options{Format: FormatJSON, Level: "info", Output: path, MaxSizeMB: 1})
+		require.NoError(t, err)
+		require.NotNil(t, closer)
+		t.Cleanup(func() { _ = SetLevel("debug") })
+
+		log.Infow("signed in", "login", "alice")
+		require.NoError(t, closer.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"msg":"signed in"`)
+		assert.Contains(t, string(data), `"login":"[REDACTED]"`)
+		assert.NotContains(t, string(data), "alice")
+	})
+}
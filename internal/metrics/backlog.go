@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// backlogTimeout bounds the count queries run on every scrape.
+const backlogTimeout = 2 * time.Second
+
+type StatusCounter interface {
+	CountByStatus(ctx context.Context, status model.OrderStatus) (int, error)
+}
+
+// BacklogCollector reports the number of orders per pending status, queried
+// on scrape so the value is never stale.
+type BacklogCollector struct {
+	counter  StatusCounter
+	statuses []model.OrderStatus
+	desc     *prometheus.Desc
+}
+
+func NewBacklogCollector(counter StatusCounter, statuses ...model.OrderStatus) *BacklogCollector {
+	return &BacklogCollector{
+		counter:  counter,
+		statuses: statuses,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "orders", "backlog"),
+			"Orders waiting for the accrual system by status.",
+			[]string{"status"}, nil,
+		),
+	}
+}
+
+func (c *BacklogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *BacklogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), backlogTimeout)
+	defer cancel()
+
+	for _, status := range c.statuses {
+		count, err := c.counter.CountByStatus(ctx, status)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.desc, err)
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), string(status))
+	}
+}
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gophermart"
+
+// Registry holds all application metrics. A dedicated registry keeps the
+// exposition free of anything registered globally by dependencies.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by chi route pattern, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by chi route pattern and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	accrualRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "requests_total",
+		Help:      "Accrual system calls by outcome: HTTP status code, error or circuit_open.",
+	}, []string{"code"})
+
+	accrualDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "request_duration_seconds",
+		Help:      "Accrual system call latency.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	workerBatchSize = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "batch_size",
+		Help:      "Orders claimed by an accrual worker per poll.",
+		Buckets:   []float64{0, 1, 2, 5, 10, 20, 50, 100},
+	})
+
+	workerOrderLag = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "order_lag_seconds",
+		Help:      "Time from order upload until the worker moved it to a final status.",
+		Buckets:   []float64{1, 5, 15, 60, 300, 900, 3600, 4 * 3600, 24 * 3600},
+	})
+
+	staleOrdersFlagged = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "stale_orders_flagged_total",
+		Help:      "Orders flagged as stuck in a non-terminal status.",
+	})
+
+	pointsCredited = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_credited_total",
+		Help:      "Loyalty points credited to users for processed orders.",
+	})
+
+	pointsWithdrawn = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_withdrawn_total",
+		Help:      "Loyalty points withdrawn by users.",
+	})
+
+	pointsExpired = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_expired_total",
+		Help:      "Loyalty points expired unused.",
+	})
+
+	pointsRefunded = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_refunded_total",
+		Help:      "Loyalty points returned for reversed withdrawals.",
+	})
+
+	pointsTransferred = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_transferred_total",
+		Help:      "Loyalty points transferred between users.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB exposes connection pool statistics of db.
+func RegisterDB(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+func ObserveHTTPRequest(route, method string, status int, d time.Duration) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+// ObserveAccrualRequest records an accrual call that got a response with the
+// given status code, or failed before that when code is "error".
+func ObserveAccrualRequest(code string, d time.Duration) {
+	accrualRequests.WithLabelValues(code).Inc()
+	accrualDuration.Observe(d.Seconds())
+}
+
+// CountAccrualCircuitOpen records a call rejected by the open circuit breaker.
+func CountAccrualCircuitOpen() {
+	accrualRequests.WithLabelValues("circuit_open").Inc()
+}
+
+func ObserveBatchSize(size int) {
+	workerBatchSize.Observe(float64(size))
+}
+
+func ObserveOrderLag(d time.Duration) {
+	workerOrderLag.Observe(d.Seconds())
+}
+
+func AddStaleOrdersFlagged(count int) {
+	staleOrdersFlagged.Add(float64(count))
+}
+
+// AddPointsCredited takes the amount in hundredths of a point, as stored in the DB.
+func AddPointsCredited(amount int) {
+	pointsCredited.Add(float64(amount) / 100)
+}
+
+// AddPointsWithdrawn takes the amount in hundredths of a point, as stored in the DB.
+func AddPointsWithdrawn(amount int) {
+	pointsWithdrawn.Add(float64(amount) / 100)
+}
+
+// AddPointsExpired takes the amount in hundredths of a point, as stored in the DB.
+func AddPointsExpired(amount int) {
+	pointsExpired.Add(float64(amount) / 100)
+}
+
+// AddPointsRefunded takes the amount in hundredths of a point, as stored in the DB.
+func AddPointsRefunded(amount int) {
+	pointsRefunded.Add(float64(amount) / 100)
+}
+
+// AddPointsTransferred takes the amount in hundredths of a point, as stored in the DB.
+func AddPointsTransferred(amount int) {
+	pointsTransferred.Add(float64(amount) / 100)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCounter map[model.OrderStatus]int
+
+func (f fakeCounter) CountByStatus(_ context.Context, status model.OrderStatus) (int, error) {
+	count, ok := f[status]
+	if !ok {
+		return 0, errors.New("db is down")
+	}
+	return count, nil
+}
+
+func TestBacklogCollector(t *testing.T) {
+	c := NewBacklogCollector(fakeCounter{
+		model.OrderStatusNew:        3,
+		model.OrderStatusProcessing: 7,
+	}, model.OrderStatusNew, model.OrderStatusProcessing)
+
+	expected := `
+# HELP gophermart_orders_backlog Orders waiting for the accrual system by status.
+# TYPE gophermart_orders_backlog gauge
+gophermart_orders_backlog{status="NEW"} 3
+gophermart_orders_backlog{status="PROCESSING"} 7
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected)))
+
+	t.Run("count error fails the scrape", func(t *testing.T) {
+		c := NewBacklogCollector(fakeCounter{}, model.OrderStatusNew)
+
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(c)
+
+		_, err := reg.Gather()
+		assert.Error(t, err)
+	})
+}
+
+func TestObserve(t *testing.T) {
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("/api/user/orders", http.MethodPost, "202"))
+	ObserveHTTPRequest("/api/user/orders", http.MethodPost, http.StatusAccepted, 10*time.Millisecond)
+	assert.Equal(t, before+1, testutil.ToFloat64(httpRequests.WithLabelValues("/api/user/orders", http.MethodPost, "202")))
+
+	before = testutil.ToFloat64(pointsCredited)
+	AddPointsCredited(12345)
+	assert.InDelta(t, before+123.45, testutil.ToFloat64(pointsCredited), 1e-9)
+
+	before = testutil.ToFloat64(staleOrdersFlagged)
+	AddStaleOrdersFlagged(2)
+	assert.Equal(t, before+2, testutil.ToFloat64(staleOrdersFlagged))
+}
+
+func TestHandler(t *testing.T) {
+	CountAccrualCircuitOpen()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `gophermart_accrual_requests_total{code="circuit_open"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// WithAdminToken allows the request only when it carries
+// "Authorization: Bearer <token>" matching the configured admin token.
+func WithAdminToken(token string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			log := logger.FromContext(r.Context())
+
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				log.With("err", model.ErrInvalidCredentials.Error()).Warn()
+				httperr.Write(w, r, model.ErrInvalidCredentials)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
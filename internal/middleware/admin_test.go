@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAdminToken(t *testing.T) {
+	token := "admin-secret"
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		configured string
+		header     string
+		wantStatus int
+	}{
+		{"valid token", token, "Bearer " + token, http.StatusOK},
+		{"missing header", token, "", http.StatusUnauthorized},
+		{"wrong token", token, "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", token, "Basic " + token, http.StatusUnauthorized},
+		{"admin token not configured", "", "Bearer ", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			WithAdminToken(tt.configured)(nextHandler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
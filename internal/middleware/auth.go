@@ -5,37 +5,48 @@ import (
 	"net/http"
 
 	"github.com/mrhyman/gophermart/internal/auth"
+	"github.com/mrhyman/gophermart/internal/httperr"
 	"github.com/mrhyman/gophermart/internal/logger"
 	"github.com/mrhyman/gophermart/internal/model"
 )
 
 func WithAuth(secret string) func(http.HandlerFunc) http.HandlerFunc {
+	keyring, err := auth.NewKeyring(secret)
+	if err != nil {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				logger.FromContext(r.Context()).With("err", err.Error()).Warn()
+				httperr.Write(w, r, model.ErrWentWrong)
+			}
+		}
+	}
+
+	return WithKeyring(keyring)
+}
+
+// WithKeyring authenticates the request by the auth cookie, accepting any
+// key currently present in the keyring.
+func WithKeyring(keyring *auth.Keyring) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			log := logger.FromContext(r.Context())
 
-			ce, err := auth.NewCookieEncoder(secret)
-			if err != nil {
-				log.With("err", err.Error()).Warn()
-				http.Error(w, model.ErrWentWrong.Error(), http.StatusInternalServerError)
-				return
-			}
-
 			cookie, err := r.Cookie(string(model.AuthCookie))
 			if err != nil {
 				log.With("err", err.Error()).Warn()
-				http.Error(w, model.ErrWentWrong.Error(), http.StatusUnauthorized)
+				httperr.Write(w, r, model.ErrUnknownUser)
 				return
 			}
 
-			userID, err := ce.DecodeUserID(cookie)
+			userID, err := keyring.DecodeUserID(cookie)
 			if err != nil || userID == "" {
-				log.With("err", err.Error()).Warn()
-				http.Error(w, model.ErrUnknownUser.Error(), http.StatusUnauthorized)
+				log.With("err", model.ErrUnknownUser.Error()).Warn()
+				httperr.Write(w, r, model.ErrUnknownUser)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), model.UserIDKey, userID)
+			ctx = logger.WithinContext(ctx, log.With("user_id", userID))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 	}
@@ -51,7 +51,7 @@ func TestWithAuth(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusUnauthorized, rr.Code)
-		assert.Contains(t, rr.Body.String(), model.ErrWentWrong.Error())
+		assert.Contains(t, rr.Body.String(), model.ErrUnknownUser.Error())
 	})
 
 	t.Run("fail with invalid cookie value", func(t *testing.T) {
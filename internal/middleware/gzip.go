@@ -3,11 +3,11 @@ package middleware
 import (
 	"compress/gzip"
 	"context"
-	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
+	"github.com/mrhyman/gophermart/internal/httperr"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/logger"
 )
@@ -15,6 +15,9 @@ import (
 type gzipWriter struct {
 	w  http.ResponseWriter
 	zw *gzip.Writer
+	// plain is set for error responses, which are written uncompressed.
+	plain       bool
+	wroteHeader bool
 }
 
 func newCompressWriter(w http.ResponseWriter) *gzipWriter {
@@ -29,17 +32,33 @@ func (c *gzipWriter) Header() http.Header {
 }
 
 func (c *gzipWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.plain {
+		return c.w.Write(p)
+	}
 	return c.zw.Write(p)
 }
 
 func (c *gzipWriter) WriteHeader(statusCode int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+
 	if statusCode < http.StatusMultipleChoices {
 		c.w.Header().Set("Content-Encoding", "gzip")
+	} else {
+		c.plain = true
 	}
 	c.w.WriteHeader(statusCode)
 }
 
 func (c *gzipWriter) Close() error {
+	if c.plain {
+		return nil
+	}
 	return c.zw.Close()
 }
 
@@ -72,11 +91,23 @@ func (c *compressReader) Close() error {
 	return c.zr.Close()
 }
 
+// Flush sends everything compressed so far to the client, so streamed
+// responses such as server-sent events arrive as they are written. The
+// header is committed first, otherwise the gzip header would go out without
+// Content-Encoding.
 func (c *gzipWriter) Flush() {
-	c.zw.Flush()
-	if f, ok := c.w.(http.Flusher); ok {
-		f.Flush()
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if !c.plain {
+		c.zw.Flush()
 	}
+	http.NewResponseController(c.w).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *gzipWriter) Unwrap() http.ResponseWriter {
+	return c.w
 }
 
 func WithGzip(next http.HandlerFunc) http.HandlerFunc {
@@ -101,8 +132,7 @@ func WithGzip(next http.HandlerFunc) http.HandlerFunc {
 			cr, err := newCompressReader(req.Context(), req.Body)
 			if err != nil {
 				log.With("err", err.Error()).Error()
-				res.WriteHeader(http.StatusBadRequest)
-				fmt.Fprint(res, model.ErrCompressReading.Error())
+				httperr.Write(res, req, model.ErrCompressReading)
 				return
 			}
 			req.Body = cr
@@ -245,6 +245,35 @@ func TestGzipWriter(t *testing.T) {
 	})
 }
 
+func TestWithGzip_Flush(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Content-Type", "text/plain")
+
+	rr := httptest.NewRecorder()
+
+	handler := WithGzip(WithLogging(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		require.NoError(t, http.NewResponseController(w).Flush())
+		assert.True(t, rr.Flushed)
+		assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+
+		_, err := w.Write([]byte("data: 1\n\n"))
+		require.NoError(t, err)
+		require.NoError(t, http.NewResponseController(w).Flush())
+
+		// Everything written so far must be readable before the stream ends.
+		zr, err := gzip.NewReader(bytes.NewReader(rr.Body.Bytes()))
+		require.NoError(t, err)
+		buf := make([]byte, 64)
+		n, _ := io.ReadAtLeast(zr, buf, len("data: 1\n\n"))
+		assert.Equal(t, "data: 1\n\n", string(buf[:n]))
+	}))
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
 func TestCompressReader(t *testing.T) {
 	t.Run("read compressed data", func(t *testing.T) {
 		originalData := "test data for compression"
@@ -264,3 +293,20 @@ func TestCompressReader(t *testing.T) {
 		assert.Equal(t, originalData, string(result))
 	})
 }
+
+func TestWithGzip_ErrorResponse(t *testing.T) {
+	handler := WithGzip(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x"))
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Content-Type", "text/plain")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "bad request\n", rr.Body.String())
+}
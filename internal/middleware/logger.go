@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/metrics"
 	"github.com/mrhyman/gophermart/internal/model"
 )
 
@@ -32,6 +33,18 @@ func (lw *logWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+func (lw *logWriter) Flush() {
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+	http.NewResponseController(lw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (lw *logWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
 func WithLogging(next http.HandlerFunc) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		start := time.Now()
@@ -44,10 +57,19 @@ func WithLogging(next http.HandlerFunc) http.HandlerFunc {
 
 		rw := &logWriter{ResponseWriter: res}
 
-		next.ServeHTTP(rw, req)
+		// Everything logged while handling the request carries the route.
+		ctx := logger.WithinContext(req.Context(), logger.FromContext(req.Context()).With("route", routePattern))
+
+		next.ServeHTTP(rw, req.WithContext(ctx))
 
 		duration := time.Since(start)
 
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		metrics.ObserveHTTPRequest(routePattern, method, status, duration)
+
 		userID := req.Context().Value(model.UserIDKey)
 
 		logger.FromContext(req.Context()).With(
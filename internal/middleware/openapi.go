@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+const (
+	// maxValidatedBodyBytes bounds the request bodies read for validation,
+	// before authentication. No validated body may need more.
+	maxValidatedBodyBytes = 1 << 20
+	// streamedBodyExtension marks operations whose handlers read and limit
+	// large bodies themselves. Their bodies are not validated.
+	streamedBodyExtension = "x-streamed-body"
+)
+
+// OpenAPIValidator rejects requests that do not match the OpenAPI document.
+type OpenAPIValidator struct {
+	router routers.Router
+}
+
+func NewOpenAPIValidator(doc *openapi3.T) (*OpenAPIValidator, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIValidator{router: router}, nil
+}
+
+// Middleware validates path, query, headers and body of requests to routes
+// described in the document. Other requests are passed through so the router
+// can answer 404 or 405. Authentication is left to the auth middleware.
+func (v *OpenAPIValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		route, params, err := v.router.FindRoute(req)
+		if err != nil {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		// Compressed bodies are unpacked later by WithGzip.
+		excludeBody := req.Header.Get("Content-Encoding") != "" || streamedBody(route.Operation)
+		if !excludeBody {
+			req.Body = http.MaxBytesReader(res, req.Body, maxValidatedBodyBytes)
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				ExcludeRequestBody: excludeBody,
+			},
+		}
+
+		if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+			logger.FromContext(req.Context()).With("err", err.Error()).Warn("request does not match the API schema")
+			httperr.Write(res, req, fmt.Errorf("%w: %s", model.ErrInvalidRequestParams, reason(err)))
+			return
+		}
+
+		next.ServeHTTP(res, req)
+	})
+}
+
+func streamedBody(op *openapi3.Operation) bool {
+	raw, ok := op.Extensions[streamedBodyExtension].(json.RawMessage)
+	if !ok {
+		return false
+	}
+
+	var streamed bool
+	return json.Unmarshal(raw, &streamed) == nil && streamed
+}
+
+// reason returns a short description of a validation error without the
+// echoed request value.
+func reason(err error) string {
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(reqErr.Err, &tooLarge) {
+			return fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)
+		}
+		var schemaErr *openapi3.SchemaError
+		if errors.As(reqErr.Err, &schemaErr) {
+			return schemaErr.Reason
+		}
+		if reqErr.Reason != "" {
+			return reqErr.Reason
+		}
+		if reqErr.Err != nil {
+			return reqErr.Err.Error()
+		}
+	}
+	return err.Error()
+}
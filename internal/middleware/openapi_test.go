@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/httperr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIValidator(t *testing.T) {
+	doc, err := api.LoadOpenAPI(context.Background())
+	require.NoError(t, err)
+
+	validator, err := NewOpenAPIValidator(doc)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"valid register", http.MethodPost, "/api/user/register", "application/json", `{"login":"alice","password":"secret"}`, http.StatusOK},
+		{"register without password", http.MethodPost, "/api/user/register", "application/json", `{"login":"alice"}`, http.StatusBadRequest},
+		{"register with wrong type", http.MethodPost, "/api/user/register", "application/json", `{"login":1,"password":"secret"}`, http.StatusBadRequest},
+		{"withdraw with string sum", http.MethodPost, "/api/user/balance/withdraw", "application/json", `{"order":"2377225624","sum":"10"}`, http.StatusBadRequest},
+		{"order upload as json", http.MethodPost, "/api/user/orders", "application/json", `"12345678903"`, http.StatusBadRequest},
+		{"valid order upload", http.MethodPost, "/api/user/orders", "text/plain", "12345678903", http.StatusOK},
+		{"route without body", http.MethodGet, "/api/user/balance", "", "", http.StatusOK},
+		{"unknown route passes through", http.MethodGet, "/unknown", "", "", http.StatusOK},
+		{"oversized body", http.MethodPost, "/api/user/register", "application/json", `{"login":"` + strings.Repeat("a", maxValidatedBodyBytes) + `","password":"secret"}`, http.StatusBadRequest},
+		{"streamed body is left to the handler", http.MethodPost, "/api/admin/imports", "text/csv", "login,number,status,accrual,uploaded_at\n", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				gotBody = string(body)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+
+			validator.Middleware(next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.body, gotBody, "body must reach the handler intact")
+				return
+			}
+
+			assert.Equal(t, httperr.ContentType, rr.Header().Get("Content-Type"))
+			var problem api.Problem
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+			assert.Equal(t, httperr.CodeInvalidRequest, problem.Code)
+		})
+	}
+}
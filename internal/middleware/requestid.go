@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLen = 128
+)
+
+// WithRequestID takes the request ID from the X-Request-ID header, or
+// generates one when it is missing or malformed, echoes it in the response
+// and puts it into the request context together with a logger carrying it.
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		res.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(req.Context(), model.RequestIDKey, id)
+		ctx = logger.WithinContext(ctx, logger.FromContext(ctx).With("request_id", id))
+
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so a
+// client-supplied value cannot break log lines or response headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
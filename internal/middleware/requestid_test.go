@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/auth"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "keep valid incoming id", incoming: "req-123_abc", keep: true},
+		{name: "generate when missing", incoming: ""},
+		{name: "generate when too long", incoming: strings.Repeat("a", maxRequestIDLen+1)},
+		{name: "generate when it has spaces", incoming: "bad id"},
+		{name: "generate when it has control characters", incoming: "bad\x7fid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID, _ = r.Context().Value(model.RequestIDKey).(string)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+
+			WithRequestID(next).ServeHTTP(rr, req)
+
+			id := rr.Header().Get(RequestIDHeader)
+			assert.Equal(t, id, ctxID)
+			if tt.keep {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				_, err := uuid.Parse(id)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWithRequestID_Logger(t *testing.T) {
+	ce, err := auth.NewCookieEncoder("test-secret")
+	require.NoError(t, err)
+	cookie, err := ce.EncodeUserID("user-1")
+	require.NoError(t, err)
+	keyring, err := auth.NewKeyring("test-secret")
+	require.NoError(t, err)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	handler := WithRequestID(WithKeyring(keyring)(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("handled")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.AddCookie(&http.Cookie{Name: string(model.AuthCookie), Value: cookie})
+	req = req.WithContext(logger.WithinContext(req.Context(), logger.NewWithCore(core)))
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, "user-1", fields["user_id"])
+}
+
+func TestWithRequestID_ErrorBody(t *testing.T) {
+	keyring, err := auth.NewKeyring("test-secret")
+	require.NoError(t, err)
+
+	handler := WithRequestID(WithKeyring(keyring)(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-2")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	var problem api.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, "req-2", problem.RequestID)
+}
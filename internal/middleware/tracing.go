@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrhyman/gophermart/internal/logger"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracing puts a logger carrying the trace ID into the request context and
+// names the server span after the matched chi route once it is known.
+func WithTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := logger.WithTraceContext(req.Context())
+
+		next.ServeHTTP(res, req.WithContext(ctx))
+
+		rctx := chi.RouteContext(ctx)
+		if rctx == nil || rctx.RoutePattern() == "" {
+			return
+		}
+
+		span := trace.SpanFromContext(ctx)
+		span.SetName(req.Method + " " + rctx.RoutePattern())
+		span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+	})
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	r := chi.NewRouter()
+	r.Use(WithTracing)
+	r.Get("/api/user/orders/{number}", func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("handled")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/orders/12345", nil)
+	ctx, span := tp.Tracer("test").Start(req.Context(), "http.server")
+	ctx = logger.WithinContext(ctx, logger.NewWithCore(core))
+
+	r.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/user/orders/{number}", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("http.route", "/api/user/orders/{number}"))
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, span.SpanContext().TraceID().String(), logs.All()[0].ContextMap()["trace_id"])
+}
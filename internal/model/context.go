@@ -7,3 +7,5 @@ const UserIDKey ContextKey = "userID"
 type AuthCookieName string
 
 const AuthCookie AuthCookieName = "X-AUTH-TOKEN"
+
+const RequestIDKey ContextKey = "requestID"
@@ -14,6 +14,8 @@ var (
 	ErrUnknownAccrualStatus       = errors.New("unknown accrual status")
 	ErrUnknownUser                = errors.New("userID is not provided")
 	ErrInvalidRequestParams       = errors.New("invalid request params")
+	ErrInvalidContentType         = errors.New("invalid content type")
+	ErrMethodNotAllowed           = errors.New("method not allowed")
 	ErrInvalidCredentials         = errors.New("invalid credentials")
 	ErrResponseDecode             = errors.New("can't decode response")
 	ErrWentWrong                  = errors.New("something went wrong")
@@ -23,12 +25,23 @@ var (
 	ErrOrderUploadedByAnotherUser = errors.New("order uploaded by another user")
 	ErrResponseEncoding           = errors.New("can't encode response")
 	ErrInsufficientFunds          = errors.New("insufficient funds")
+	ErrInvalidStatusTransition    = errors.New("invalid order status transition")
+	ErrAccrualNotCredited         = errors.New("order accrual not credited")
+	ErrHoldNotActive              = errors.New("withdrawal hold is not active")
+	ErrRefundExceedsWithdrawal    = errors.New("refund exceeds the withdrawn sum")
+	ErrTransferLimitExceeded      = errors.New("daily transfer limit exceeded")
+	ErrIdempotencyKeyReused       = errors.New("idempotency key was used for another request")
+	ErrShuttingDown               = errors.New("shutting down")
+	ErrMigrationsDirty            = errors.New("database migration is dirty")
+	ErrMigrationsOutdated         = errors.New("database schema is older than expected")
+	ErrWorkerStalled              = errors.New("accrual worker heartbeat is stale")
 	// accrual errors
 	ErrAccrualRequestCreateFailed = errors.New("can't create accrual request")
 	ErrAccrualRequestSendFailed   = errors.New("can't send accrual request")
 	ErrOrderNotRegistered         = errors.New("order not registered")
 	ErrAccrualTooManyRequests     = errors.New("too many accrual requests")
 	ErrAccrualInternalError       = errors.New("accrual internal error")
+	ErrAccrualCircuitOpen         = errors.New("accrual circuit is open")
 )
 
 type AlreadyExistsError struct {
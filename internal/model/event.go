@@ -0,0 +1,24 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// EventType names a change streamed to the user.
+type EventType string
+
+const (
+	EventOrderStatus EventType = "order.status"
+	EventBalance     EventType = "balance"
+)
+
+// Event is a change of the user's orders or balance. IDs come from a
+// database sequence, so every replica sees the same IDs.
+type Event struct {
+	ID     int64           `json:"id"`
+	UserID uuid.UUID       `json:"user_id"`
+	Type   EventType       `json:"type"`
+	Data   json.RawMessage `json:"data"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type HoldStatus string
+
+const (
+	HoldStatusHeld      HoldStatus = "HELD"
+	HoldStatusConfirmed HoldStatus = "CONFIRMED"
+	HoldStatusReleased  HoldStatus = "RELEASED"
+	HoldStatusExpired   HoldStatus = "EXPIRED"
+)
+
+// WithdrawalHold reserves points for an order until it is confirmed, which
+// withdraws them, or released. A hold past ExpiresAt reserves nothing.
+type WithdrawalHold struct {
+	ID         uuid.UUID  `db:"id"`
+	UserID     uuid.UUID  `db:"user_id"`
+	Order      string     `db:"order_number"`
+	Sum        int        `db:"sum"`
+	Status     HoldStatus `db:"status"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	ResolvedAt *time.Time `db:"resolved_at"`
+}
+
+// Balance is the state of a user account. Current includes the held points.
+type Balance struct {
+	Current   int `db:"current"`
+	Withdrawn int `db:"withdrawn"`
+	Held      int `db:"held"`
+}
+
+// Available is what can be withdrawn or held.
+func (b Balance) Available() int {
+	return b.Current - b.Held
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportMode tells an import job whether to write anything.
+type ImportMode string
+
+const (
+	// ImportModeDryRun validates the file and reports what would be imported.
+	ImportModeDryRun ImportMode = "dry-run"
+	// ImportModeApply imports the valid rows.
+	ImportModeApply ImportMode = "apply"
+)
+
+func ParseImportMode(s string) (ImportMode, error) {
+	switch ImportMode(s) {
+	case "", ImportModeDryRun:
+		return ImportModeDryRun, nil
+	case ImportModeApply:
+		return ImportModeApply, nil
+	default:
+		return "", fmt.Errorf("%w: unknown import mode %q", ErrInvalidRequestParams, s)
+	}
+}
+
+type ImportStatus string
+
+const (
+	ImportStatusRunning   ImportStatus = "running"
+	ImportStatusCompleted ImportStatus = "completed"
+	// ImportStatusFailed means the job stopped half way. Chunks committed
+	// before the failure stay imported, re-running the file picks up the rest.
+	ImportStatusFailed ImportStatus = "failed"
+)
+
+// ImportJob is one run of the legacy orders import. Report holds the CSV
+// error report with one line per rejected row.
+type ImportJob struct {
+	ID         uuid.UUID    `db:"id"`
+	Mode       ImportMode   `db:"mode"`
+	Status     ImportStatus `db:"status"`
+	TotalRows  int          `db:"total_rows"`
+	Imported   int          `db:"imported"`
+	Skipped    int          `db:"skipped"`
+	Failed     int          `db:"failed"`
+	Report     string       `db:"report"`
+	CreatedAt  time.Time    `db:"created_at"`
+	FinishedAt *time.Time   `db:"finished_at"`
+}
+
+func (ImportJob) TableName() string { return "import_jobs" }
+
+// ImportRow is a validated line of the import file. Accrual is in hundredths.
+type ImportRow struct {
+	Line       int
+	Login      string
+	Number     string
+	Status     OrderStatus
+	Accrual    int
+	UploadedAt time.Time
+}
+
+type ImportOutcome string
+
+const (
+	// ImportImported is reported for imported rows, or for rows a dry run
+	// would import.
+	ImportImported ImportOutcome = "imported"
+	// ImportSkipped is reported for orders the user already has, e.g. rows
+	// imported by a previous run.
+	ImportSkipped ImportOutcome = "skipped"
+	ImportFailed  ImportOutcome = "failed"
+)
+
+// ImportRowResult is the outcome of one line of the import file. Reason
+// explains failed rows.
+type ImportRowResult struct {
+	Line    int
+	Login   string
+	Number  string
+	Outcome ImportOutcome
+	Reason  string
+}
@@ -22,8 +22,29 @@ const (
 	OrderStatusProcessing OrderStatus = "PROCESSING"
 	OrderStatusInvalid    OrderStatus = "INVALID"
 	OrderStatusProcessed  OrderStatus = "PROCESSED"
+	// OrderStatusExpired is set by the stale order sweeper, never by the accrual system.
+	OrderStatusExpired OrderStatus = "EXPIRED"
 )
 
+// IsTerminal reports whether the order has reached a final status that
+// must never be left again.
+func (s OrderStatus) IsTerminal() bool {
+	return s == OrderStatusInvalid || s == OrderStatusProcessed || s == OrderStatusExpired
+}
+
+// CanTransitionTo reports whether the order may move from s to next.
+// Orders only move forward: NEW -> PROCESSING -> INVALID|PROCESSED|EXPIRED.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	switch s {
+	case OrderStatusNew:
+		return next == OrderStatusProcessing || next.IsTerminal()
+	case OrderStatusProcessing:
+		return next.IsTerminal()
+	default:
+		return false
+	}
+}
+
 func MapAccrualStatusToOrderStatus(accrualStatus AccrualStatus) (OrderStatus, error) {
 	switch accrualStatus {
 	case AccrualStatusNew:
@@ -46,6 +67,9 @@ type Order struct {
 	Status    OrderStatus `db:"status" json:"status"`
 	Accrual   int         `db:"accrual" json:"accrual"`
 	CreatedAt time.Time   `db:"created_at" json:"uploaded_at"`
+	FlaggedAt *time.Time  `db:"flagged_at" json:"flagged_at,omitempty"`
+	// Attempts counts the accrual system lookups made for the order.
+	Attempts int `db:"accrual_attempts" json:"attempts"`
 }
 
 func NewOrder(
@@ -69,3 +93,55 @@ func NewOrder(
 }
 
 func (Order) TableName() string { return "orders" }
+
+// OrderStatusChange is a status transition observed by the accrual worker.
+// Accrual is the amount reported by the accrual system, Attempt is the
+// number of lookups it took to observe the change.
+type OrderStatusChange struct {
+	OrderID   uuid.UUID   `db:"order_id"`
+	OldStatus OrderStatus `db:"old_status"`
+	NewStatus OrderStatus `db:"new_status"`
+	Accrual   int         `db:"accrual"`
+	Attempt   int         `db:"attempt"`
+	ChangedAt time.Time   `db:"created_at"`
+}
+
+// BatchOrder is an order returned by a batch insert. Created is false when
+// the number had already been uploaded, possibly by another user.
+type BatchOrder struct {
+	Order
+	Created bool `db:"created"`
+}
+
+// UploadOutcome is the result of uploading one order number in bulk.
+type UploadOutcome string
+
+const (
+	UploadAccepted           UploadOutcome = "accepted"
+	UploadAlreadyYours       UploadOutcome = "already_yours"
+	UploadOwnedByAnotherUser UploadOutcome = "owned_by_another_user"
+	UploadInvalid            UploadOutcome = "invalid"
+)
+
+// UploadResult pairs an uploaded number with its outcome. Order is set unless
+// the number is invalid or belongs to another user.
+type UploadResult struct {
+	Number  string
+	Outcome UploadOutcome
+	Order   *Order
+}
+
+// Err returns the error a single upload would have failed with, nil for
+// accepted numbers.
+func (r UploadResult) Err() error {
+	switch r.Outcome {
+	case UploadAlreadyYours:
+		return ErrOrderAlreadyUploaded
+	case UploadOwnedByAnotherUser:
+		return ErrOrderUploadedByAnotherUser
+	case UploadInvalid:
+		return ErrInvalidOrderNumber
+	default:
+		return nil
+	}
+}
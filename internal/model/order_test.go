@@ -23,9 +23,9 @@ func TestMapAccrualStatusToOrderStatus(t *testing.T) {
 			wantErr:       nil,
 		},
 		{
-			name:          "PROCESSING maps to NEW",
+			name:          "PROCESSING maps to PROCESSING",
 			accrualStatus: AccrualStatusProcessing,
-			want:          OrderStatusNew,
+			want:          OrderStatusProcessing,
 			wantErr:       nil,
 		},
 		{
@@ -171,3 +171,35 @@ func TestAccrualStatus_Constants(t *testing.T) {
 		assert.Equal(t, AccrualStatus("PROCESSED"), AccrualStatusProcessed)
 	})
 }
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		name string
+		from OrderStatus
+		to   OrderStatus
+		want bool
+	}{
+		{"NEW to PROCESSING", OrderStatusNew, OrderStatusProcessing, true},
+		{"NEW to PROCESSED", OrderStatusNew, OrderStatusProcessed, true},
+		{"NEW to INVALID", OrderStatusNew, OrderStatusInvalid, true},
+		{"PROCESSING to PROCESSED", OrderStatusProcessing, OrderStatusProcessed, true},
+		{"PROCESSING to INVALID", OrderStatusProcessing, OrderStatusInvalid, true},
+		{"PROCESSING to NEW", OrderStatusProcessing, OrderStatusNew, false},
+		{"PROCESSED to PROCESSED", OrderStatusProcessed, OrderStatusProcessed, false},
+		{"PROCESSED to NEW", OrderStatusProcessed, OrderStatusNew, false},
+		{"INVALID to PROCESSED", OrderStatusInvalid, OrderStatusProcessed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
+
+func TestOrderStatus_IsTerminal(t *testing.T) {
+	assert.False(t, OrderStatusNew.IsTerminal())
+	assert.False(t, OrderStatusProcessing.IsTerminal())
+	assert.True(t, OrderStatusInvalid.IsTerminal())
+	assert.True(t, OrderStatusProcessed.IsTerminal())
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DomainEventType names a state change published to the data platform.
+type DomainEventType string
+
+const (
+	UserRegistered     DomainEventType = "UserRegistered"
+	OrderUploaded      DomainEventType = "OrderUploaded"
+	OrderStatusChanged DomainEventType = "OrderStatusChanged"
+	PointsCredited     DomainEventType = "PointsCredited"
+	PointsWithdrawn    DomainEventType = "PointsWithdrawn"
+	PointsExpired      DomainEventType = "PointsExpired"
+	PointsRefunded     DomainEventType = "PointsRefunded"
+	PointsSent         DomainEventType = "PointsSent"
+	PointsReceived     DomainEventType = "PointsReceived"
+)
+
+// DomainEvent is a state change recorded in the events outbox. Seq numbers
+// the events of a user without gaps, so consumers can detect reordering and
+// duplicates of an at-least-once delivery.
+type DomainEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	UserID    uuid.UUID       `db:"user_id" json:"user_id"`
+	Seq       int64           `db:"seq" json:"seq"`
+	Type      DomainEventType `db:"type" json:"type"`
+	Data      json.RawMessage `db:"payload" json:"data"`
+	CreatedAt time.Time       `db:"created_at" json:"occurred_at"`
+}
+
+// Order sources of OrderUploaded.
+const (
+	OrderSourceAPI    = "api"
+	OrderSourceImport = "import"
+)
+
+// Reasons of PointsCredited.
+const (
+	CreditReasonAccrual    = "accrual"
+	CreditReasonAdjustment = "adjustment"
+	CreditReasonImport     = "import"
+)
+
+// The payloads of the domain events. Amounts are in points, as in the API.
+type (
+	UserRegisteredData struct {
+		Login string `json:"login"`
+	}
+
+	OrderUploadedData struct {
+		OrderID uuid.UUID   `json:"order_id"`
+		Number  string      `json:"number"`
+		Status  OrderStatus `json:"status"`
+		Source  string      `json:"source"`
+	}
+
+	OrderStatusChangedData struct {
+		OrderID uuid.UUID   `json:"order_id"`
+		Number  string      `json:"number"`
+		From    OrderStatus `json:"from"`
+		To      OrderStatus `json:"to"`
+		Accrual float64     `json:"accrual"`
+	}
+
+	// PointsCreditedData has a negative amount when an adjustment lowers an
+	// earlier accrual.
+	PointsCreditedData struct {
+		OrderID uuid.UUID `json:"order_id"`
+		Amount  float64   `json:"amount"`
+		Reason  string    `json:"reason"`
+	}
+
+	PointsWithdrawnData struct {
+		Order string  `json:"order"`
+		Sum   float64 `json:"sum"`
+	}
+
+	PointsExpiredData struct {
+		Amount float64 `json:"amount"`
+	}
+
+	PointsRefundedData struct {
+		WithdrawalID uuid.UUID `json:"withdrawal_id"`
+		Order        string    `json:"order"`
+		Sum          float64   `json:"sum"`
+		Reason       string    `json:"reason"`
+	}
+
+	PointsSentData struct {
+		TransferID  uuid.UUID `json:"transfer_id"`
+		RecipientID uuid.UUID `json:"recipient_id"`
+		Sum         float64   `json:"sum"`
+	}
+
+	PointsReceivedData struct {
+		TransferID uuid.UUID `json:"transfer_id"`
+		SenderID   uuid.UUID `json:"sender_id"`
+		Sum        float64   `json:"sum"`
+	}
+)
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxPointsAmount is the largest amount, in hundredths, a single operation
+// may move: amounts are stored in INTEGER columns.
+const MaxPointsAmount = math.MaxInt32
+
+// ExpiringPoints is the part of a balance that expires at ExpiresAt.
+type ExpiringPoints struct {
+	Amount    int       `db:"amount"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// PointsExpiration is what the expiry job took from a user's balance.
+type PointsExpiration struct {
+	UserID uuid.UUID `db:"user_id"`
+	Amount int       `db:"amount"`
+	Lots   int       `db:"lots"`
+}
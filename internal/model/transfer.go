@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransferDirection tells whether a user sent or received a transfer.
+type TransferDirection string
+
+const (
+	TransferSent     TransferDirection = "SENT"
+	TransferReceived TransferDirection = "RECEIVED"
+)
+
+// PointsTransfer moves Sum points from the balance of one user to another.
+type PointsTransfer struct {
+	ID             uuid.UUID `db:"id"`
+	SenderID       uuid.UUID `db:"sender_id"`
+	SenderLogin    string    `db:"sender_login"`
+	RecipientID    uuid.UUID `db:"recipient_id"`
+	RecipientLogin string    `db:"recipient_login"`
+	Sum            int       `db:"sum"`
+	Note           string    `db:"note"`
+	IdempotencyKey string    `db:"idempotency_key"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// Direction is the side of the transfer the user is on.
+func (t PointsTransfer) Direction(userID uuid.UUID) TransferDirection {
+	if t.SenderID == userID {
+		return TransferSent
+	}
+	return TransferReceived
+}
+
+// Counterparty is the login of the other side of the transfer for the user.
+func (t PointsTransfer) Counterparty(userID uuid.UUID) string {
+	if t.SenderID == userID {
+		return t.RecipientLogin
+	}
+	return t.SenderLogin
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventType names an event sent to webhook subscribers.
+type WebhookEventType string
+
+const (
+	WebhookOrderProcessed    WebhookEventType = "order.processed"
+	WebhookOrderInvalid      WebhookEventType = "order.invalid"
+	WebhookWithdrawalCreated WebhookEventType = "withdrawal.created"
+)
+
+// ParseWebhookEventTypes checks a subscription's event types and drops
+// duplicates.
+func ParseWebhookEventTypes(types []string) ([]WebhookEventType, error) {
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%w: no event types", ErrInvalidRequestParams)
+	}
+
+	seen := make(map[WebhookEventType]bool, len(types))
+	parsed := make([]WebhookEventType, 0, len(types))
+	for _, s := range types {
+		t := WebhookEventType(s)
+		switch t {
+		case WebhookOrderProcessed, WebhookOrderInvalid, WebhookWithdrawalCreated:
+		default:
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidRequestParams, s)
+		}
+		if !seen[t] {
+			seen[t] = true
+			parsed = append(parsed, t)
+		}
+	}
+
+	return parsed, nil
+}
+
+// WebhookSubscription is a receiver of events. Secret signs the payloads.
+type WebhookSubscription struct {
+	ID         uuid.UUID
+	URL        string
+	Secret     string
+	EventTypes []WebhookEventType
+	CreatedAt  time.Time
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed means every attempt failed. The delivery can
+	// still be replayed.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent to one subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `db:"id"`
+	EventID        uuid.UUID             `db:"event_id"`
+	SubscriptionID uuid.UUID             `db:"subscription_id"`
+	EventType      WebhookEventType      `db:"event_type"`
+	Status         WebhookDeliveryStatus `db:"status"`
+	Attempts       int                   `db:"attempts"`
+	NextAttemptAt  time.Time             `db:"next_attempt_at"`
+	DeliveredAt    *time.Time            `db:"delivered_at"`
+	CreatedAt      time.Time             `db:"created_at"`
+	Log            []WebhookAttempt      `db:"-"`
+}
+
+// WebhookAttempt is the log entry of one request to the receiver.
+// StatusCode is nil when no response was received.
+type WebhookAttempt struct {
+	DeliveryID uuid.UUID `db:"delivery_id"`
+	Attempt    int       `db:"attempt"`
+	StatusCode *int      `db:"status_code"`
+	Error      string    `db:"error"`
+	DurationMs int       `db:"duration_ms"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// WebhookJob is a due delivery together with what is needed to send it.
+type WebhookJob struct {
+	DeliveryID uuid.UUID        `db:"id"`
+	EventID    uuid.UUID        `db:"event_id"`
+	EventType  WebhookEventType `db:"event_type"`
+	Attempts   int              `db:"attempts"`
+	Payload    json.RawMessage  `db:"payload"`
+	URL        string           `db:"url"`
+	Secret     string           `db:"secret"`
+}
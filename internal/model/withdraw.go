@@ -6,12 +6,23 @@ import (
 	"github.com/google/uuid"
 )
 
+// RefundStatus tells how much of a withdrawal was returned to the user.
+type RefundStatus string
+
+const (
+	RefundStatusNone     RefundStatus = ""
+	RefundStatusPartial  RefundStatus = "PARTIALLY_REFUNDED"
+	RefundStatusRefunded RefundStatus = "REFUNDED"
+)
+
 type Withdrawal struct {
-	ID          uuid.UUID `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	OrderID     string    `json:"order"`
-	Sum         int       `json:"sum"`
-	ProcessedAt time.Time `json:"processed_at"`
+	ID          uuid.UUID `json:"id" db:"id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	OrderID     string    `json:"order" db:"order_id"`
+	Sum         int       `json:"sum" db:"sum"`
+	ProcessedAt time.Time `json:"processed_at" db:"processed_at"`
+	// Refunded is the part of Sum returned by refunds so far.
+	Refunded int `json:"refunded" db:"refunded"`
 }
 
 func NewWithdrawal(
@@ -33,3 +44,29 @@ func NewWithdrawal(
 }
 
 func (Withdrawal) TableName() string { return "withdraws" }
+
+// Refundable is the part of the withdrawal that can still be refunded.
+func (w Withdrawal) Refundable() int {
+	return w.Sum - w.Refunded
+}
+
+func (w Withdrawal) RefundStatus() RefundStatus {
+	switch {
+	case w.Refunded == 0:
+		return RefundStatusNone
+	case w.Refunded < w.Sum:
+		return RefundStatusPartial
+	default:
+		return RefundStatusRefunded
+	}
+}
+
+// WithdrawalRefund returns Sum of a withdrawal to the balance of the user.
+type WithdrawalRefund struct {
+	ID           uuid.UUID `db:"id"`
+	WithdrawalID uuid.UUID `db:"withdrawal_id"`
+	UserID       uuid.UUID `db:"user_id"`
+	Sum          int       `db:"sum"`
+	Reason       string    `db:"reason"`
+	CreatedAt    time.Time `db:"created_at"`
+}
@@ -0,0 +1,64 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// JSONLinesPublisher writes every event as one line of JSON.
+type JSONLinesPublisher struct {
+	mu   sync.Mutex
+	w    io.Writer
+	file *os.File
+}
+
+func NewJSONLinesPublisher(w io.Writer) *JSONLinesPublisher {
+	return &JSONLinesPublisher{w: w}
+}
+
+// OpenJSONLinesFile appends events to the file at path. Every batch is synced
+// to disk before it counts as published.
+func OpenJSONLinesFile(path string) (*JSONLinesPublisher, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONLinesPublisher{w: f, file: f}, nil
+}
+
+func (p *JSONLinesPublisher) Publish(_ context.Context, events []model.DomainEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	buf := bufio.NewWriter(p.w)
+	enc := json.NewEncoder(buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	if p.file != nil {
+		return p.file.Sync()
+	}
+
+	return nil
+}
+
+func (p *JSONLinesPublisher) Close() error {
+	if p.file != nil {
+		return p.file.Close()
+	}
+	return nil
+}
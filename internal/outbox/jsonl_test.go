@@ -0,0 +1,62 @@
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLinesPublisher(t *testing.T) {
+	userID := uuid.New()
+
+	var buf bytes.Buffer
+	p := NewJSONLinesPublisher(&buf)
+
+	require.NoError(t, p.Publish(t.Context(), []model.DomainEvent{testEvent(1, userID, 1), testEvent(2, userID, 2)}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(lines[1], &got))
+	assert.Equal(t, map[string]any{
+		"id":          float64(2),
+		"user_id":     userID.String(),
+		"seq":         float64(2),
+		"type":        "PointsWithdrawn",
+		"data":        map[string]any{"order": "2377225624", "sum": 7.5},
+		"occurred_at": "2025-01-01T10:00:00Z",
+	}, got)
+}
+
+func TestOpenJSONLinesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	userID := uuid.New()
+
+	for id := range int64(2) {
+		p, err := OpenJSONLinesFile(path)
+		require.NoError(t, err)
+		require.NoError(t, p.Publish(t.Context(), []model.DomainEvent{testEvent(id+1, userID, id+1)}))
+		require.NoError(t, p.Close())
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var ids []int64
+	for s := bufio.NewScanner(f); s.Scan(); {
+		var e model.DomainEvent
+		require.NoError(t, json.Unmarshal(s.Bytes(), &e))
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []int64{1, 2}, ids, "reopening appends")
+}
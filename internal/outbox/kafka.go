@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+const (
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+	// kafkaErrorBodyLimit bounds the part of an error response kept in the error.
+	kafkaErrorBodyLimit = 512
+)
+
+// KafkaRESTPublisher produces events to a topic through the REST proxy API
+// v2 of Confluent or Redpanda. Records are keyed by user, so the events of a
+// user land in one partition and keep their order.
+type KafkaRESTPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewKafkaRESTPublisher(baseURL, topic string, client *http.Client) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   client,
+	}
+}
+
+type kafkaRecord struct {
+	Key   string            `json:"key"`
+	Value model.DomainEvent `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, events []model.DomainEvent) error {
+	records := make([]kafkaRecord, len(events))
+	for i, e := range events {
+		records[i] = kafkaRecord{Key: e.UserID.String(), Value: e}
+	}
+
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, kafkaErrorBodyLimit))
+		return fmt.Errorf("kafka rest proxy returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&produced); err != nil {
+		return fmt.Errorf("malformed kafka rest proxy response: %w", err)
+	}
+
+	for i, o := range produced.Offsets {
+		if o.ErrorCode != nil {
+			return fmt.Errorf("kafka rejected event %d: %s (code %d)", events[i].ID, o.Error, *o.ErrorCode)
+		}
+	}
+
+	return nil
+}
+
+func (p *KafkaRESTPublisher) Close() error {
+	return nil
+}
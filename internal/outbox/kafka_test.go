@@ -0,0 +1,67 @@
+package outbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaRESTPublisher(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	events := []model.DomainEvent{testEvent(1, alice, 1), testEvent(2, bob, 1)}
+
+	t.Run("records are keyed by user", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/topics/gophermart.events", r.URL.Path)
+			assert.Equal(t, kafkaContentType, r.Header.Get("Content-Type"))
+
+			var body struct {
+				Records []struct {
+					Key   string            `json:"key"`
+					Value model.DomainEvent `json:"value"`
+				} `json:"records"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Len(t, body.Records, 2)
+			assert.Equal(t, alice.String(), body.Records[0].Key)
+			assert.Equal(t, int64(1), body.Records[0].Value.ID)
+			assert.Equal(t, bob.String(), body.Records[1].Key)
+
+			w.Write([]byte(`{"offsets":[{"partition":0,"offset":10,"error_code":null,"error":null},{"partition":1,"offset":4}]}`))
+		}))
+		defer srv.Close()
+
+		p := NewKafkaRESTPublisher(srv.URL+"/", "gophermart.events", srv.Client())
+		assert.NoError(t, p.Publish(t.Context(), events))
+	})
+
+	t.Run("rejected record fails the batch", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"offsets":[{"partition":0,"offset":10},{"error_code":50003,"error":"leader not available"}]}`))
+		}))
+		defer srv.Close()
+
+		p := NewKafkaRESTPublisher(srv.URL, "gophermart.events", srv.Client())
+		err := p.Publish(t.Context(), events)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "leader not available")
+	})
+
+	t.Run("proxy error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"error_code":40401,"message":"Topic not found."}`, http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		p := NewKafkaRESTPublisher(srv.URL, "gophermart.events", srv.Client())
+		err := p.Publish(t.Context(), events)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Topic not found.")
+	})
+}
@@ -0,0 +1,238 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// ErrNATSProtocol is returned when the NATS server rejects the connection or
+// a message.
+var ErrNATSProtocol = errors.New("nats protocol error")
+
+// NATSPublisher publishes events to the subject <prefix>.<event type> over
+// the NATS client protocol. A batch counts as delivered once the server has
+// answered the PING sent after it, which it does only after processing the
+// messages before. For durable delivery capture the subjects in a JetStream
+// stream: the Nats-Msg-Id header lets it drop redelivered events. A tls://
+// URL upgrades the connection to TLS after the server INFO.
+type NATSPublisher struct {
+	addr    string
+	user    string
+	pass    string
+	prefix  string
+	timeout time.Duration
+	// tlsConfig is nil for nats:// URLs.
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	// headers tells whether the server accepts HPUB.
+	headers bool
+}
+
+func NewNATSPublisher(rawURL, prefix string, timeout time.Duration) (*NATSPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	p := &NATSPublisher{addr: addr, prefix: prefix, timeout: timeout}
+	switch u.Scheme {
+	case "nats":
+	case "tls":
+		p.tlsConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return nil, fmt.Errorf("unsupported NATS URL scheme %q, expected nats or tls", u.Scheme)
+	}
+	if u.User != nil {
+		p.user = u.User.Username()
+		p.pass, _ = u.User.Password()
+	}
+
+	return p, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, events []model.DomainEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The connection is dropped on any error, its state is unknown then.
+	if err := p.publish(ctx, events); err != nil {
+		p.close()
+		return err
+	}
+
+	return nil
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, events []model.DomainEvent) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(p.conn)
+	for _, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		subject := p.prefix + "." + string(e.Type)
+		if p.headers {
+			hdr := "NATS/1.0\r\nNats-Msg-Id: " + strconv.FormatInt(e.ID, 10) + "\r\n\r\n"
+			fmt.Fprintf(w, "HPUB %s %d %d\r\n%s", subject, len(hdr), len(hdr)+len(payload), hdr)
+		} else {
+			fmt.Fprintf(w, "PUB %s %d\r\n", subject, len(payload))
+		}
+		w.Write(payload)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("PING\r\n")
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return p.awaitPong()
+}
+
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+	p.r = bufio.NewReader(conn)
+
+	if err := conn.SetDeadline(time.Now().Add(p.timeout)); err != nil {
+		return err
+	}
+
+	line, err := p.readLine()
+	if err != nil {
+		return err
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return fmt.Errorf("%w: expected INFO, got %q", ErrNATSProtocol, line)
+	}
+
+	var info struct {
+		Headers      bool `json:"headers"`
+		TLSRequired  bool `json:"tls_required"`
+		TLSAvailable bool `json:"tls_available"`
+	}
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		return fmt.Errorf("%w: malformed INFO: %v", ErrNATSProtocol, err)
+	}
+	p.headers = info.Headers
+
+	switch {
+	case p.tlsConfig == nil && info.TLSRequired:
+		return fmt.Errorf("%w: server requires TLS, use a tls:// URL", ErrNATSProtocol)
+	case p.tlsConfig != nil && !info.TLSRequired && !info.TLSAvailable:
+		return fmt.Errorf("%w: server does not offer TLS", ErrNATSProtocol)
+	case p.tlsConfig != nil:
+		tlsConn := tls.Client(conn, p.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		p.conn = tlsConn
+		p.r = bufio.NewReader(tlsConn)
+	}
+
+	opts, err := json.Marshal(map[string]any{
+		"verbose":      false,
+		"pedantic":     false,
+		"headers":      info.Headers,
+		"name":         "gophermart",
+		"lang":         "go",
+		"protocol":     1,
+		"tls_required": p.tlsConfig != nil,
+		"user":         p.user,
+		"pass":         p.pass,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The server answers the PING only if it accepted CONNECT.
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", opts); err != nil {
+		return err
+	}
+
+	return p.awaitPong()
+}
+
+func (p *NATSPublisher) awaitPong() error {
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("%w: %s", ErrNATSProtocol, strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and INFO updates need no answer.
+	}
+}
+
+func (p *NATSPublisher) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *NATSPublisher) close() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.close()
+	return nil
+}
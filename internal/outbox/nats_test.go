@@ -0,0 +1,184 @@
+package outbox
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type natsMessage struct {
+	subject string
+	msgID   string
+	event   model.DomainEvent
+}
+
+// testTLS returns a server certificate for 127.0.0.1 and a pool trusting it.
+func testTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	return srv.TLS, srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+}
+
+// fakeNATS accepts one client, answers the handshake and collects the
+// published messages. rejectAfter, when positive, makes it answer -ERR
+// instead of PONG once that many messages arrived. With tlsConfig the
+// server requires TLS after INFO.
+func fakeNATS(t *testing.T, rejectAfter int, tlsConfig *tls.Config) (string, <-chan natsMessage) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan natsMessage, 16)
+
+	go func() {
+		defer close(messages)
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if tlsConfig == nil {
+			fmt.Fprint(conn, `INFO {"server_id":"test","headers":true}`+"\r\n")
+		} else {
+			fmt.Fprint(conn, `INFO {"server_id":"test","headers":true,"tls_required":true}`+"\r\n")
+			conn = tls.Server(conn, tlsConfig)
+		}
+
+		r := bufio.NewReader(conn)
+		received := 0
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+
+			switch fields[0] {
+			case "PING":
+				if rejectAfter > 0 && received >= rejectAfter {
+					fmt.Fprint(conn, "-ERR 'Permissions Violation'\r\n")
+					continue
+				}
+				fmt.Fprint(conn, "PONG\r\n")
+			case "HPUB":
+				hdrLen, _ := strconv.Atoi(fields[2])
+				total, _ := strconv.Atoi(fields[3])
+				buf := make([]byte, total+2)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					return
+				}
+
+				msg := natsMessage{subject: fields[1]}
+				for _, h := range strings.Split(string(buf[:hdrLen]), "\r\n") {
+					if v, ok := strings.CutPrefix(h, "Nats-Msg-Id: "); ok {
+						msg.msgID = v
+					}
+				}
+				if err := json.Unmarshal(buf[hdrLen:total], &msg.event); err != nil {
+					return
+				}
+				received++
+				messages <- msg
+			}
+		}
+	}()
+
+	if tlsConfig != nil {
+		return "tls://" + ln.Addr().String(), messages
+	}
+	return "nats://" + ln.Addr().String(), messages
+}
+
+func TestNATSPublisher(t *testing.T) {
+	userID := uuid.New()
+	events := []model.DomainEvent{testEvent(7, userID, 1), testEvent(8, userID, 2)}
+
+	t.Run("publishes to the event type subject", func(t *testing.T) {
+		url, messages := fakeNATS(t, 0, nil)
+
+		p, err := NewNATSPublisher(url, "gophermart.events", time.Second)
+		require.NoError(t, err)
+		defer p.Close()
+
+		require.NoError(t, p.Publish(t.Context(), events))
+
+		for _, want := range events {
+			msg := <-messages
+			assert.Equal(t, "gophermart.events.PointsWithdrawn", msg.subject)
+			assert.Equal(t, strconv.FormatInt(want.ID, 10), msg.msgID)
+			assert.Equal(t, want.Seq, msg.event.Seq)
+		}
+	})
+
+	t.Run("server error fails the batch", func(t *testing.T) {
+		url, _ := fakeNATS(t, 1, nil)
+
+		p, err := NewNATSPublisher(url, "gophermart.events", time.Second)
+		require.NoError(t, err)
+		defer p.Close()
+
+		err = p.Publish(t.Context(), events)
+		require.ErrorIs(t, err, ErrNATSProtocol)
+		assert.Nil(t, p.conn, "connection is dropped after an error")
+	})
+
+	t.Run("publishes over TLS", func(t *testing.T) {
+		serverTLS, roots := testTLS(t)
+		url, messages := fakeNATS(t, 0, serverTLS)
+
+		p, err := NewNATSPublisher(url, "gophermart.events", time.Second)
+		require.NoError(t, err)
+		defer p.Close()
+		p.tlsConfig.RootCAs = roots
+
+		require.NoError(t, p.Publish(t.Context(), events))
+
+		for _, want := range events {
+			msg := <-messages
+			assert.Equal(t, strconv.FormatInt(want.ID, 10), msg.msgID)
+		}
+	})
+
+	t.Run("plain connection to a TLS server is rejected", func(t *testing.T) {
+		serverTLS, _ := testTLS(t)
+		url, _ := fakeNATS(t, 0, serverTLS)
+
+		p, err := NewNATSPublisher(strings.Replace(url, "tls://", "nats://", 1), "gophermart.events", time.Second)
+		require.NoError(t, err)
+		defer p.Close()
+
+		err = p.Publish(t.Context(), events)
+		require.ErrorIs(t, err, ErrNATSProtocol)
+		assert.Contains(t, err.Error(), "requires TLS")
+	})
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		_, err := NewNATSPublisher("http://localhost:4222", "gophermart.events", time.Second)
+		assert.Error(t, err)
+	})
+}
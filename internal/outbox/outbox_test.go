@@ -0,0 +1,48 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// memoryPublisher is the in-memory sink used by tests. The first failures
+// calls fail.
+type memoryPublisher struct {
+	mu        sync.Mutex
+	published []model.DomainEvent
+	failures  int
+	closed    bool
+}
+
+func (p *memoryPublisher) Publish(_ context.Context, events []model.DomainEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return context.DeadlineExceeded
+	}
+	p.published = append(p.published, events...)
+	return nil
+}
+
+func (p *memoryPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func testEvent(id int64, userID uuid.UUID, seq int64) model.DomainEvent {
+	return model.DomainEvent{
+		ID:        id,
+		UserID:    userID,
+		Seq:       seq,
+		Type:      model.PointsWithdrawn,
+		Data:      json.RawMessage(`{"order":"2377225624","sum":7.5}`),
+		CreatedAt: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
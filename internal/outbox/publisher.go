@@ -0,0 +1,54 @@
+// Package outbox relays the domain events recorded in the events table to the
+// data platform.
+//
+// Events are written in the transaction that changes the state, so none is
+// lost or published for a rolled back change. The Relay publishes them in
+// order to a Publisher and marks them published afterwards: delivery is
+// at-least-once and consumers deduplicate by event ID. The seq of an event
+// numbers the events of its user, which are always published in seq order.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/config"
+	"github.com/mrhyman/gophermart/internal/model"
+)
+
+// publishTimeout bounds a single publish to a network sink.
+const publishTimeout = 10 * time.Second
+
+// Publisher delivers events to a sink. Publish must deliver the events in the
+// given order; if it fails, any of them may have been delivered and all of
+// them are passed again later.
+type Publisher interface {
+	Publish(ctx context.Context, events []model.DomainEvent) error
+	Close() error
+}
+
+// NewPublisher returns the sink selected by the configuration, or nil when
+// domain events are not relayed.
+func NewPublisher(cfg config.AppConfig) (Publisher, error) {
+	switch cfg.EventsSink {
+	case config.EventsSinkNone:
+		return nil, nil
+	case config.EventsSinkStdout:
+		return NewJSONLinesPublisher(os.Stdout), nil
+	case config.EventsSinkFile:
+		p, err := OpenJSONLinesFile(cfg.EventsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open events file: %w", err)
+		}
+		return p, nil
+	case config.EventsSinkNATS:
+		return NewNATSPublisher(cfg.EventsURL, cfg.EventsTopic, publishTimeout)
+	case config.EventsSinkKafka:
+		return NewKafkaRESTPublisher(cfg.EventsURL, cfg.EventsTopic, &http.Client{Timeout: publishTimeout}), nil
+	default:
+		return nil, fmt.Errorf("unknown events sink %q", cfg.EventsSink)
+	}
+}
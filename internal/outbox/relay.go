@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/repository"
+)
+
+// DefaultBatchSize is the number of events published at once.
+const DefaultBatchSize = 100
+
+// Relay moves events from the outbox to the publisher. Only one replica
+// relays at a time.
+type Relay struct {
+	repo      repository.OutboxRepository
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRelay(repo repository.OutboxRepository, publisher Publisher, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	log.Info("events relay started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("events relay stopped")
+			return r.publisher.Close()
+		case <-ticker.C:
+			if err := r.drain(ctx); err != nil && ctx.Err() == nil {
+				log.With("err", err.Error()).Error("failed to relay events")
+			}
+		}
+	}
+}
+
+// drain publishes batches until the backlog is empty. A failed batch is
+// retried on the next tick, later events wait for it to keep the order.
+func (r *Relay) drain(ctx context.Context) error {
+	for {
+		n, err := r.repo.PublishPending(ctx, r.batchSize, r.publisher.Publish)
+		if err != nil {
+			return err
+		}
+		if n < r.batchSize {
+			return nil
+		}
+	}
+}
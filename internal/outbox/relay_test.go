@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeOutbox keeps events in memory and behaves like OutboxRepo: a batch is
+// marked published only after a successful publish.
+type fakeOutbox struct {
+	pending []model.DomainEvent
+}
+
+func (f *fakeOutbox) PublishPending(ctx context.Context, limit int, publish repository.PublishFunc) (int, error) {
+	batch := f.pending[:min(limit, len(f.pending))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(ctx, batch); err != nil {
+		return 0, err
+	}
+	f.pending = f.pending[len(batch):]
+	return len(batch), nil
+}
+
+func TestRelay_drain(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	events := []model.DomainEvent{
+		testEvent(1, alice, 1),
+		testEvent(2, bob, 1),
+		testEvent(3, alice, 2),
+		testEvent(4, alice, 3),
+		testEvent(5, bob, 2),
+	}
+
+	t.Run("publishes the whole backlog in order", func(t *testing.T) {
+		pub := &memoryPublisher{}
+		r := NewRelay(&fakeOutbox{pending: events}, pub, time.Second, 2)
+
+		require.NoError(t, r.drain(context.Background()))
+		assert.Equal(t, events, pub.published)
+	})
+
+	t.Run("failed batch is published again before later events", func(t *testing.T) {
+		pub := &memoryPublisher{failures: 1}
+		outbox := &fakeOutbox{pending: events}
+		r := NewRelay(outbox, pub, time.Second, 2)
+
+		require.Error(t, r.drain(context.Background()))
+		assert.Empty(t, pub.published)
+		assert.Len(t, outbox.pending, len(events))
+
+		require.NoError(t, r.drain(context.Background()))
+		assert.Equal(t, events, pub.published)
+	})
+}
+
+func TestRelay_drainStopsOnShortBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockOutboxRepository(ctrl)
+	r := NewRelay(repo, &memoryPublisher{}, time.Second, DefaultBatchSize)
+
+	gomock.InOrder(
+		repo.EXPECT().PublishPending(gomock.Any(), DefaultBatchSize, gomock.Any()).Return(DefaultBatchSize, nil),
+		repo.EXPECT().PublishPending(gomock.Any(), DefaultBatchSize, gomock.Any()).Return(3, nil),
+	)
+
+	assert.NoError(t, r.drain(context.Background()))
+}
+
+func TestRelay_StartClosesPublisher(t *testing.T) {
+	pub := &memoryPublisher{}
+	r := NewRelay(&fakeOutbox{}, pub, time.Millisecond, DefaultBatchSize)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, r.Start(ctx))
+	assert.True(t, pub.closed)
+}
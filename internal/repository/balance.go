@@ -1,96 +1,355 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
 )
 
 //go:generate mockgen -source=balance.go -destination=mocks/mock_balance_repository.go -package=mocks
 
 type BalanceRepository interface {
-	GetUserBalance(ctx context.Context, userID uuid.UUID) (int, int, error)
+	GetUserBalance(ctx context.Context, userID uuid.UUID) (*model.Balance, error)
 	Withdraw(ctx context.Context, userID uuid.UUID, orderNumber string, sum int) error
+	CreateHold(ctx context.Context, userID uuid.UUID, orderNumber string, sum int, ttl time.Duration) (*model.WithdrawalHold, error)
+	GetHold(ctx context.Context, userID, holdID uuid.UUID) (*model.WithdrawalHold, error)
+	ConfirmHold(ctx context.Context, userID, holdID uuid.UUID) (*model.WithdrawalHold, error)
+	ReleaseHold(ctx context.Context, userID, holdID uuid.UUID) (*model.WithdrawalHold, error)
+	ExpireHolds(ctx context.Context, now time.Time, limit int) (int, error)
 	GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]model.Withdrawal, error)
+	GetWithdrawalsByOrder(ctx context.Context, orderNumber string) ([]model.Withdrawal, error)
+	RefundWithdrawal(
+		ctx context.Context,
+		withdrawalID uuid.UUID,
+		sum int,
+		full bool,
+		reason string,
+	) (*model.WithdrawalRefund, *model.Withdrawal, error)
+	Transfer(
+		ctx context.Context,
+		senderID uuid.UUID,
+		recipientLogin string,
+		sum int,
+		note, idempotencyKey string,
+		dailyLimit int,
+	) (*model.PointsTransfer, bool, error)
+	GetTransfers(ctx context.Context, userID uuid.UUID) ([]model.PointsTransfer, error)
+	GetExpiringPoints(ctx context.Context, userID uuid.UUID, until time.Time) ([]model.ExpiringPoints, error)
+	ExpirePoints(ctx context.Context, now time.Time, limit int) ([]model.PointsExpiration, error)
 }
 
+const holdColumns = `id, user_id, order_number, sum, status, created_at, expires_at, resolved_at`
+
+const withdrawalColumns = `id, user_id, order_id, sum, processed_at, refunded`
+
+// transferQuery selects transfers with the logins of both sides.
+const transferQuery = `
+	SELECT t.id, t.sender_id, s.login AS sender_login, t.recipient_id, r.login AS recipient_login,
+		t.sum, t.note, t.idempotency_key, t.created_at
+	FROM point_transfers t
+	JOIN users s ON s.id = t.sender_id
+	JOIN users r ON r.id = t.recipient_id
+`
+
+// heldQuery sums the points reserved by active holds of the user $1.
+const heldQuery = `
+	SELECT COALESCE(SUM(sum), 0) AS held
+	FROM withdrawal_holds
+	WHERE user_id = $1 AND status = 'HELD' AND expires_at > NOW()
+`
+
+// expireLotsQuery zeroes the due lots of the given users and writes a debit
+// entry for each. It returns the expired amount per user.
+const expireLotsQuery = `
+	WITH due AS (
+		SELECT id, user_id, remaining
+		FROM point_lots
+		WHERE user_id = ANY($1) AND remaining > 0 AND expires_at <= $2
+	), expired AS (
+		UPDATE point_lots l
+		SET remaining = 0
+		FROM due
+		WHERE l.id = due.id
+		RETURNING due.id, due.user_id, due.remaining
+	), debits AS (
+		INSERT INTO point_expirations (lot_id, user_id, amount)
+		SELECT id, user_id, remaining FROM expired
+	)
+	SELECT user_id, SUM(remaining) AS amount, COUNT(*) AS lots
+	FROM expired
+	GROUP BY user_id
+	ORDER BY user_id
+`
+
 type BalanceRepo struct {
 	*GenericRepository[model.Withdrawal]
+	// pointsTTL is how long refunded points last, zero meaning forever.
+	pointsTTL time.Duration
 }
 
-func NewBalanceRepository(db *sqlx.DB) *BalanceRepo {
+func NewBalanceRepository(db *sqlx.DB, pointsTTL time.Duration) *BalanceRepo {
 	return &BalanceRepo{
 		GenericRepository: NewGenericRepository[model.Withdrawal](db),
+		pointsTTL:         pointsTTL,
 	}
 }
 
-func (r *BalanceRepo) GetUserBalance(ctx context.Context, userID uuid.UUID) (int, int, error) {
+func (r *BalanceRepo) GetUserBalance(ctx context.Context, userID uuid.UUID) (_ *model.Balance, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.GetUserBalance", "select_user_balance")
+	defer tracing.End(span, &err)
+
 	query := `
-		SELECT u.balance, COALESCE(w.withdrawn, 0) AS withdrawn
+		SELECT u.balance AS current, COALESCE(w.withdrawn, 0) AS withdrawn, h.held
 		FROM users u 
 		LEFT JOIN (
-			SELECT user_id, SUM(sum) AS withdrawn 
+			SELECT user_id, SUM(sum - refunded) AS withdrawn 
 			FROM withdraws 
 			GROUP BY user_id
 		) AS w ON u.id = w.user_id 
+		CROSS JOIN LATERAL (` + heldQuery + `) AS h
 		WHERE u.id = $1
 	`
 
-	var current, withdrawn int
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&current, &withdrawn)
-	if err != nil {
+	var balance model.Balance
+	if err := r.db.GetContext(ctx, &balance, query, userID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return 0, 0, model.ErrNotFound
+			return nil, model.ErrNotFound
 		}
-		return 0, 0, err
+		return nil, err
 	}
 
-	return current, withdrawn, nil
+	return &balance, nil
 }
 
-func (r *BalanceRepo) Withdraw(ctx context.Context, userID uuid.UUID, orderNumber string, sum int) error {
-	tx, err := r.db.BeginTx(ctx, nil)
+func (r *BalanceRepo) Withdraw(ctx context.Context, userID uuid.UUID, orderNumber string, sum int) (err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.Withdraw", "insert_withdrawal")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	var balance int
-	query := `SELECT balance FROM users WHERE id = $1 FOR UPDATE`
-	err = tx.QueryRowContext(ctx, query, userID).Scan(&balance)
+	balance, err := lockBalanceTx(ctx, tx, userID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return model.ErrNotFound
-		}
 		return err
 	}
 
-	if balance < sum {
+	if balance.Available() < sum {
 		return model.ErrInsufficientFunds
 	}
 
-	updateQuery := `UPDATE users SET balance = balance - $1 WHERE id = $2`
-	_, err = tx.ExecContext(ctx, updateQuery, sum, userID)
-	if err != nil {
+	if err := withdrawTx(ctx, tx, userID, orderNumber, sum); err != nil {
 		return err
 	}
 
-	insertQuery := `INSERT INTO withdraws (user_id, order_id, sum, processed_at) VALUES ($1, $2, $3, NOW())`
-	_, err = tx.ExecContext(ctx, insertQuery, userID, orderNumber, sum)
+	return tx.Commit()
+}
+
+// CreateHold reserves sum of the available points for the order until ttl
+// passes. A user can have one active hold per order.
+func (r *BalanceRepo) CreateHold(
+	ctx context.Context,
+	userID uuid.UUID,
+	orderNumber string,
+	sum int,
+	ttl time.Duration,
+) (_ *model.WithdrawalHold, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.CreateHold", "insert_withdrawal_hold")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	return tx.Commit()
+	balance, err := lockBalanceTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existsQuery := `
+		SELECT EXISTS (
+			SELECT 1 FROM withdrawal_holds
+			WHERE user_id = $1 AND order_number = $2 AND status = 'HELD' AND expires_at > NOW()
+		)
+	`
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, existsQuery, userID, orderNumber); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, model.NewAlreadyExistsError("withdrawal hold", orderNumber, nil)
+	}
+
+	if balance.Available() < sum {
+		return nil, model.ErrInsufficientFunds
+	}
+
+	insertQuery := `
+		INSERT INTO withdrawal_holds (user_id, order_number, sum, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4::float8 * INTERVAL '1 second')
+		RETURNING ` + holdColumns
+
+	var hold model.WithdrawalHold
+	if err := tx.GetContext(ctx, &hold, insertQuery, userID, orderNumber, sum, ttl.Seconds()); err != nil {
+		return nil, err
+	}
+
+	if err := notifyBalance(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+func (r *BalanceRepo) GetHold(ctx context.Context, userID, holdID uuid.UUID) (_ *model.WithdrawalHold, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.GetHold", "select_withdrawal_hold")
+	defer tracing.End(span, &err)
+
+	query := `SELECT ` + holdColumns + ` FROM withdrawal_holds WHERE id = $1 AND user_id = $2`
+
+	var hold model.WithdrawalHold
+	if err := r.db.GetContext(ctx, &hold, query, holdID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &hold, nil
 }
 
-func (r *BalanceRepo) GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]model.Withdrawal, error) {
+// ConfirmHold withdraws the held points. It fails with ErrInsufficientFunds
+// if points expired meanwhile left the balance short of the hold.
+func (r *BalanceRepo) ConfirmHold(ctx context.Context, userID, holdID uuid.UUID) (_ *model.WithdrawalHold, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.ConfirmHold", "confirm_withdrawal_hold")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	balance, err := lockBalanceTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := resolveHoldTx(ctx, tx, userID, holdID, model.HoldStatusConfirmed)
+	if err != nil {
+		return nil, err
+	}
+
+	if balance.Current < hold.Sum {
+		return nil, model.ErrInsufficientFunds
+	}
+
+	if err := withdrawTx(ctx, tx, userID, hold.Order, hold.Sum); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func (r *BalanceRepo) ReleaseHold(ctx context.Context, userID, holdID uuid.UUID) (_ *model.WithdrawalHold, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.ReleaseHold", "release_withdrawal_hold")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hold, err := resolveHoldTx(ctx, tx, userID, holdID, model.HoldStatusReleased)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := notifyBalance(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// ExpireHolds marks up to limit holds past their TTL at now as expired and
+// returns how many it marked. Such holds reserve nothing already, marking
+// them only settles their status.
+func (r *BalanceRepo) ExpireHolds(ctx context.Context, now time.Time, limit int) (_ int, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.ExpireHolds", "expire_withdrawal_holds")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE withdrawal_holds
+		SET status = 'EXPIRED', resolved_at = NOW()
+		WHERE id IN (
+			SELECT id FROM withdrawal_holds
+			WHERE status = 'HELD' AND expires_at <= $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING user_id
+	`
+
+	var expired int
+	err = r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var userIDs []uuid.UUID
+		if err := tx.SelectContext(ctx, &userIDs, query, now, limit); err != nil {
+			return err
+		}
+		expired = len(userIDs)
+
+		notified := make(map[uuid.UUID]bool, len(userIDs))
+		for _, userID := range userIDs {
+			if notified[userID] {
+				continue
+			}
+			notified[userID] = true
+
+			if err := notifyBalance(ctx, tx, userID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return expired, err
+}
+
+func (r *BalanceRepo) GetWithdrawals(ctx context.Context, userID uuid.UUID) (_ []model.Withdrawal, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.GetWithdrawals", "select_withdrawals_by_user")
+	defer tracing.End(span, &err)
+
 	query := `
-		SELECT id, user_id, order_id, sum, processed_at 
+		SELECT id, user_id, order_id, sum, processed_at, refunded 
 		FROM withdraws 
 		WHERE user_id = $1 
 		ORDER BY processed_at DESC
@@ -105,7 +364,7 @@ func (r *BalanceRepo) GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]m
 	var withdrawals []model.Withdrawal
 	for rows.Next() {
 		var w model.Withdrawal
-		err := rows.Scan(&w.ID, &w.UserID, &w.OrderID, &w.Sum, &w.ProcessedAt)
+		err := rows.Scan(&w.ID, &w.UserID, &w.OrderID, &w.Sum, &w.ProcessedAt, &w.Refunded)
 		if err != nil {
 			return nil, err
 		}
@@ -118,3 +377,465 @@ func (r *BalanceRepo) GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]m
 
 	return withdrawals, nil
 }
+
+// GetWithdrawalsByOrder returns the withdrawals paying for the shop order of
+// any user, latest first.
+func (r *BalanceRepo) GetWithdrawalsByOrder(ctx context.Context, orderNumber string) (_ []model.Withdrawal, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.GetWithdrawalsByOrder", "select_withdrawals_by_order")
+	defer tracing.End(span, &err)
+
+	query := `SELECT ` + withdrawalColumns + ` FROM withdraws WHERE order_id = $1 ORDER BY processed_at DESC`
+
+	var withdrawals []model.Withdrawal
+	err = r.db.SelectContext(ctx, &withdrawals, query, orderNumber)
+
+	return withdrawals, err
+}
+
+// RefundWithdrawal returns sum of the withdrawal to the balance of its user,
+// or all of the part not refunded yet when full is set. Refunds of a
+// withdrawal add up to at most its sum, beyond that the refund fails with
+// ErrRefundExceedsWithdrawal. The points come back as a new lot, so they
+// expire like any fresh credit.
+func (r *BalanceRepo) RefundWithdrawal(
+	ctx context.Context,
+	withdrawalID uuid.UUID,
+	sum int,
+	full bool,
+	reason string,
+) (_ *model.WithdrawalRefund, _ *model.Withdrawal, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.RefundWithdrawal", "insert_withdrawal_refund")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	if err := tx.GetContext(ctx, &userID, `SELECT user_id FROM withdraws WHERE id = $1`, withdrawalID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, model.ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	// The user is locked before the withdrawal, the same order of locks as
+	// in Withdraw.
+	if _, err := lockBalanceTx(ctx, tx, userID); err != nil {
+		return nil, nil, err
+	}
+
+	var withdrawal model.Withdrawal
+	lockQuery := `SELECT ` + withdrawalColumns + ` FROM withdraws WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &withdrawal, lockQuery, withdrawalID); err != nil {
+		return nil, nil, err
+	}
+
+	if full {
+		sum = withdrawal.Refundable()
+	}
+	if sum <= 0 || sum > withdrawal.Refundable() {
+		return nil, nil, model.ErrRefundExceedsWithdrawal
+	}
+
+	updateQuery := `UPDATE withdraws SET refunded = refunded + $1 WHERE id = $2 RETURNING ` + withdrawalColumns
+	if err := tx.GetContext(ctx, &withdrawal, updateQuery, sum, withdrawalID); err != nil {
+		return nil, nil, err
+	}
+
+	insertQuery := `
+		INSERT INTO withdrawal_refunds (withdrawal_id, user_id, sum, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, withdrawal_id, user_id, sum, reason, created_at
+	`
+	var refund model.WithdrawalRefund
+	if err := tx.GetContext(ctx, &refund, insertQuery, withdrawalID, userID, sum, reason); err != nil {
+		return nil, nil, err
+	}
+
+	balanceQuery := `UPDATE users SET balance = balance + $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, balanceQuery, sum, userID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := addLotTx(ctx, tx, userID, uuid.NullUUID{}, sum, r.pointsTTL, sql.NullTime{}); err != nil {
+		return nil, nil, err
+	}
+
+	err = appendEvent(ctx, tx, userID, model.PointsRefunded, model.PointsRefundedData{
+		WithdrawalID: withdrawalID,
+		Order:        withdrawal.OrderID,
+		Sum:          points(sum),
+		Reason:       reason,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := notifyBalance(ctx, tx, userID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return &refund, &withdrawal, nil
+}
+
+// Transfer moves sum points from the sender to the user with the recipient
+// login. A repeated idempotency key of the sender returns the transfer made
+// with it and false instead of moving the points again; the key reused for
+// another recipient, sum or note fails with ErrIdempotencyKeyReused. The
+// sender can transfer at most dailyLimit points within 24 hours, any amount
+// when it is zero. The recipient gets the points as a new lot, so they
+// expire like a fresh credit.
+func (r *BalanceRepo) Transfer(
+	ctx context.Context,
+	senderID uuid.UUID,
+	recipientLogin string,
+	sum int,
+	note, idempotencyKey string,
+	dailyLimit int,
+) (_ *model.PointsTransfer, _ bool, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.Transfer", "insert_point_transfer")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var recipientID uuid.UUID
+	if err := tx.GetContext(ctx, &recipientID, `SELECT id FROM users WHERE login = $1`, recipientLogin); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, model.ErrNotFound
+		}
+		return nil, false, err
+	}
+
+	if recipientID == senderID {
+		return nil, false, fmt.Errorf("%w: cannot transfer points to yourself", model.ErrInvalidRequestParams)
+	}
+
+	// Both users are locked in id order, like in lockUsersTx. Locking the
+	// sender first would deadlock two users transferring to each other.
+	first, second := senderID, recipientID
+	if bytes.Compare(first[:], second[:]) > 0 {
+		first, second = second, first
+	}
+
+	var balance *model.Balance
+	for _, userID := range []uuid.UUID{first, second} {
+		locked, err := lockBalanceTx(ctx, tx, userID)
+		if err != nil {
+			return nil, false, err
+		}
+		if userID == senderID {
+			balance = locked
+		}
+	}
+
+	// The lock of the sender serializes requests with the same key, so the
+	// key is looked up only now.
+	var existing model.PointsTransfer
+	existingQuery := transferQuery + ` WHERE t.sender_id = $1 AND t.idempotency_key = $2`
+	err = tx.GetContext(ctx, &existing, existingQuery, senderID, idempotencyKey)
+	switch {
+	case err == nil:
+		if existing.RecipientID != recipientID || existing.Sum != sum || existing.Note != note {
+			return nil, false, model.ErrIdempotencyKeyReused
+		}
+		return &existing, false, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, false, err
+	}
+
+	if balance.Available() < sum {
+		return nil, false, model.ErrInsufficientFunds
+	}
+
+	if dailyLimit > 0 {
+		sentQuery := `
+			SELECT COALESCE(SUM(sum), 0)
+			FROM point_transfers
+			WHERE sender_id = $1 AND created_at > NOW() - INTERVAL '24 hours'
+		`
+		var sent int
+		if err := tx.GetContext(ctx, &sent, sentQuery, senderID); err != nil {
+			return nil, false, err
+		}
+		if sent+sum > dailyLimit {
+			return nil, false, model.ErrTransferLimitExceeded
+		}
+	}
+
+	balanceQuery := `UPDATE users SET balance = balance + $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, balanceQuery, -sum, senderID); err != nil {
+		return nil, false, err
+	}
+	if err := consumeLotsTx(ctx, tx, senderID, sum); err != nil {
+		return nil, false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, balanceQuery, sum, recipientID); err != nil {
+		return nil, false, err
+	}
+	if err := addLotTx(ctx, tx, recipientID, uuid.NullUUID{}, sum, r.pointsTTL, sql.NullTime{}); err != nil {
+		return nil, false, err
+	}
+
+	insertQuery := `
+		INSERT INTO point_transfers (sender_id, recipient_id, sum, note, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	transfer := model.PointsTransfer{
+		SenderID:       senderID,
+		RecipientID:    recipientID,
+		RecipientLogin: recipientLogin,
+		Sum:            sum,
+		Note:           note,
+		IdempotencyKey: idempotencyKey,
+	}
+	row := tx.QueryRowxContext(ctx, insertQuery, senderID, recipientID, sum, note, idempotencyKey)
+	if err := row.Scan(&transfer.ID, &transfer.CreatedAt); err != nil {
+		return nil, false, err
+	}
+
+	err = appendEvent(ctx, tx, senderID, model.PointsSent, model.PointsSentData{
+		TransferID:  transfer.ID,
+		RecipientID: recipientID,
+		Sum:         points(sum),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = appendEvent(ctx, tx, recipientID, model.PointsReceived, model.PointsReceivedData{
+		TransferID: transfer.ID,
+		SenderID:   senderID,
+		Sum:        points(sum),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, userID := range []uuid.UUID{senderID, recipientID} {
+		if err := notifyBalance(ctx, tx, userID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return &transfer, true, nil
+}
+
+// GetTransfers returns the transfers the user sent or received, latest first.
+func (r *BalanceRepo) GetTransfers(ctx context.Context, userID uuid.UUID) (_ []model.PointsTransfer, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.GetTransfers", "select_point_transfers_by_user")
+	defer tracing.End(span, &err)
+
+	query := transferQuery + `
+		WHERE t.sender_id = $1 OR t.recipient_id = $1
+		ORDER BY t.created_at DESC
+	`
+
+	var transfers []model.PointsTransfer
+	err = r.db.SelectContext(ctx, &transfers, query, userID)
+
+	return transfers, err
+}
+
+// GetExpiringPoints returns the points of the user expiring until the given
+// time, soonest first.
+func (r *BalanceRepo) GetExpiringPoints(ctx context.Context, userID uuid.UUID, until time.Time) (_ []model.ExpiringPoints, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.GetExpiringPoints", "select_expiring_point_lots")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT SUM(remaining) AS amount, expires_at
+		FROM point_lots
+		WHERE user_id = $1 AND remaining > 0 AND expires_at <= $2
+		GROUP BY expires_at
+		ORDER BY expires_at
+	`
+
+	var expiring []model.ExpiringPoints
+	err = r.db.SelectContext(ctx, &expiring, query, userID, until)
+
+	return expiring, err
+}
+
+// ExpirePoints expires the lots due at now of up to limit users and takes
+// the expired points from their balances.
+func (r *BalanceRepo) ExpirePoints(ctx context.Context, now time.Time, limit int) (_ []model.PointsExpiration, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.ExpirePoints", "expire_point_lots")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Users are locked in id order before their lots are touched, the same
+	// order of locks as in Withdraw.
+	usersQuery := `
+		SELECT id FROM users
+		WHERE id IN (
+			SELECT user_id FROM point_lots
+			WHERE remaining > 0 AND expires_at <= $1
+		)
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE
+	`
+
+	var userIDs []uuid.UUID
+	if err := tx.SelectContext(ctx, &userIDs, usersQuery, now, limit); err != nil {
+		return nil, err
+	}
+
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	var expirations []model.PointsExpiration
+	if err := tx.SelectContext(ctx, &expirations, expireLotsQuery, pq.Array(userIDs), now); err != nil {
+		return nil, err
+	}
+
+	balanceQuery := `UPDATE users SET balance = balance - $1 WHERE id = $2`
+	for _, e := range expirations {
+		if _, err := tx.ExecContext(ctx, balanceQuery, e.Amount, e.UserID); err != nil {
+			return nil, err
+		}
+
+		err := appendEvent(ctx, tx, e.UserID, model.PointsExpired, model.PointsExpiredData{
+			Amount: points(e.Amount),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := notifyBalance(ctx, tx, e.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return expirations, nil
+}
+
+// lockUsersTx locks the rows of the given users in id order. Transactions
+// changing several users take their locks this way before anything else
+// locks a user row, e.g. taking an event seq or updating a balance, so they
+// cannot deadlock with each other or with Transfer.
+func lockUsersTx(ctx context.Context, tx sqlx.QueryerContext, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	query := `SELECT id FROM users WHERE id = ANY($1) ORDER BY id FOR UPDATE`
+
+	var locked []uuid.UUID
+	return sqlx.SelectContext(ctx, tx, &locked, query, pq.Array(userIDs))
+}
+
+// lockBalanceTx locks the user row, serializing every change to the balance
+// of the user, and returns the balance as of then.
+func lockBalanceTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) (*model.Balance, error) {
+	query := `
+		SELECT u.balance AS current, h.held
+		FROM users u
+		CROSS JOIN LATERAL (` + heldQuery + `) AS h
+		WHERE u.id = $1
+		FOR UPDATE OF u
+	`
+
+	var balance model.Balance
+	if err := tx.GetContext(ctx, &balance, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &balance, nil
+}
+
+// withdrawTx debits sum from the balance locked by the caller.
+func withdrawTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, orderNumber string, sum int) error {
+	updateQuery := `UPDATE users SET balance = balance - $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, updateQuery, sum, userID); err != nil {
+		return err
+	}
+
+	if err := consumeLotsTx(ctx, tx, userID, sum); err != nil {
+		return err
+	}
+
+	insertQuery := `INSERT INTO withdraws (user_id, order_id, sum, processed_at) VALUES ($1, $2, $3, NOW())`
+	if _, err := tx.ExecContext(ctx, insertQuery, userID, orderNumber, sum); err != nil {
+		return err
+	}
+
+	err := appendEvent(ctx, tx, userID, model.PointsWithdrawn, model.PointsWithdrawnData{
+		Order: orderNumber,
+		Sum:   points(sum),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueWithdrawalWebhook(ctx, tx, userID, orderNumber, sum); err != nil {
+		return err
+	}
+
+	return notifyBalance(ctx, tx, userID)
+}
+
+// resolveHoldTx moves an active hold of the user to status. A hold that is
+// not active fails with ErrHoldNotActive, a missing one with ErrNotFound.
+func resolveHoldTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID, holdID uuid.UUID,
+	status model.HoldStatus,
+) (*model.WithdrawalHold, error) {
+	query := `
+		UPDATE withdrawal_holds
+		SET status = $3, resolved_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'HELD' AND expires_at > NOW()
+		RETURNING ` + holdColumns
+
+	var hold model.WithdrawalHold
+	err := tx.GetContext(ctx, &hold, query, holdID, userID, status)
+	if err == nil {
+		return &hold, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var exists bool
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM withdrawal_holds WHERE id = $1 AND user_id = $2)`
+	if err := tx.GetContext(ctx, &exists, existsQuery, holdID, userID); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, model.ErrNotFound
+	}
+
+	return nil, model.ErrHoldNotActive
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
+)
+
+//go:generate mockgen -source=event.go -destination=mocks/mock_event_repository.go -package=mocks
+
+// EventsChannel is the NOTIFY channel carrying user events to every replica.
+const EventsChannel = "user_events"
+
+// EventRepository publishes user events with NOTIFY. Notifications are sent
+// when the transaction commits and dropped when it rolls back, so an event is
+// never seen for a change that did not happen.
+type EventRepository interface {
+	PublishOrderStatusTx(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) error
+	PublishBalanceTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) error
+}
+
+// The payloads mirror the order and balance responses of the API.
+const (
+	notifyOrderStatusQuery = `
+		SELECT pg_notify($1, json_build_object(
+			'id', nextval('user_event_seq'),
+			'user_id', user_id,
+			'type', $3::text,
+			'data', json_build_object(
+				'number', number,
+				'status', status,
+				'accrual', round(COALESCE(accrual, 0) / 100.0, 2)
+			)
+		)::text)
+		FROM orders
+		WHERE id = $2
+	`
+
+	notifyBalanceQuery = `
+		SELECT pg_notify($1, json_build_object(
+			'id', nextval('user_event_seq'),
+			'user_id', u.id,
+			'type', $3::text,
+			'data', json_build_object(
+				'current', round(u.balance / 100.0, 2),
+				'withdrawn', round(COALESCE((SELECT SUM(w.sum - w.refunded) FROM withdraws w WHERE w.user_id = u.id), 0) / 100.0, 2),
+				'available', round((u.balance - h.held) / 100.0, 2),
+				'held', round(h.held / 100.0, 2)
+			)
+		)::text)
+		FROM users u
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(sum), 0) AS held
+			FROM withdrawal_holds
+			WHERE user_id = u.id AND status = 'HELD' AND expires_at > NOW()
+		) AS h
+		WHERE u.id = $2
+	`
+)
+
+type EventRepo struct {
+	db *sqlx.DB
+}
+
+func NewEventRepository(db *sqlx.DB) *EventRepo {
+	return &EventRepo{db: db}
+}
+
+func (r *EventRepo) PublishOrderStatusTx(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "EventRepo.PublishOrderStatusTx", "notify_order_status")
+	defer tracing.End(span, &err)
+
+	_, err = tx.ExecContext(ctx, notifyOrderStatusQuery, EventsChannel, orderID, model.EventOrderStatus)
+	return err
+}
+
+func (r *EventRepo) PublishBalanceTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "EventRepo.PublishBalanceTx", "notify_balance")
+	defer tracing.End(span, &err)
+
+	return notifyBalance(ctx, tx, userID)
+}
+
+func notifyBalance(ctx context.Context, tx sqlx.ExecerContext, userID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, notifyBalanceQuery, EventsChannel, userID, model.EventBalance)
+	return err
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
+)
+
+//go:generate mockgen -source=import.go -destination=mocks/mock_import_repository.go -package=mocks
+
+type ImportRepository interface {
+	CreateJob(ctx context.Context, job *model.ImportJob) error
+	FinishJob(ctx context.Context, job *model.ImportJob) error
+	GetJob(ctx context.Context, id uuid.UUID) (*model.ImportJob, error)
+	Preview(ctx context.Context, rows []model.ImportRow) ([]model.ImportRowResult, error)
+	Apply(ctx context.Context, jobID uuid.UUID, rows []model.ImportRow) ([]model.ImportRowResult, error)
+}
+
+const (
+	reasonUnknownLogin = "unknown login"
+	reasonOwnedByOther = "order uploaded by another user"
+)
+
+type ImportRepo struct {
+	*GenericRepository[model.ImportJob]
+	// pointsTTL is how long credited points last, zero meaning forever.
+	pointsTTL time.Duration
+}
+
+func NewImportRepository(db *sqlx.DB, pointsTTL time.Duration) *ImportRepo {
+	return &ImportRepo{
+		GenericRepository: NewGenericRepository[model.ImportJob](db),
+		pointsTTL:         pointsTTL,
+	}
+}
+
+func (r *ImportRepo) CreateJob(ctx context.Context, job *model.ImportJob) (err error) {
+	ctx, span := startSpan(ctx, "ImportRepo.CreateJob", "insert_import_job")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO import_jobs (id, mode, status, total_rows)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	return r.db.QueryRowContext(ctx, query, job.ID, job.Mode, job.Status, job.TotalRows).Scan(&job.CreatedAt)
+}
+
+func (r *ImportRepo) FinishJob(ctx context.Context, job *model.ImportJob) (err error) {
+	ctx, span := startSpan(ctx, "ImportRepo.FinishJob", "update_import_job")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE import_jobs
+		SET status = $1, imported = $2, skipped = $3, failed = $4, report = $5, finished_at = NOW()
+		WHERE id = $6
+		RETURNING finished_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		job.Status,
+		job.Imported,
+		job.Skipped,
+		job.Failed,
+		job.Report,
+		job.ID,
+	).Scan(&job.FinishedAt)
+}
+
+func (r *ImportRepo) GetJob(ctx context.Context, id uuid.UUID) (*model.ImportJob, error) {
+	job, err := r.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+
+	return job, err
+}
+
+// Preview reports what Apply would do with rows without writing anything.
+func (r *ImportRepo) Preview(ctx context.Context, rows []model.ImportRow) (_ []model.ImportRowResult, err error) {
+	ctx, span := startSpan(ctx, "ImportRepo.Preview", "select_import_targets")
+	defer tracing.End(span, &err)
+
+	users, err := r.usersByLogin(ctx, r.db, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := make([]string, len(rows))
+	for i, row := range rows {
+		numbers[i] = row.Number
+	}
+
+	var owners []struct {
+		Number string    `db:"number"`
+		UserID uuid.UUID `db:"user_id"`
+	}
+	query := `SELECT number, user_id FROM orders WHERE number = ANY($1)`
+	if err := r.db.SelectContext(ctx, &owners, query, pq.Array(numbers)); err != nil {
+		return nil, err
+	}
+
+	ownerByNumber := make(map[string]uuid.UUID, len(owners))
+	for _, o := range owners {
+		ownerByNumber[o.Number] = o.UserID
+	}
+
+	results := make([]model.ImportRowResult, len(rows))
+	for i, row := range rows {
+		userID, known := users[row.Login]
+		owner, exists := ownerByNumber[row.Number]
+		results[i] = importResult(row, known, exists, owner == userID)
+	}
+
+	return results, nil
+}
+
+// insertImportedOrderQuery keeps existing orders untouched, so rows that have
+// already been imported are skipped.
+const insertImportedOrderQuery = `
+	INSERT INTO orders (id, user_id, number, status, accrual, created_at, import_job_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (number) DO NOTHING
+	RETURNING id
+`
+
+// Apply imports rows in a single transaction. Processed orders are credited
+// to the user balance the same way the accrual worker credits them.
+func (r *ImportRepo) Apply(ctx context.Context, jobID uuid.UUID, rows []model.ImportRow) (_ []model.ImportRowResult, err error) {
+	ctx, span := startSpan(ctx, "ImportRepo.Apply", "insert_imported_orders")
+	defer tracing.End(span, &err)
+
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	users, err := r.usersByLogin(ctx, tx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(users))
+	for _, userID := range users {
+		userIDs = append(userIDs, userID)
+	}
+	if err := lockUsersTx(ctx, tx, userIDs); err != nil {
+		return nil, err
+	}
+
+	results := make([]model.ImportRowResult, len(rows))
+	credited := make(map[uuid.UUID]bool)
+	for i, row := range rows {
+		userID, known := users[row.Login]
+		if !known {
+			results[i] = importResult(row, false, false, false)
+			continue
+		}
+
+		orderID := uuid.New()
+		err := tx.QueryRowContext(
+			ctx,
+			insertImportedOrderQuery,
+			orderID,
+			userID,
+			row.Number,
+			row.Status,
+			row.Accrual,
+			row.UploadedAt,
+			jobID,
+		).Scan(&orderID)
+		if errors.Is(err, sql.ErrNoRows) {
+			var owner uuid.UUID
+			if err := tx.GetContext(ctx, &owner, `SELECT user_id FROM orders WHERE number = $1`, row.Number); err != nil {
+				return nil, err
+			}
+			results[i] = importResult(row, true, true, owner == userID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		err = appendOrderUploaded(ctx, tx, userID, orderID, row.Number, row.Status, model.OrderSourceImport)
+		if err != nil {
+			return nil, err
+		}
+
+		if row.Status == model.OrderStatusProcessed && row.Accrual > 0 {
+			if err := creditImportedTx(ctx, tx, orderID, userID, row.Accrual, r.pointsTTL, row.UploadedAt); err != nil {
+				return nil, err
+			}
+			credited[userID] = true
+		}
+		results[i] = importResult(row, true, false, false)
+	}
+
+	for userID := range credited {
+		if err := notifyBalance(ctx, tx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *ImportRepo) usersByLogin(ctx context.Context, q sqlx.QueryerContext, rows []model.ImportRow) (map[string]uuid.UUID, error) {
+	logins := make([]string, len(rows))
+	for i, row := range rows {
+		logins[i] = row.Login
+	}
+
+	var users []model.User
+	query := `SELECT id, login FROM users WHERE login = ANY($1)`
+	if err := sqlx.SelectContext(ctx, q, &users, query, pq.Array(logins)); err != nil {
+		return nil, err
+	}
+
+	byLogin := make(map[string]uuid.UUID, len(users))
+	for _, u := range users {
+		byLogin[u.Login] = u.ID
+	}
+
+	return byLogin, nil
+}
+
+// creditImportedTx credits a historical accrual. Its points expire ttl after
+// creditedAt, the time the order was originally uploaded, not after now.
+func creditImportedTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	orderID, userID uuid.UUID,
+	amount int,
+	ttl time.Duration,
+	creditedAt time.Time,
+) error {
+	creditQuery := `
+		INSERT INTO accrual_credits (order_id, user_id, amount)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.ExecContext(ctx, creditQuery, orderID, userID, amount); err != nil {
+		return err
+	}
+
+	balanceQuery := `UPDATE users SET balance = balance + $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, balanceQuery, amount, userID); err != nil {
+		return err
+	}
+
+	if err := addLotTx(ctx, tx, userID, uuid.NullUUID{UUID: orderID, Valid: true}, amount, ttl, sql.NullTime{Time: creditedAt, Valid: true}); err != nil {
+		return err
+	}
+
+	return appendEvent(ctx, tx, userID, model.PointsCredited, model.PointsCreditedData{
+		OrderID: orderID,
+		Amount:  points(amount),
+		Reason:  model.CreditReasonImport,
+	})
+}
+
+func importResult(row model.ImportRow, knownUser, orderExists, sameOwner bool) model.ImportRowResult {
+	res := model.ImportRowResult{
+		Line:    row.Line,
+		Login:   row.Login,
+		Number:  row.Number,
+		Outcome: model.ImportImported,
+	}
+
+	switch {
+	case !knownUser:
+		res.Outcome, res.Reason = model.ImportFailed, reasonUnknownLogin
+	case orderExists && sameOwner:
+		res.Outcome = model.ImportSkipped
+	case orderExists:
+		res.Outcome, res.Reason = model.ImportFailed, reasonOwnedByOther
+	}
+
+	return res
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mrhyman/gophermart/internal/tracing"
+)
+
+//go:generate mockgen -source=lock.go -destination=mocks/mock_lock_repository.go -package=mocks
+
+type LockRepository interface {
+	TryAcquire(ctx context.Context, name string) (Lock, bool, error)
+}
+
+// Lock is a held session-level lock. It is released automatically by
+// Postgres when the session holding it dies.
+type Lock interface {
+	Alive(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+type LockRepo struct {
+	db *sqlx.DB
+}
+
+func NewLockRepository(db *sqlx.DB) *LockRepo {
+	return &LockRepo{db: db}
+}
+
+// TryAcquire takes a Postgres advisory lock keyed by name without blocking.
+// The returned lock pins a dedicated connection until it is released.
+func (r *LockRepo) TryAcquire(ctx context.Context, name string) (_ Lock, _ bool, err error) {
+	ctx, span := startSpan(ctx, "LockRepo.TryAcquire", "try_advisory_lock")
+	defer tracing.End(span, &err)
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := lockKey(name)
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Close()
+		return nil, false, err
+	}
+
+	return &advisoryLock{conn: conn, key: key}, true, nil
+}
+
+type advisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+func (l *advisoryLock) Alive(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, `SELECT 1`)
+	return err
+}
+
+func (l *advisoryLock) Release(ctx context.Context) error {
+	defer l.conn.Close()
+
+	_, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key)
+	return err
+}
+
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
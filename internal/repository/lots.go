@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// addLotQuery opens a lot for the part of the balance that no lot covers
+// yet. It runs after the balance was raised, so a credit that only pays off
+// a negative balance leaves nothing to expire. The lot is credited at $5, or
+// now when it is null, and expires relative to that.
+const addLotQuery = `
+	INSERT INTO point_lots (user_id, order_id, amount, remaining, credited_at, expires_at)
+	SELECT u.id, $2::uuid, $3::integer, LEAST($3::integer, u.balance - l.total), c.at,
+		CASE WHEN $4::float8 > 0 THEN c.at + $4::float8 * INTERVAL '1 second' END
+	FROM users u, (SELECT COALESCE($5::timestamptz, NOW()) AS at) c, LATERAL (
+		SELECT COALESCE(SUM(remaining), 0) AS total
+		FROM point_lots
+		WHERE user_id = u.id AND remaining > 0
+	) l
+	WHERE u.id = $1 AND u.balance > l.total
+`
+
+// consumeLotsQuery takes amount from the oldest lots first. Lots are not
+// locked here: every caller holds the lock of the user row.
+const consumeLotsQuery = `
+	WITH open AS (
+		SELECT id, SUM(remaining) OVER (ORDER BY credited_at, id) - remaining AS preceding
+		FROM point_lots
+		WHERE user_id = $1 AND remaining > 0
+	)
+	UPDATE point_lots l
+	SET remaining = l.remaining - LEAST(l.remaining, $2::bigint - o.preceding)
+	FROM open o
+	WHERE l.id = o.id AND o.preceding < $2::bigint
+`
+
+// addLotTx records a credit of amount as a lot expiring ttl after creditedAt,
+// or never when ttl is zero. orderID is null for credits not earned by an
+// order, creditedAt is null for credits made now. It must run after the user
+// balance was updated.
+func addLotTx(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	userID uuid.UUID,
+	orderID uuid.NullUUID,
+	amount int,
+	ttl time.Duration,
+	creditedAt sql.NullTime,
+) error {
+	_, err := tx.ExecContext(ctx, addLotQuery, userID, orderID, amount, ttl.Seconds(), creditedAt)
+	return err
+}
+
+// consumeLotsTx debits amount from the user's lots, oldest first.
+func consumeLotsTx(ctx context.Context, tx sqlx.ExecerContext, userID uuid.UUID, amount int) error {
+	_, err := tx.ExecContext(ctx, consumeLotsQuery, userID, amount)
+	return err
+}
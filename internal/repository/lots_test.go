@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// newTestDB starts a migrated Postgres in a container. Tests using it are
+// skipped with -short or when Docker is not available.
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("needs a Postgres container")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	ctr, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("gophermart"),
+		postgres.WithUsername("gophermart"),
+		postgres.WithPassword("gophermart"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	require.NoError(t, err)
+
+	dsn, err := ctr.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	repos, err := NewRepos(dsn, 5, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { repos.Close() })
+
+	require.NoError(t, repos.MigrateUp("../../migrations", dsn))
+
+	return repos.db
+}
+
+type lotRow struct {
+	Amount     int          `db:"amount"`
+	Remaining  int          `db:"remaining"`
+	CreditedAt time.Time    `db:"credited_at"`
+	ExpiresAt  sql.NullTime `db:"expires_at"`
+}
+
+func createLotUser(t *testing.T, db *sqlx.DB, balance int) uuid.UUID {
+	t.Helper()
+
+	var id uuid.UUID
+	err := db.Get(&id, `INSERT INTO users (login, password, balance) VALUES ($1, '', $2) RETURNING id`, uuid.NewString(), balance)
+	require.NoError(t, err)
+
+	return id
+}
+
+// credit raises the balance and adds the lot the way the repositories do.
+func credit(t *testing.T, db *sqlx.DB, userID uuid.UUID, amount int, ttl time.Duration, creditedAt sql.NullTime) {
+	t.Helper()
+
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, `UPDATE users SET balance = balance + $1 WHERE id = $2`, amount, userID)
+	require.NoError(t, err)
+	require.NoError(t, addLotTx(ctx, db, userID, uuid.NullUUID{}, amount, ttl, creditedAt))
+}
+
+func lots(t *testing.T, db *sqlx.DB, userID uuid.UUID) []lotRow {
+	t.Helper()
+
+	var rows []lotRow
+	err := db.Select(&rows, `
+		SELECT amount, remaining, credited_at, expires_at
+		FROM point_lots WHERE user_id = $1 ORDER BY credited_at, id
+	`, userID)
+	require.NoError(t, err)
+
+	return rows
+}
+
+func TestLots(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	t.Run("consumption takes the oldest lots first and splits the last one", func(t *testing.T) {
+		userID := createLotUser(t, db, 0)
+		credit(t, db, userID, 500, 0, sql.NullTime{Time: time.Now().Add(-2 * time.Hour), Valid: true})
+		credit(t, db, userID, 300, 0, sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true})
+		credit(t, db, userID, 200, 0, sql.NullTime{})
+
+		require.NoError(t, consumeLotsTx(ctx, db, userID, 600))
+
+		got := lots(t, db, userID)
+		require.Len(t, got, 3)
+		assert.Equal(t, 0, got[0].Remaining)
+		assert.Equal(t, 200, got[1].Remaining)
+		assert.Equal(t, 200, got[2].Remaining)
+	})
+
+	t.Run("consuming exactly a lot leaves the next one whole", func(t *testing.T) {
+		userID := createLotUser(t, db, 0)
+		credit(t, db, userID, 500, 0, sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true})
+		credit(t, db, userID, 300, 0, sql.NullTime{})
+
+		require.NoError(t, consumeLotsTx(ctx, db, userID, 500))
+
+		got := lots(t, db, userID)
+		require.Len(t, got, 2)
+		assert.Equal(t, 0, got[0].Remaining)
+		assert.Equal(t, 300, got[1].Remaining)
+	})
+
+	t.Run("credit only paying off a negative balance opens no lot", func(t *testing.T) {
+		userID := createLotUser(t, db, -500)
+		credit(t, db, userID, 300, time.Hour, sql.NullTime{})
+
+		assert.Empty(t, lots(t, db, userID))
+	})
+
+	t.Run("credit turning a negative balance positive covers only the surplus", func(t *testing.T) {
+		userID := createLotUser(t, db, -300)
+		credit(t, db, userID, 500, time.Hour, sql.NullTime{})
+
+		got := lots(t, db, userID)
+		require.Len(t, got, 1)
+		assert.Equal(t, 500, got[0].Amount)
+		assert.Equal(t, 200, got[0].Remaining)
+	})
+
+	t.Run("lot expires relative to its credit time", func(t *testing.T) {
+		userID := createLotUser(t, db, 0)
+		uploadedAt := time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Second)
+		credit(t, db, userID, 500, 24*time.Hour, sql.NullTime{Time: uploadedAt, Valid: true})
+
+		got := lots(t, db, userID)
+		require.Len(t, got, 1)
+		assert.True(t, got[0].CreditedAt.Equal(uploadedAt))
+		require.True(t, got[0].ExpiresAt.Valid)
+		assert.True(t, got[0].ExpiresAt.Time.Equal(uploadedAt.Add(24*time.Hour)))
+	})
+
+	t.Run("lot credited now expires after the ttl", func(t *testing.T) {
+		userID := createLotUser(t, db, 0)
+		before := time.Now()
+		credit(t, db, userID, 500, time.Hour, sql.NullTime{})
+
+		got := lots(t, db, userID)
+		require.Len(t, got, 1)
+		require.True(t, got[0].ExpiresAt.Valid)
+		assert.WithinDuration(t, before.Add(time.Hour), got[0].ExpiresAt.Time, time.Minute)
+	})
+}
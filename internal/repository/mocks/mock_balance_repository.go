@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	model "github.com/mrhyman/gophermart/internal/model"
@@ -42,14 +43,118 @@ func (m *MockBalanceRepository) EXPECT() *MockBalanceRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ConfirmHold mocks base method.
+func (m *MockBalanceRepository) ConfirmHold(ctx context.Context, userID, holdID uuid.UUID) (*model.WithdrawalHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmHold", ctx, userID, holdID)
+	ret0, _ := ret[0].(*model.WithdrawalHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmHold indicates an expected call of ConfirmHold.
+func (mr *MockBalanceRepositoryMockRecorder) ConfirmHold(ctx, userID, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmHold", reflect.TypeOf((*MockBalanceRepository)(nil).ConfirmHold), ctx, userID, holdID)
+}
+
+// CreateHold mocks base method.
+func (m *MockBalanceRepository) CreateHold(ctx context.Context, userID uuid.UUID, orderNumber string, sum int, ttl time.Duration) (*model.WithdrawalHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, userID, orderNumber, sum, ttl)
+	ret0, _ := ret[0].(*model.WithdrawalHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockBalanceRepositoryMockRecorder) CreateHold(ctx, userID, orderNumber, sum, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockBalanceRepository)(nil).CreateHold), ctx, userID, orderNumber, sum, ttl)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockBalanceRepository) ExpireHolds(ctx context.Context, now time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", ctx, now, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockBalanceRepositoryMockRecorder) ExpireHolds(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockBalanceRepository)(nil).ExpireHolds), ctx, now, limit)
+}
+
+// ExpirePoints mocks base method.
+func (m *MockBalanceRepository) ExpirePoints(ctx context.Context, now time.Time, limit int) ([]model.PointsExpiration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpirePoints", ctx, now, limit)
+	ret0, _ := ret[0].([]model.PointsExpiration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpirePoints indicates an expected call of ExpirePoints.
+func (mr *MockBalanceRepositoryMockRecorder) ExpirePoints(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpirePoints", reflect.TypeOf((*MockBalanceRepository)(nil).ExpirePoints), ctx, now, limit)
+}
+
+// GetExpiringPoints mocks base method.
+func (m *MockBalanceRepository) GetExpiringPoints(ctx context.Context, userID uuid.UUID, until time.Time) ([]model.ExpiringPoints, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiringPoints", ctx, userID, until)
+	ret0, _ := ret[0].([]model.ExpiringPoints)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiringPoints indicates an expected call of GetExpiringPoints.
+func (mr *MockBalanceRepositoryMockRecorder) GetExpiringPoints(ctx, userID, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiringPoints", reflect.TypeOf((*MockBalanceRepository)(nil).GetExpiringPoints), ctx, userID, until)
+}
+
+// GetHold mocks base method.
+func (m *MockBalanceRepository) GetHold(ctx context.Context, userID, holdID uuid.UUID) (*model.WithdrawalHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, userID, holdID)
+	ret0, _ := ret[0].(*model.WithdrawalHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockBalanceRepositoryMockRecorder) GetHold(ctx, userID, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockBalanceRepository)(nil).GetHold), ctx, userID, holdID)
+}
+
+// GetTransfers mocks base method.
+func (m *MockBalanceRepository) GetTransfers(ctx context.Context, userID uuid.UUID) ([]model.PointsTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransfers", ctx, userID)
+	ret0, _ := ret[0].([]model.PointsTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransfers indicates an expected call of GetTransfers.
+func (mr *MockBalanceRepositoryMockRecorder) GetTransfers(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfers", reflect.TypeOf((*MockBalanceRepository)(nil).GetTransfers), ctx, userID)
+}
+
 // GetUserBalance mocks base method.
-func (m *MockBalanceRepository) GetUserBalance(ctx context.Context, userID uuid.UUID) (int, int, error) {
+func (m *MockBalanceRepository) GetUserBalance(ctx context.Context, userID uuid.UUID) (*model.Balance, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetUserBalance", ctx, userID)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(int)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret0, _ := ret[0].(*model.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetUserBalance indicates an expected call of GetUserBalance.
@@ -73,6 +178,68 @@ func (mr *MockBalanceRepositoryMockRecorder) GetWithdrawals(ctx, userID any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawals", reflect.TypeOf((*MockBalanceRepository)(nil).GetWithdrawals), ctx, userID)
 }
 
+// GetWithdrawalsByOrder mocks base method.
+func (m *MockBalanceRepository) GetWithdrawalsByOrder(ctx context.Context, orderNumber string) ([]model.Withdrawal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawalsByOrder", ctx, orderNumber)
+	ret0, _ := ret[0].([]model.Withdrawal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawalsByOrder indicates an expected call of GetWithdrawalsByOrder.
+func (mr *MockBalanceRepositoryMockRecorder) GetWithdrawalsByOrder(ctx, orderNumber any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawalsByOrder", reflect.TypeOf((*MockBalanceRepository)(nil).GetWithdrawalsByOrder), ctx, orderNumber)
+}
+
+// RefundWithdrawal mocks base method.
+func (m *MockBalanceRepository) RefundWithdrawal(ctx context.Context, withdrawalID uuid.UUID, sum int, full bool, reason string) (*model.WithdrawalRefund, *model.Withdrawal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundWithdrawal", ctx, withdrawalID, sum, full, reason)
+	ret0, _ := ret[0].(*model.WithdrawalRefund)
+	ret1, _ := ret[1].(*model.Withdrawal)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RefundWithdrawal indicates an expected call of RefundWithdrawal.
+func (mr *MockBalanceRepositoryMockRecorder) RefundWithdrawal(ctx, withdrawalID, sum, full, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundWithdrawal", reflect.TypeOf((*MockBalanceRepository)(nil).RefundWithdrawal), ctx, withdrawalID, sum, full, reason)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockBalanceRepository) ReleaseHold(ctx context.Context, userID, holdID uuid.UUID) (*model.WithdrawalHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, userID, holdID)
+	ret0, _ := ret[0].(*model.WithdrawalHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockBalanceRepositoryMockRecorder) ReleaseHold(ctx, userID, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockBalanceRepository)(nil).ReleaseHold), ctx, userID, holdID)
+}
+
+// Transfer mocks base method.
+func (m *MockBalanceRepository) Transfer(ctx context.Context, senderID uuid.UUID, recipientLogin string, sum int, note, idempotencyKey string, dailyLimit int) (*model.PointsTransfer, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, senderID, recipientLogin, sum, note, idempotencyKey, dailyLimit)
+	ret0, _ := ret[0].(*model.PointsTransfer)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockBalanceRepositoryMockRecorder) Transfer(ctx, senderID, recipientLogin, sum, note, idempotencyKey, dailyLimit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockBalanceRepository)(nil).Transfer), ctx, senderID, recipientLogin, sum, note, idempotencyKey, dailyLimit)
+}
+
 // Withdraw mocks base method.
 func (m *MockBalanceRepository) Withdraw(ctx context.Context, userID uuid.UUID, orderNumber string, sum int) error {
 	m.ctrl.T.Helper()
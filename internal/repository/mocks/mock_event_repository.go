@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: event.go
+//
+// Generated by this command:
+//
+//	mockgen -source=event.go -destination=mocks/mock_event_repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	uuid "github.com/google/uuid"
+	sqlx "github.com/jmoiron/sqlx"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockEventRepository is a mock of EventRepository interface.
+type MockEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockEventRepositoryMockRecorder is the mock recorder for MockEventRepository.
+type MockEventRepositoryMockRecorder struct {
+	mock *MockEventRepository
+}
+
+// NewMockEventRepository creates a new mock instance.
+func NewMockEventRepository(ctrl *gomock.Controller) *MockEventRepository {
+	mock := &MockEventRepository{ctrl: ctrl}
+	mock.recorder = &MockEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventRepository) EXPECT() *MockEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// PublishBalanceTx mocks base method.
+func (m *MockEventRepository) PublishBalanceTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishBalanceTx", ctx, tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PublishBalanceTx indicates an expected call of PublishBalanceTx.
+func (mr *MockEventRepositoryMockRecorder) PublishBalanceTx(ctx, tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishBalanceTx", reflect.TypeOf((*MockEventRepository)(nil).PublishBalanceTx), ctx, tx, userID)
+}
+
+// PublishOrderStatusTx mocks base method.
+func (m *MockEventRepository) PublishOrderStatusTx(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishOrderStatusTx", ctx, tx, orderID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PublishOrderStatusTx indicates an expected call of PublishOrderStatusTx.
+func (mr *MockEventRepositoryMockRecorder) PublishOrderStatusTx(ctx, tx, orderID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishOrderStatusTx", reflect.TypeOf((*MockEventRepository)(nil).PublishOrderStatusTx), ctx, tx, orderID)
+}
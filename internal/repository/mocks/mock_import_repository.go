@@ -0,0 +1,116 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: import.go
+//
+// Generated by this command:
+//
+//	mockgen -source=import.go -destination=mocks/mock_import_repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	uuid "github.com/google/uuid"
+	model "github.com/mrhyman/gophermart/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockImportRepository is a mock of ImportRepository interface.
+type MockImportRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockImportRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockImportRepositoryMockRecorder is the mock recorder for MockImportRepository.
+type MockImportRepositoryMockRecorder struct {
+	mock *MockImportRepository
+}
+
+// NewMockImportRepository creates a new mock instance.
+func NewMockImportRepository(ctrl *gomock.Controller) *MockImportRepository {
+	mock := &MockImportRepository{ctrl: ctrl}
+	mock.recorder = &MockImportRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImportRepository) EXPECT() *MockImportRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Apply mocks base method.
+func (m *MockImportRepository) Apply(ctx context.Context, jobID uuid.UUID, rows []model.ImportRow) ([]model.ImportRowResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Apply", ctx, jobID, rows)
+	ret0, _ := ret[0].([]model.ImportRowResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Apply indicates an expected call of Apply.
+func (mr *MockImportRepositoryMockRecorder) Apply(ctx, jobID, rows any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Apply", reflect.TypeOf((*MockImportRepository)(nil).Apply), ctx, jobID, rows)
+}
+
+// CreateJob mocks base method.
+func (m *MockImportRepository) CreateJob(ctx context.Context, job *model.ImportJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJob", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateJob indicates an expected call of CreateJob.
+func (mr *MockImportRepositoryMockRecorder) CreateJob(ctx, job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockImportRepository)(nil).CreateJob), ctx, job)
+}
+
+// FinishJob mocks base method.
+func (m *MockImportRepository) FinishJob(ctx context.Context, job *model.ImportJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishJob", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishJob indicates an expected call of FinishJob.
+func (mr *MockImportRepositoryMockRecorder) FinishJob(ctx, job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishJob", reflect.TypeOf((*MockImportRepository)(nil).FinishJob), ctx, job)
+}
+
+// GetJob mocks base method.
+func (m *MockImportRepository) GetJob(ctx context.Context, id uuid.UUID) (*model.ImportJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJob", ctx, id)
+	ret0, _ := ret[0].(*model.ImportJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJob indicates an expected call of GetJob.
+func (mr *MockImportRepositoryMockRecorder) GetJob(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJob", reflect.TypeOf((*MockImportRepository)(nil).GetJob), ctx, id)
+}
+
+// Preview mocks base method.
+func (m *MockImportRepository) Preview(ctx context.Context, rows []model.ImportRow) ([]model.ImportRowResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preview", ctx, rows)
+	ret0, _ := ret[0].([]model.ImportRowResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preview indicates an expected call of Preview.
+func (mr *MockImportRepositoryMockRecorder) Preview(ctx, rows any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preview", reflect.TypeOf((*MockImportRepository)(nil).Preview), ctx, rows)
+}
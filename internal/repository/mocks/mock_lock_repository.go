@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: lock.go
+//
+// Generated by this command:
+//
+//	mockgen -source=lock.go -destination=mocks/mock_lock_repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/mrhyman/gophermart/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLockRepository is a mock of LockRepository interface.
+type MockLockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockLockRepositoryMockRecorder is the mock recorder for MockLockRepository.
+type MockLockRepositoryMockRecorder struct {
+	mock *MockLockRepository
+}
+
+// NewMockLockRepository creates a new mock instance.
+func NewMockLockRepository(ctrl *gomock.Controller) *MockLockRepository {
+	mock := &MockLockRepository{ctrl: ctrl}
+	mock.recorder = &MockLockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLockRepository) EXPECT() *MockLockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// TryAcquire mocks base method.
+func (m *MockLockRepository) TryAcquire(ctx context.Context, name string) (repository.Lock, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryAcquire", ctx, name)
+	ret0, _ := ret[0].(repository.Lock)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TryAcquire indicates an expected call of TryAcquire.
+func (mr *MockLockRepositoryMockRecorder) TryAcquire(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryAcquire", reflect.TypeOf((*MockLockRepository)(nil).TryAcquire), ctx, name)
+}
+
+// MockLock is a mock of Lock interface.
+type MockLock struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockMockRecorder
+	isgomock struct{}
+}
+
+// MockLockMockRecorder is the mock recorder for MockLock.
+type MockLockMockRecorder struct {
+	mock *MockLock
+}
+
+// NewMockLock creates a new mock instance.
+func NewMockLock(ctrl *gomock.Controller) *MockLock {
+	mock := &MockLock{ctrl: ctrl}
+	mock.recorder = &MockLockMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLock) EXPECT() *MockLockMockRecorder {
+	return m.recorder
+}
+
+// Alive mocks base method.
+func (m *MockLock) Alive(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Alive", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Alive indicates an expected call of Alive.
+func (mr *MockLockMockRecorder) Alive(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Alive", reflect.TypeOf((*MockLock)(nil).Alive), ctx)
+}
+
+// Release mocks base method.
+func (m *MockLock) Release(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockLockMockRecorder) Release(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockLock)(nil).Release), ctx)
+}
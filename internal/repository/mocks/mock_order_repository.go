@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	sqlx "github.com/jmoiron/sqlx"
@@ -43,6 +44,35 @@ func (m *MockOrderRepository) EXPECT() *MockOrderRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddStatusChangeTx mocks base method.
+func (m *MockOrderRepository) AddStatusChangeTx(ctx context.Context, tx *sqlx.Tx, change model.OrderStatusChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddStatusChangeTx", ctx, tx, change)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddStatusChangeTx indicates an expected call of AddStatusChangeTx.
+func (mr *MockOrderRepositoryMockRecorder) AddStatusChangeTx(ctx, tx, change any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddStatusChangeTx", reflect.TypeOf((*MockOrderRepository)(nil).AddStatusChangeTx), ctx, tx, change)
+}
+
+// AdjustAccrual mocks base method.
+func (m *MockOrderRepository) AdjustAccrual(ctx context.Context, orderID uuid.UUID, amount int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustAccrual", ctx, orderID, amount)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdjustAccrual indicates an expected call of AdjustAccrual.
+func (mr *MockOrderRepositoryMockRecorder) AdjustAccrual(ctx, orderID, amount any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustAccrual", reflect.TypeOf((*MockOrderRepository)(nil).AdjustAccrual), ctx, orderID, amount)
+}
+
 // BeginTx mocks base method.
 func (m *MockOrderRepository) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
 	m.ctrl.T.Helper()
@@ -88,6 +118,51 @@ func (mr *MockOrderRepositoryMockRecorder) Create(ctx, userID, number any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOrderRepository)(nil).Create), ctx, userID, number)
 }
 
+// CreateBatch mocks base method.
+func (m *MockOrderRepository) CreateBatch(ctx context.Context, userID uuid.UUID, numbers []string) ([]model.BatchOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", ctx, userID, numbers)
+	ret0, _ := ret[0].([]model.BatchOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockOrderRepositoryMockRecorder) CreateBatch(ctx, userID, numbers any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockOrderRepository)(nil).CreateBatch), ctx, userID, numbers)
+}
+
+// Expire mocks base method.
+func (m *MockOrderRepository) Expire(ctx context.Context, createdBefore time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expire", ctx, createdBefore)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Expire indicates an expected call of Expire.
+func (mr *MockOrderRepositoryMockRecorder) Expire(ctx, createdBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockOrderRepository)(nil).Expire), ctx, createdBefore)
+}
+
+// FlagStale mocks base method.
+func (m *MockOrderRepository) FlagStale(ctx context.Context, createdBefore time.Time) ([]*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlagStale", ctx, createdBefore)
+	ret0, _ := ret[0].([]*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FlagStale indicates an expected call of FlagStale.
+func (mr *MockOrderRepositoryMockRecorder) FlagStale(ctx, createdBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlagStale", reflect.TypeOf((*MockOrderRepository)(nil).FlagStale), ctx, createdBefore)
+}
+
 // GetByID mocks base method.
 func (m *MockOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Order, error) {
 	m.ctrl.T.Helper()
@@ -118,6 +193,21 @@ func (mr *MockOrderRepositoryMockRecorder) GetByNumber(ctx, number any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByNumber", reflect.TypeOf((*MockOrderRepository)(nil).GetByNumber), ctx, number)
 }
 
+// GetFlagged mocks base method.
+func (m *MockOrderRepository) GetFlagged(ctx context.Context) ([]*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFlagged", ctx)
+	ret0, _ := ret[0].([]*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFlagged indicates an expected call of GetFlagged.
+func (mr *MockOrderRepositoryMockRecorder) GetFlagged(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFlagged", reflect.TypeOf((*MockOrderRepository)(nil).GetFlagged), ctx)
+}
+
 // GetForProcessing mocks base method.
 func (m *MockOrderRepository) GetForProcessing(ctx context.Context, tx *sqlx.Tx, limit int) ([]*model.Order, error) {
 	m.ctrl.T.Helper()
@@ -133,6 +223,21 @@ func (mr *MockOrderRepositoryMockRecorder) GetForProcessing(ctx, tx, limit any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetForProcessing", reflect.TypeOf((*MockOrderRepository)(nil).GetForProcessing), ctx, tx, limit)
 }
 
+// GetStatusHistory mocks base method.
+func (m *MockOrderRepository) GetStatusHistory(ctx context.Context, orderID uuid.UUID) ([]model.OrderStatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatusHistory", ctx, orderID)
+	ret0, _ := ret[0].([]model.OrderStatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatusHistory indicates an expected call of GetStatusHistory.
+func (mr *MockOrderRepositoryMockRecorder) GetStatusHistory(ctx, orderID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatusHistory", reflect.TypeOf((*MockOrderRepository)(nil).GetStatusHistory), ctx, orderID)
+}
+
 // GetUserOrders mocks base method.
 func (m *MockOrderRepository) GetUserOrders(ctx context.Context, userID uuid.UUID) ([]*model.Order, error) {
 	m.ctrl.T.Helper()
@@ -148,6 +253,35 @@ func (mr *MockOrderRepositoryMockRecorder) GetUserOrders(ctx, userID any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrders", reflect.TypeOf((*MockOrderRepository)(nil).GetUserOrders), ctx, userID)
 }
 
+// LockUsersTx mocks base method.
+func (m *MockOrderRepository) LockUsersTx(ctx context.Context, tx *sqlx.Tx, userIDs []uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUsersTx", ctx, tx, userIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUsersTx indicates an expected call of LockUsersTx.
+func (mr *MockOrderRepositoryMockRecorder) LockUsersTx(ctx, tx, userIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUsersTx", reflect.TypeOf((*MockOrderRepository)(nil).LockUsersTx), ctx, tx, userIDs)
+}
+
+// RecordAttemptsTx mocks base method.
+func (m *MockOrderRepository) RecordAttemptsTx(ctx context.Context, tx *sqlx.Tx, orderIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAttemptsTx", ctx, tx, orderIDs)
+	ret0, _ := ret[0].(map[uuid.UUID]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordAttemptsTx indicates an expected call of RecordAttemptsTx.
+func (mr *MockOrderRepositoryMockRecorder) RecordAttemptsTx(ctx, tx, orderIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAttemptsTx", reflect.TypeOf((*MockOrderRepository)(nil).RecordAttemptsTx), ctx, tx, orderIDs)
+}
+
 // UpdateStatus mocks base method.
 func (m *MockOrderRepository) UpdateStatus(ctx context.Context, orderID uuid.UUID, status model.OrderStatus, accrual int) error {
 	m.ctrl.T.Helper()
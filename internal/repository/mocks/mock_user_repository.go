@@ -100,6 +100,22 @@ func (mr *MockUserRepositoryMockRecorder) Create(ctx, user any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserRepository)(nil).Create), ctx, user)
 }
 
+// CreditAccrualTx mocks base method.
+func (m *MockUserRepository) CreditAccrualTx(ctx context.Context, tx *sqlx.Tx, orderID, userID uuid.UUID, amount int) (int, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreditAccrualTx", ctx, tx, orderID, userID, amount)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreditAccrualTx indicates an expected call of CreditAccrualTx.
+func (mr *MockUserRepositoryMockRecorder) CreditAccrualTx(ctx, tx, orderID, userID, amount any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreditAccrualTx", reflect.TypeOf((*MockUserRepository)(nil).CreditAccrualTx), ctx, tx, orderID, userID, amount)
+}
+
 // GetBalance mocks base method.
 func (m *MockUserRepository) GetBalance(ctx context.Context, userID uuid.UUID) (int, error) {
 	m.ctrl.T.Helper()
@@ -1,15 +1,19 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/mrhyman/gophermart/internal/logger"
 	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
 )
 
 //go:generate mockgen -source=order.go -destination=mocks/mock_order_repository.go -package=mocks
@@ -17,26 +21,59 @@ import (
 type OrderRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Order, error)
 	Create(ctx context.Context, userID uuid.UUID, number string) (*model.Order, error)
+	CreateBatch(ctx context.Context, userID uuid.UUID, numbers []string) ([]model.BatchOrder, error)
 	GetByNumber(ctx context.Context, number string) (*model.Order, error)
 	GetUserOrders(ctx context.Context, userID uuid.UUID) ([]*model.Order, error)
 	CountByStatus(ctx context.Context, status model.OrderStatus) (int, error)
 	GetForProcessing(ctx context.Context, tx *sqlx.Tx, limit int) ([]*model.Order, error)
 	UpdateStatus(ctx context.Context, orderID uuid.UUID, status model.OrderStatus, accrual int) error
 	UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID, status model.OrderStatus, accrual int) error
+	RecordAttemptsTx(ctx context.Context, tx *sqlx.Tx, orderIDs []uuid.UUID) (map[uuid.UUID]int, error)
+	AddStatusChangeTx(ctx context.Context, tx *sqlx.Tx, change model.OrderStatusChange) error
+	GetStatusHistory(ctx context.Context, orderID uuid.UUID) ([]model.OrderStatusChange, error)
+	AdjustAccrual(ctx context.Context, orderID uuid.UUID, amount int) (int, error)
+	FlagStale(ctx context.Context, createdBefore time.Time) ([]*model.Order, error)
+	Expire(ctx context.Context, createdBefore time.Time) (int, error)
+	GetFlagged(ctx context.Context) ([]*model.Order, error)
 	BeginTx(ctx context.Context) (*sqlx.Tx, error)
+	LockUsersTx(ctx context.Context, tx *sqlx.Tx, userIDs []uuid.UUID) error
 }
 
+// previousStatus is an order as it was before a status update.
+type previousStatus struct {
+	ID     uuid.UUID         `db:"id"`
+	UserID uuid.UUID         `db:"user_id"`
+	Number string            `db:"number"`
+	Status model.OrderStatus `db:"status"`
+}
+
+// updateStatusQuery never moves an order out of a terminal status. It
+// returns the status the order had before.
+const updateStatusQuery = `
+	UPDATE orders o
+	SET status = $1, accrual = $2
+	FROM (SELECT id, status FROM orders WHERE id = $3 FOR UPDATE) old
+	WHERE o.id = old.id AND old.status IN ('NEW', 'PROCESSING')
+	RETURNING o.id, o.user_id, o.number, old.status
+`
+
 type OrderRepo struct {
 	*GenericRepository[model.Order]
+	// pointsTTL is how long credited points last, zero meaning forever.
+	pointsTTL time.Duration
 }
 
-func NewOrderRepository(db *sqlx.DB) *OrderRepo {
+func NewOrderRepository(db *sqlx.DB, pointsTTL time.Duration) *OrderRepo {
 	return &OrderRepo{
 		GenericRepository: NewGenericRepository[model.Order](db),
+		pointsTTL:         pointsTTL,
 	}
 }
 
-func (r *OrderRepo) Create(ctx context.Context, userID uuid.UUID, number string) (*model.Order, error) {
+func (r *OrderRepo) Create(ctx context.Context, userID uuid.UUID, number string) (_ *model.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.Create", "insert_order")
+	defer tracing.End(span, &err)
+
 	order, err := model.NewOrder(uuid.New(), userID, number, model.OrderStatusNew, 0, time.Now())
 
 	if err != nil {
@@ -49,14 +86,21 @@ func (r *OrderRepo) Create(ctx context.Context, userID uuid.UUID, number string)
 		RETURNING created_at
 	`
 
-	err = r.db.QueryRowContext(
-		ctx,
-		query,
-		order.ID,
-		order.UserID,
-		order.Number,
-		order.Status,
-	).Scan(&order.CreatedAt)
+	err = r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			order.ID,
+			order.UserID,
+			order.Number,
+			order.Status,
+		).Scan(&order.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		return appendOrderUploaded(ctx, tx, order.UserID, order.ID, order.Number, order.Status, model.OrderSourceAPI)
+	})
 
 	if err != nil {
 		return nil, r.convertPgError(ctx, "order", number, err)
@@ -65,15 +109,83 @@ func (r *OrderRepo) Create(ctx context.Context, userID uuid.UUID, number string)
 	return order, nil
 }
 
-func (r *OrderRepo) GetByNumber(ctx context.Context, number string) (*model.Order, error) {
+// createBatchQuery inserts all numbers at once, skipping those already
+// uploaded, and returns the inserted rows together with the existing ones.
+// The outer select reads the snapshot taken before the insert, so inserted
+// rows are not reported twice. An OrderUploaded event is appended for each
+// inserted row, with seqs reserved in one update like appendEventQuery does.
+const createBatchQuery = `
+	WITH input AS (
+		SELECT * FROM unnest($2::uuid[], $3::text[]) WITH ORDINALITY AS t(id, number, ord)
+	), inserted AS (
+		INSERT INTO orders (id, user_id, number, status, created_at)
+		SELECT id, $1::uuid, number, 'NEW'::order_status, NOW() FROM input
+		ON CONFLICT (number) DO NOTHING
+		RETURNING id, user_id, number, status, accrual, created_at
+	), seq AS (
+		UPDATE users SET event_seq = event_seq + (SELECT COUNT(*) FROM inserted)
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM inserted)
+		RETURNING event_seq - (SELECT COUNT(*) FROM inserted) AS base
+	), events AS (
+		INSERT INTO events (user_id, seq, type, payload)
+		SELECT $1, seq.base + ROW_NUMBER() OVER (ORDER BY i.ord), $4,
+			jsonb_build_object('order_id', ins.id, 'number', ins.number, 'status', ins.status, 'source', $5::text)
+		FROM inserted ins
+		JOIN input i ON i.id = ins.id
+		CROSS JOIN seq
+	)
+	SELECT id, user_id, number, status, accrual, created_at, TRUE AS created
+	FROM inserted
+	UNION ALL
+	SELECT o.id, o.user_id, o.number, o.status, o.accrual, o.created_at, FALSE AS created
+	FROM orders o
+	JOIN input i ON i.number = o.number
+`
+
+// CreateBatch inserts orders for all numbers in a single round trip. Numbers
+// must be unique. A number inserted concurrently by another transaction may
+// be missing from the result.
+func (r *OrderRepo) CreateBatch(ctx context.Context, userID uuid.UUID, numbers []string) (_ []model.BatchOrder, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.CreateBatch", "insert_orders_batch")
+	defer tracing.End(span, &err)
+
+	ids := make([]string, len(numbers))
+	for i := range numbers {
+		ids[i] = uuid.NewString()
+	}
+
+	var orders []model.BatchOrder
+	err = r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(
+			ctx,
+			&orders,
+			createBatchQuery,
+			userID,
+			pq.Array(ids),
+			pq.Array(numbers),
+			model.OrderUploaded,
+			model.OrderSourceAPI,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (r *OrderRepo) GetByNumber(ctx context.Context, number string) (_ *model.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.GetByNumber", "select_order_by_number")
+	defer tracing.End(span, &err)
+
 	query := `
-		SELECT id, user_id, number, status, created_at 
+		SELECT id, user_id, number, status, accrual, accrual_attempts, created_at 
 		FROM orders 
 		WHERE number = $1
 	`
 
 	var order model.Order
-	err := r.db.GetContext(ctx, &order, query, number)
+	err = r.db.GetContext(ctx, &order, query, number)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, model.ErrNotFound
@@ -84,7 +196,10 @@ func (r *OrderRepo) GetByNumber(ctx context.Context, number string) (*model.Orde
 	return &order, nil
 }
 
-func (r *OrderRepo) GetUserOrders(ctx context.Context, userID uuid.UUID) ([]*model.Order, error) {
+func (r *OrderRepo) GetUserOrders(ctx context.Context, userID uuid.UUID) (_ []*model.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.GetUserOrders", "select_orders_by_user")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT id, user_id, number, status, accrual, created_at 
 		FROM orders 
@@ -93,7 +208,7 @@ func (r *OrderRepo) GetUserOrders(ctx context.Context, userID uuid.UUID) ([]*mod
 	`
 
 	var orders []*model.Order
-	err := r.db.SelectContext(ctx, &orders, query, userID)
+	err = r.db.SelectContext(ctx, &orders, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -101,11 +216,14 @@ func (r *OrderRepo) GetUserOrders(ctx context.Context, userID uuid.UUID) ([]*mod
 	return orders, nil
 }
 
-func (r *OrderRepo) CountByStatus(ctx context.Context, status model.OrderStatus) (int, error) {
+func (r *OrderRepo) CountByStatus(ctx context.Context, status model.OrderStatus) (_ int, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.CountByStatus", "count_orders_by_status")
+	defer tracing.End(span, &err)
+
 	query := `SELECT COUNT(*) FROM orders WHERE status = $1`
 
 	var count int
-	err := r.db.GetContext(ctx, &count, query, status)
+	err = r.db.GetContext(ctx, &count, query, status)
 	return count, err
 }
 
@@ -113,9 +231,12 @@ func (r *OrderRepo) GetForProcessing(
 	ctx context.Context,
 	tx *sqlx.Tx,
 	limit int,
-) ([]*model.Order, error) {
+) (_ []*model.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.GetForProcessing", "select_orders_for_processing")
+	defer tracing.End(span, &err)
+
 	query := `
-		SELECT id, user_id, number, status, accrual, created_at
+		SELECT id, user_id, number, status, accrual, accrual_attempts, created_at
 		FROM orders 
 		WHERE status IN ('NEW', 'PROCESSING')
 		ORDER BY created_at
@@ -124,7 +245,7 @@ func (r *OrderRepo) GetForProcessing(
 	`
 
 	var orders []*model.Order
-	err := tx.SelectContext(ctx, &orders, query, limit)
+	err = tx.SelectContext(ctx, &orders, query, limit)
 
 	return orders, err
 }
@@ -135,32 +256,128 @@ func (r *OrderRepo) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
 	})
 }
 
+// LockUsersTx locks the given users in id order. A batch touching orders of
+// several users calls it before changing any of them.
+func (r *OrderRepo) LockUsersTx(ctx context.Context, tx *sqlx.Tx, userIDs []uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.LockUsersTx", "lock_users")
+	defer tracing.End(span, &err)
+
+	return lockUsersTx(ctx, tx, userIDs)
+}
+
 func (r *OrderRepo) UpdateStatusTx(
 	ctx context.Context,
 	tx *sqlx.Tx,
 	orderID uuid.UUID,
 	status model.OrderStatus,
 	accrual int,
-) error {
+) (err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.UpdateStatusTx", "update_order_status")
+	defer tracing.End(span, &err)
+
 	log := logger.FromContext(ctx)
 	log.With("orderID", orderID, "status", status, "accrual", accrual).Debug("updating order")
 
-	query := `
-		UPDATE orders 
-		SET status = $1, accrual = $2 
-		WHERE id = $3
-	`
-
-	result, err := tx.ExecContext(ctx, query, status, accrual, orderID)
+	var old previousStatus
+	err = tx.GetContext(ctx, &old, updateStatusQuery, status, accrual, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrInvalidStatusTransition
+	}
 	if err != nil {
 		log.With("err", err.Error()).Error("update failed")
 		return err
 	}
 
-	rows, _ := result.RowsAffected()
-	log.With("rows_affected", rows).Debug("update completed")
+	log.With("old_status", old.Status).Debug("update completed")
 
-	return nil
+	if old.Status != status {
+		err := appendEvent(ctx, tx, old.UserID, model.OrderStatusChanged, model.OrderStatusChangedData{
+			OrderID: orderID,
+			Number:  old.Number,
+			From:    old.Status,
+			To:      status,
+			Accrual: points(accrual),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Webhook subscribers learn about the final status in the same
+	// transaction, so an event is never lost or sent for a rolled back update.
+	return enqueueOrderWebhook(ctx, tx, orderID, status)
+}
+
+// RecordAttemptsTx counts one more accrual system lookup for each order and
+// returns the updated counts.
+func (r *OrderRepo) RecordAttemptsTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	orderIDs []uuid.UUID,
+) (_ map[uuid.UUID]int, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.RecordAttemptsTx", "update_order_attempts")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE orders
+		SET accrual_attempts = accrual_attempts + 1
+		WHERE id = ANY($1)
+		RETURNING id, accrual_attempts
+	`
+
+	var rows []struct {
+		ID       uuid.UUID `db:"id"`
+		Attempts int       `db:"accrual_attempts"`
+	}
+	if err := tx.SelectContext(ctx, &rows, query, pq.Array(orderIDs)); err != nil {
+		return nil, err
+	}
+
+	attempts := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		attempts[row.ID] = row.Attempts
+	}
+
+	return attempts, nil
+}
+
+func (r *OrderRepo) AddStatusChangeTx(ctx context.Context, tx *sqlx.Tx, change model.OrderStatusChange) (err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.AddStatusChangeTx", "insert_order_status_history")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO order_status_history (order_id, old_status, new_status, accrual, attempt)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		change.OrderID,
+		change.OldStatus,
+		change.NewStatus,
+		change.Accrual,
+		change.Attempt,
+	)
+	return err
+}
+
+// GetStatusHistory returns the status transitions of an order, oldest first.
+func (r *OrderRepo) GetStatusHistory(ctx context.Context, orderID uuid.UUID) (_ []model.OrderStatusChange, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.GetStatusHistory", "select_order_status_history")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT order_id, old_status, new_status, accrual, attempt, created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`
+
+	var history []model.OrderStatusChange
+	err = r.db.SelectContext(ctx, &history, query, orderID)
+
+	return history, err
 }
 
 func (r *OrderRepo) UpdateStatus(
@@ -169,12 +386,197 @@ func (r *OrderRepo) UpdateStatus(
 	status model.OrderStatus,
 	accrual int,
 ) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return r.UpdateStatusTx(ctx, tx, orderID, status, accrual)
+	})
+}
+
+// AdjustAccrual reconciles an already credited order with a new accrual
+// amount reported by the accrual system. The difference is applied to the
+// user balance and recorded in accrual_adjustments. It returns the applied delta.
+func (r *OrderRepo) AdjustAccrual(ctx context.Context, orderID uuid.UUID, amount int) (_ int, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.AdjustAccrual", "adjust_order_accrual")
+	defer tracing.End(span, &err)
+
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var credit struct {
+		UserID uuid.UUID `db:"user_id"`
+		Amount int       `db:"amount"`
+	}
+	selectQuery := `SELECT user_id, amount FROM accrual_credits WHERE order_id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &credit, selectQuery, orderID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, model.ErrAccrualNotCredited
+		}
+		return 0, err
+	}
+
+	delta := amount - credit.Amount
+	if delta == 0 {
+		return 0, nil
+	}
+
+	creditQuery := `UPDATE accrual_credits SET amount = $1, updated_at = NOW() WHERE order_id = $2`
+	if _, err := tx.ExecContext(ctx, creditQuery, amount, orderID); err != nil {
+		return 0, err
+	}
+
+	adjustmentQuery := `
+		INSERT INTO accrual_adjustments (order_id, old_amount, new_amount)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.ExecContext(ctx, adjustmentQuery, orderID, credit.Amount, amount); err != nil {
+		return 0, err
+	}
+
+	balanceQuery := `UPDATE users SET balance = balance + $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, balanceQuery, delta, credit.UserID); err != nil {
+		return 0, err
+	}
+
+	// A raise is a new lot, a cut is taken from the oldest lots like a withdrawal.
+	if delta > 0 {
+		err = addLotTx(ctx, tx, credit.UserID, uuid.NullUUID{UUID: orderID, Valid: true}, delta, r.pointsTTL, sql.NullTime{})
+	} else {
+		err = consumeLotsTx(ctx, tx, credit.UserID, -delta)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	orderQuery := `UPDATE orders SET accrual = $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, orderQuery, amount, orderID); err != nil {
+		return 0, err
+	}
+
+	err = appendEvent(ctx, tx, credit.UserID, model.PointsCredited, model.PointsCreditedData{
+		OrderID: orderID,
+		Amount:  points(delta),
+		Reason:  model.CreditReasonAdjustment,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := notifyBalance(ctx, tx, credit.UserID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return delta, nil
+}
+
+// FlagStale marks unfinished orders created before createdBefore as stale
+// and returns the ones flagged by this call. Orders flagged before are left
+// as they are and not returned again.
+func (r *OrderRepo) FlagStale(ctx context.Context, createdBefore time.Time) (_ []*model.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.FlagStale", "flag_stale_orders")
+	defer tracing.End(span, &err)
+
 	query := `
-		UPDATE orders 
-		SET status = $1, accrual = $2 
-		WHERE id = $3
+		UPDATE orders
+		SET flagged_at = NOW()
+		WHERE status IN ('NEW', 'PROCESSING') AND created_at < $1 AND flagged_at IS NULL
+		RETURNING id, user_id, number, status, accrual, created_at, flagged_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query, status, accrual, orderID)
-	return err
+	var orders []*model.Order
+	err = r.db.SelectContext(ctx, &orders, query, createdBefore)
+
+	return orders, err
+}
+
+// Expire moves every unfinished order created before createdBefore to the
+// terminal EXPIRED status, flagging it too if it was not yet. Orders flagged
+// by earlier sweeps are included, so none is left behind when a sweep fails
+// between flagging and expiring.
+func (r *OrderRepo) Expire(ctx context.Context, createdBefore time.Time) (_ int, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.Expire", "expire_orders")
+	defer tracing.End(span, &err)
+
+	// Orders are locked in user order, as appending events locks the users.
+	query := `
+		UPDATE orders o
+		SET status = 'EXPIRED', flagged_at = COALESCE(o.flagged_at, NOW())
+		FROM (
+			SELECT id, status FROM orders
+			WHERE status IN ('NEW', 'PROCESSING') AND created_at < $1
+			ORDER BY user_id, id
+			FOR UPDATE
+		) old
+		WHERE o.id = old.id
+		RETURNING o.id, o.user_id, o.number, old.status
+	`
+
+	var expired []previousStatus
+	err = r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.SelectContext(ctx, &expired, query, createdBefore); err != nil {
+			return err
+		}
+
+		slices.SortFunc(expired, func(a, b previousStatus) int {
+			return bytes.Compare(a.UserID[:], b.UserID[:])
+		})
+
+		for _, o := range expired {
+			err := appendEvent(ctx, tx, o.UserID, model.OrderStatusChanged, model.OrderStatusChangedData{
+				OrderID: o.ID,
+				Number:  o.Number,
+				From:    o.Status,
+				To:      model.OrderStatusExpired,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(expired), nil
+}
+
+func (r *OrderRepo) GetFlagged(ctx context.Context) (_ []*model.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.GetFlagged", "select_flagged_orders")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT id, user_id, number, status, accrual, created_at, flagged_at
+		FROM orders
+		WHERE flagged_at IS NOT NULL AND status IN ('NEW', 'PROCESSING', 'EXPIRED')
+		ORDER BY created_at
+	`
+
+	var orders []*model.Order
+	err = r.db.SelectContext(ctx, &orders, query)
+
+	return orders, err
+}
+
+func appendOrderUploaded(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	userID uuid.UUID,
+	orderID uuid.UUID,
+	number string,
+	status model.OrderStatus,
+	source string,
+) error {
+	return appendEvent(ctx, tx, userID, model.OrderUploaded, model.OrderUploadedData{
+		OrderID: orderID,
+		Number:  number,
+		Status:  status,
+		Source:  source,
+	})
 }
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
+	"github.com/mrhyman/gophermart/internal/util"
+)
+
+//go:generate mockgen -source=outbox.go -destination=mocks/mock_outbox_repository.go -package=mocks
+
+// PublishFunc hands a batch of events to a sink. The events count as
+// published only if it returns nil.
+type PublishFunc func(ctx context.Context, events []model.DomainEvent) error
+
+type OutboxRepository interface {
+	PublishPending(ctx context.Context, limit int, publish PublishFunc) (int, error)
+}
+
+// appendEventQuery takes the next seq of the user. The update locks the
+// user row until the transaction ends, so a later event of the same user
+// can neither get a lower seq nor become visible first. Transactions with
+// events of several users lock them with lockUsersTx first.
+const appendEventQuery = `
+	WITH next AS (
+		UPDATE users SET event_seq = event_seq + 1
+		WHERE id = $1
+		RETURNING event_seq
+	)
+	INSERT INTO events (user_id, seq, type, payload)
+	SELECT $1, event_seq, $2, $3 FROM next
+`
+
+// relayLockKey serializes relays across replicas; publishing in id order
+// from one relay at a time keeps the events of a user in order.
+var relayLockKey = lockKey("events-relay")
+
+type OutboxRepo struct {
+	db *sqlx.DB
+}
+
+func NewOutboxRepository(db *sqlx.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// PublishPending passes the oldest unpublished events to publish and marks
+// them published. It returns how many were published, zero also when another
+// replica is relaying. If marking fails after publish succeeded, the events
+// are published again next time.
+func (r *OutboxRepo) PublishPending(ctx context.Context, limit int, publish PublishFunc) (_ int, err error) {
+	ctx, span := startSpan(ctx, "OutboxRepo.PublishPending", "select_unpublished_events")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.GetContext(ctx, &locked, `SELECT pg_try_advisory_xact_lock($1)`, relayLockKey); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	query := `
+		SELECT id, user_id, seq, type, payload, created_at
+		FROM events
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`
+
+	var events []model.DomainEvent
+	if err := tx.SelectContext(ctx, &events, query, limit); err != nil {
+		return 0, err
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, events); err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE events SET published_at = NOW() WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(events), nil
+}
+
+// appendEvent writes a domain event to the outbox in the transaction that
+// made the change.
+func appendEvent(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	userID uuid.UUID,
+	eventType model.DomainEventType,
+	data any,
+) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, appendEventQuery, userID, eventType, payload)
+	return err
+}
+
+// points converts hundredths, as stored, to points as shown in the API.
+func points(amount int) float64 {
+	return util.RoundToTwoDecimals(float64(amount) / 100)
+}
@@ -1,11 +1,16 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jmoiron/sqlx"
 )
@@ -15,15 +20,22 @@ type Repos struct {
 	User    *UserRepo
 	Order   *OrderRepo
 	Balance *BalanceRepo
+	Lock    *LockRepo
+	Import  *ImportRepo
+	Event   *EventRepo
+	Webhook *WebhookRepo
+	Outbox  *OutboxRepo
 }
 
-func NewRepos(dsn string) (*Repos, error) {
+// NewRepos connects to the database. Points credited from now on expire after
+// pointsTTL, or never when it is zero.
+func NewRepos(dsn string, maxOpenConns int, pointsTTL time.Duration) (*Repos, error) {
 	db, err := sqlx.Open("postgres", dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	db.SetMaxOpenConns(10)
+	db.SetMaxOpenConns(maxOpenConns)
 	db.SetConnMaxLifetime(time.Hour)
 
 	if err := db.Ping(); err != nil {
@@ -32,16 +44,69 @@ func NewRepos(dsn string) (*Repos, error) {
 
 	return &Repos{
 		db:      db,
-		User:    NewUserRepository(db),
-		Order:   NewOrderRepository(db),
-		Balance: NewBalanceRepository(db),
+		User:    NewUserRepository(db, pointsTTL),
+		Order:   NewOrderRepository(db, pointsTTL),
+		Balance: NewBalanceRepository(db, pointsTTL),
+		Lock:    NewLockRepository(db),
+		Import:  NewImportRepository(db, pointsTTL),
+		Event:   NewEventRepository(db),
+		Webhook: NewWebhookRepository(db),
+		Outbox:  NewOutboxRepository(db),
 	}, nil
 }
 
+// DB exposes the underlying pool, e.g. for connection statistics.
+func (r *Repos) DB() *sql.DB {
+	return r.db.DB
+}
+
 func (r *Repos) Ping() error {
 	return r.db.Ping()
 }
 
+func (r *Repos) PingContext(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// MigrationVersion returns the schema version recorded by golang-migrate and
+// whether the last migration failed half way.
+func (r *Repos) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var row struct {
+		Version uint `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+
+	err := r.db.GetContext(ctx, &row, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return row.Version, row.Dirty, nil
+}
+
+// LatestMigration returns the highest migration version in migrationsDir,
+// i.e. the version this build expects the database to be at.
+func LatestMigration(migrationsDir string) (uint, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		m, err := source.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+		latest = max(latest, m.Version)
+	}
+
+	return latest, nil
+}
+
 func (r *Repos) Close() error {
 	return r.db.Close()
 }
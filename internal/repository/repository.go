@@ -12,6 +12,9 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type TxFunc func(tx *sqlx.Tx) error
@@ -28,40 +31,69 @@ func NewGenericRepository[T Entity](db *sqlx.DB) *GenericRepository[T] {
 	return &GenericRepository[T]{db: db}
 }
 
-func (r *GenericRepository[T]) GetByID(ctx context.Context, id uuid.UUID) (*T, error) {
+// startSpan opens a span for a repository call labelled with the name of the
+// SQL statement it runs. Calls made outside of a trace are not traced, so
+// background polling does not start a new trace for every query.
+func startSpan(ctx context.Context, name, statement string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return tracing.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBQuerySummary(statement),
+		),
+	)
+}
+
+func (r *GenericRepository[T]) GetByID(ctx context.Context, id uuid.UUID) (_ *T, err error) {
 	var entity T
+	ctx, span := startSpan(ctx, "Repository.GetByID", "select_"+entity.TableName()+"_by_id")
+	defer tracing.End(span, &err)
+
 	query := fmt.Sprintf(`SELECT * FROM %s WHERE id = $1`, entity.TableName())
 
-	err := r.db.GetContext(ctx, &entity, query, id)
+	err = r.db.GetContext(ctx, &entity, query, id)
 	if err != nil {
 		return nil, err
 	}
 	return &entity, nil
 }
 
-func (r *GenericRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+func (r *GenericRepository[T]) GetAll(ctx context.Context) (_ []T, err error) {
 	var entity T
 	var entities []T
+	ctx, span := startSpan(ctx, "Repository.GetAll", "select_"+entity.TableName())
+	defer tracing.End(span, &err)
+
 	query := fmt.Sprintf(`SELECT * FROM %s`, entity.TableName())
 
-	err := r.db.SelectContext(ctx, &entities, query)
+	err = r.db.SelectContext(ctx, &entities, query)
 	return entities, err
 }
 
-func (r *GenericRepository[T]) Delete(ctx context.Context, id uuid.UUID) error {
+func (r *GenericRepository[T]) Delete(ctx context.Context, id uuid.UUID) (err error) {
 	var entity T
+	ctx, span := startSpan(ctx, "Repository.Delete", "delete_"+entity.TableName()+"_by_id")
+	defer tracing.End(span, &err)
+
 	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, entity.TableName())
 
-	_, err := r.db.ExecContext(ctx, query, id)
+	_, err = r.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (r *GenericRepository[T]) Count(ctx context.Context) (int, error) {
+func (r *GenericRepository[T]) Count(ctx context.Context) (_ int, err error) {
 	var entity T
 	var count int
+	ctx, span := startSpan(ctx, "Repository.Count", "count_"+entity.TableName())
+	defer tracing.End(span, &err)
+
 	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s`, entity.TableName())
 
-	err := r.db.GetContext(ctx, &count, query)
+	err = r.db.GetContext(ctx, &count, query)
 	return count, err
 }
 
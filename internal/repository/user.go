@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
 )
 
 //go:generate mockgen -source=user.go -destination=mocks/mock_user_repository.go -package=mocks
@@ -16,30 +20,48 @@ type UserRepository interface {
 	GetByLogin(ctx context.Context, login string) (*model.User, error)
 	AddBalance(ctx context.Context, userID uuid.UUID, amount int) error
 	AddBalanceTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, amount int) error
+	CreditAccrualTx(ctx context.Context, tx *sqlx.Tx, orderID, userID uuid.UUID, amount int) (int, bool, error)
 	GetBalance(ctx context.Context, userID uuid.UUID) (int, error)
 	BeginTx(ctx context.Context) (*sqlx.Tx, error)
 }
 
 type UserRepo struct {
 	*GenericRepository[model.User]
+	// pointsTTL is how long credited points last, zero meaning forever.
+	pointsTTL time.Duration
 }
 
-func NewUserRepository(db *sqlx.DB) *UserRepo {
+func NewUserRepository(db *sqlx.DB, pointsTTL time.Duration) *UserRepo {
 	return &UserRepo{
 		GenericRepository: NewGenericRepository[model.User](db),
+		pointsTTL:         pointsTTL,
 	}
 }
 
-func (r *UserRepo) Create(ctx context.Context, user model.User) error {
+func (r *UserRepo) Create(ctx context.Context, user model.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.Create", "insert_user")
+	defer tracing.End(span, &err)
+
 	query := `INSERT INTO users (id, login, password) VALUES ($1, $2, $3)`
-	if _, err := r.DB().ExecContext(ctx, query, user.ID, user.Login, user.Password); err != nil {
+
+	err = r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, query, user.ID, user.Login, user.Password); err != nil {
+			return err
+		}
+
+		return appendEvent(ctx, tx, user.ID, model.UserRegistered, model.UserRegisteredData{Login: user.Login})
+	})
+	if err != nil {
 		return r.convertPgError(ctx, "user", user.Login, err)
 	}
 
 	return nil
 }
 
-func (r *UserRepo) GetByLogin(ctx context.Context, login string) (*model.User, error) {
+func (r *UserRepo) GetByLogin(ctx context.Context, login string) (_ *model.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetByLogin", "select_user_by_login")
+	defer tracing.End(span, &err)
+
 	query := `SELECT id, login, password FROM users WHERE login = $1`
 
 	var user model.User
@@ -50,25 +72,86 @@ func (r *UserRepo) GetByLogin(ctx context.Context, login string) (*model.User, e
 	return &user, nil
 }
 
-func (r *UserRepo) AddBalance(ctx context.Context, userID uuid.UUID, amount int) error {
+func (r *UserRepo) AddBalance(ctx context.Context, userID uuid.UUID, amount int) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.AddBalance", "update_user_balance")
+	defer tracing.End(span, &err)
+
 	query := `UPDATE users SET balance = balance + $1 WHERE id = $2`
 
-	_, err := r.db.ExecContext(ctx, query, amount, userID)
+	_, err = r.db.ExecContext(ctx, query, amount, userID)
 	return err
 }
 
-func (r *UserRepo) AddBalanceTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, amount int) error {
+func (r *UserRepo) AddBalanceTx(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, amount int) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.AddBalanceTx", "update_user_balance")
+	defer tracing.End(span, &err)
+
 	query := `UPDATE users SET balance = balance + $1 WHERE id = $2`
 
-	_, err := tx.ExecContext(ctx, query, amount, userID)
+	_, err = tx.ExecContext(ctx, query, amount, userID)
 	return err
 }
 
-func (r *UserRepo) GetBalance(ctx context.Context, userID uuid.UUID) (int, error) {
+// CreditAccrualTx credits the order accrual to the user exactly once.
+// It returns the amount on record for the order: the freshly credited one,
+// or the previously credited one if the order has already been credited.
+// fresh tells whether this call credited it.
+func (r *UserRepo) CreditAccrualTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	orderID uuid.UUID,
+	userID uuid.UUID,
+	amount int,
+) (_ int, fresh bool, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.CreditAccrualTx", "insert_accrual_credit")
+	defer tracing.End(span, &err)
+
+	insertQuery := `
+		INSERT INTO accrual_credits (order_id, user_id, amount)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (order_id) DO NOTHING
+		RETURNING amount
+	`
+
+	var credited int
+	err = tx.QueryRowContext(ctx, insertQuery, orderID, userID, amount).Scan(&credited)
+	if errors.Is(err, sql.ErrNoRows) {
+		selectQuery := `SELECT amount FROM accrual_credits WHERE order_id = $1`
+		err = tx.GetContext(ctx, &credited, selectQuery, orderID)
+		return credited, false, err
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := r.AddBalanceTx(ctx, tx, userID, credited); err != nil {
+		return 0, false, err
+	}
+
+	if err := addLotTx(ctx, tx, userID, uuid.NullUUID{UUID: orderID, Valid: true}, credited, r.pointsTTL, sql.NullTime{}); err != nil {
+		return 0, false, err
+	}
+
+	err = appendEvent(ctx, tx, userID, model.PointsCredited, model.PointsCreditedData{
+		OrderID: orderID,
+		Amount:  points(credited),
+		Reason:  model.CreditReasonAccrual,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return credited, true, nil
+}
+
+func (r *UserRepo) GetBalance(ctx context.Context, userID uuid.UUID) (_ int, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetBalance", "select_user_balance")
+	defer tracing.End(span, &err)
+
 	query := `SELECT balance FROM users WHERE id = $1`
 
 	var balance int
-	err := r.db.GetContext(ctx, &balance, query, userID)
+	err = r.db.GetContext(ctx, &balance, query, userID)
 	if err != nil {
 		return 0, err
 	}
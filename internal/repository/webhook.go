@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
+)
+
+//go:generate mockgen -source=webhook.go -destination=mocks/mock_webhook_repository.go -package=mocks
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error
+	ListSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	GetDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]model.WebhookDelivery, error)
+	Replay(ctx context.Context, deliveryID uuid.UUID) (*model.WebhookDelivery, error)
+	ClaimDueTx(ctx context.Context, tx *sqlx.Tx, limit int) ([]model.WebhookJob, error)
+	RecordAttemptTx(ctx context.Context, tx *sqlx.Tx, delivery *model.WebhookDelivery, attempt model.WebhookAttempt) error
+	BeginTx(ctx context.Context) (*sqlx.Tx, error)
+}
+
+// The enqueue queries write an event to the outbox and a pending delivery for
+// every subscription that wants it. Nothing is written without subscribers.
+const (
+	enqueueOrderWebhookQuery = `
+		WITH event AS (
+			INSERT INTO webhook_events (id, type, payload)
+			SELECT $1, $2, json_build_object(
+				'id', $1::uuid,
+				'type', $2::text,
+				'created_at', NOW(),
+				'data', json_build_object(
+					'user_id', u.id,
+					'login', u.login,
+					'number', o.number,
+					'status', o.status,
+					'accrual', round(COALESCE(o.accrual, 0) / 100.0, 2)
+				)
+			)
+			FROM orders o
+			JOIN users u ON u.id = o.user_id
+			WHERE o.id = $3
+			  AND EXISTS (SELECT 1 FROM webhook_subscriptions WHERE $2 = ANY(event_types))
+			RETURNING id, type
+		)
+		INSERT INTO webhook_deliveries (event_id, subscription_id)
+		SELECT event.id, s.id
+		FROM event
+		JOIN webhook_subscriptions s ON event.type = ANY(s.event_types)
+	`
+
+	enqueueWithdrawalWebhookQuery = `
+		WITH event AS (
+			INSERT INTO webhook_events (id, type, payload)
+			SELECT $1, $2, json_build_object(
+				'id', $1::uuid,
+				'type', $2::text,
+				'created_at', NOW(),
+				'data', json_build_object(
+					'user_id', u.id,
+					'login', u.login,
+					'order', $4::text,
+					'sum', round($5::numeric / 100, 2),
+					'balance', round(u.balance / 100.0, 2)
+				)
+			)
+			FROM users u
+			WHERE u.id = $3
+			  AND EXISTS (SELECT 1 FROM webhook_subscriptions WHERE $2 = ANY(event_types))
+			RETURNING id, type
+		)
+		INSERT INTO webhook_deliveries (event_id, subscription_id)
+		SELECT event.id, s.id
+		FROM event
+		JOIN webhook_subscriptions s ON event.type = ANY(s.event_types)
+	`
+)
+
+type WebhookRepo struct {
+	db *sqlx.DB
+}
+
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.CreateSubscription", "insert_webhook_subscription")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO webhook_subscriptions (id, url, secret, event_types)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		sub.ID,
+		sub.URL,
+		sub.Secret,
+		pq.Array(eventTypeStrings(sub.EventTypes)),
+	).Scan(&sub.CreatedAt)
+}
+
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context) (_ []model.WebhookSubscription, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.ListSubscriptions", "select_webhook_subscriptions")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.WebhookSubscription
+	for rows.Next() {
+		var (
+			sub   model.WebhookSubscription
+			types pq.StringArray
+		)
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &types, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		for _, t := range types {
+			sub.EventTypes = append(sub.EventTypes, model.WebhookEventType(t))
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+// DeleteSubscription removes the subscription together with its deliveries.
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.DeleteSubscription", "delete_webhook_subscription")
+	defer tracing.End(span, &err)
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the latest deliveries of a subscription, newest
+// first, each with its attempts log.
+func (r *WebhookRepo) GetDeliveries(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	limit int,
+) (_ []model.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.GetDeliveries", "select_webhook_deliveries")
+	defer tracing.End(span, &err)
+
+	var exists bool
+	err = r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)`, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, model.ErrNotFound
+	}
+
+	query := `
+		SELECT d.id, d.event_id, d.subscription_id, e.type AS event_type, d.status,
+		       d.attempts, d.next_attempt_at, d.delivered_at, d.created_at
+		FROM webhook_deliveries d
+		JOIN webhook_events e ON e.id = d.event_id
+		WHERE d.subscription_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2
+	`
+
+	var deliveries []model.WebhookDelivery
+	if err := r.db.SelectContext(ctx, &deliveries, query, subscriptionID, limit); err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]uuid.UUID, len(deliveries))
+	index := make(map[uuid.UUID]int, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.ID
+		index[d.ID] = i
+	}
+
+	var attempts []model.WebhookAttempt
+	query = `
+		SELECT delivery_id, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY created_at, attempt
+	`
+	if err := r.db.SelectContext(ctx, &attempts, query, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+
+	for _, a := range attempts {
+		i := index[a.DeliveryID]
+		deliveries[i].Log = append(deliveries[i].Log, a)
+	}
+
+	return deliveries, nil
+}
+
+// Replay queues the event of a delivery once more for the same subscription.
+// The original delivery and its log are kept.
+func (r *WebhookRepo) Replay(ctx context.Context, deliveryID uuid.UUID) (_ *model.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.Replay", "insert_webhook_delivery")
+	defer tracing.End(span, &err)
+
+	query := `
+		WITH d AS (
+			INSERT INTO webhook_deliveries (event_id, subscription_id)
+			SELECT event_id, subscription_id
+			FROM webhook_deliveries
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT d.id, d.event_id, d.subscription_id, e.type AS event_type, d.status,
+		       d.attempts, d.next_attempt_at, d.delivered_at, d.created_at
+		FROM d
+		JOIN webhook_events e ON e.id = d.event_id
+	`
+
+	var d model.WebhookDelivery
+	err = r.db.GetContext(ctx, &d, query, deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// ClaimDueTx locks pending deliveries whose next attempt is due. Deliveries
+// claimed by other replicas are skipped.
+func (r *WebhookRepo) ClaimDueTx(ctx context.Context, tx *sqlx.Tx, limit int) (_ []model.WebhookJob, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.ClaimDueTx", "select_due_webhook_deliveries")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT d.id, d.event_id, e.type AS event_type, d.attempts, e.payload, s.url, s.secret
+		FROM webhook_deliveries d
+		JOIN webhook_events e ON e.id = d.event_id
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+		ORDER BY d.next_attempt_at
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED
+	`
+
+	var jobs []model.WebhookJob
+	if err := tx.SelectContext(ctx, &jobs, query, limit); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// RecordAttemptTx logs an attempt and stores the resulting state of the
+// delivery.
+func (r *WebhookRepo) RecordAttemptTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	delivery *model.WebhookDelivery,
+	attempt model.WebhookAttempt,
+) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.RecordAttemptTx", "update_webhook_delivery")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.ExecContext(ctx, query, attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, delivered_at = $4
+		WHERE id = $5
+	`
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.DeliveredAt,
+		delivery.ID,
+	)
+	return err
+}
+
+func (r *WebhookRepo) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+}
+
+// enqueueOrderWebhook writes the webhook event of an order that reached a
+// terminal status.
+func enqueueOrderWebhook(ctx context.Context, tx sqlx.ExecerContext, orderID uuid.UUID, status model.OrderStatus) error {
+	var eventType model.WebhookEventType
+	switch status {
+	case model.OrderStatusProcessed:
+		eventType = model.WebhookOrderProcessed
+	case model.OrderStatusInvalid:
+		eventType = model.WebhookOrderInvalid
+	default:
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, enqueueOrderWebhookQuery, uuid.New(), eventType, orderID)
+	return err
+}
+
+func enqueueWithdrawalWebhook(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	userID uuid.UUID,
+	orderNumber string,
+	sum int,
+) error {
+	_, err := tx.ExecContext(
+		ctx,
+		enqueueWithdrawalWebhookQuery,
+		uuid.New(),
+		model.WebhookWithdrawalCreated,
+		userID,
+		orderNumber,
+		sum,
+	)
+	return err
+}
+
+func eventTypeStrings(types []model.WebhookEventType) []string {
+	s := make([]string, len(types))
+	for i, t := range types {
+		s[i] = string(t)
+	}
+	return s
+}
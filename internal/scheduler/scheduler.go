@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/repository"
+	"golang.org/x/sync/errgroup"
+)
+
+type JobFunc func(ctx context.Context) error
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      JobFunc
+}
+
+// Scheduler runs named periodic jobs as cluster-wide singletons: each job
+// runs only on the instance holding its advisory lock. Other instances keep
+// trying to take the lock and pick the job up when the leader goes away.
+type Scheduler struct {
+	locker           repository.LockRepository
+	electionInterval time.Duration
+	jobs             []Job
+}
+
+func New(locker repository.LockRepository, electionInterval time.Duration) *Scheduler {
+	return &Scheduler{
+		locker:           locker,
+		electionInterval: electionInterval,
+	}
+}
+
+func (s *Scheduler) Register(name string, interval time.Duration, fn JobFunc) {
+	s.jobs = append(s.jobs, Job{
+		Name:     name,
+		Interval: interval,
+		Run:      fn,
+	})
+}
+
+func (s *Scheduler) Start(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, job := range s.jobs {
+		g.Go(func() error {
+			return s.runJob(ctx, job)
+		})
+	}
+
+	return g.Wait()
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job Job) error {
+	log := logger.FromContext(ctx).With("job", job.Name)
+
+	ticker := time.NewTicker(s.electionInterval)
+	defer ticker.Stop()
+
+	var lock repository.Lock
+	var nextRun time.Time
+
+	defer func() {
+		if lock != nil {
+			if err := lock.Release(context.Background()); err != nil {
+				log.With("err", err.Error()).Warn("failed to release job lock")
+			}
+		}
+	}()
+
+	log.Info("scheduled job registered")
+
+	for {
+		if lock == nil {
+			acquired, ok, err := s.locker.TryAcquire(ctx, job.Name)
+			switch {
+			case err != nil:
+				log.With("err", err.Error()).Error("failed to acquire job lock")
+			case ok:
+				log.Info("job leadership acquired")
+				lock = acquired
+				nextRun = time.Now()
+			}
+		} else if err := lock.Alive(ctx); err != nil {
+			log.With("err", err.Error()).Warn("job leadership lost")
+			lock.Release(ctx)
+			lock = nil
+		}
+
+		if lock != nil && !time.Now().Before(nextRun) {
+			if err := job.Run(ctx); err != nil {
+				log.With("err", err.Error()).Error("job failed")
+			}
+			nextRun = time.Now().Add(job.Interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("scheduled job stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
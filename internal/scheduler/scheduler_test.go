@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLocker emulates advisory locks shared by several scheduler instances.
+type fakeLocker struct {
+	mu   sync.Mutex
+	held map[string]*fakeLock
+}
+
+type fakeLock struct {
+	locker *fakeLocker
+	name   string
+	dead   atomic.Bool
+}
+
+func (l *fakeLocker) TryAcquire(_ context.Context, name string) (repository.Lock, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.held[name]; ok {
+		return nil, false, nil
+	}
+
+	lock := &fakeLock{locker: l, name: name}
+	l.held[name] = lock
+	return lock, true, nil
+}
+
+func (l *fakeLock) Alive(context.Context) error {
+	if l.dead.Load() {
+		return errors.New("connection lost")
+	}
+	return nil
+}
+
+func (l *fakeLock) Release(context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	if l.locker.held[l.name] == l {
+		delete(l.locker.held, l.name)
+	}
+	return nil
+}
+
+func (l *fakeLocker) kill(name string) {
+	l.mu.Lock()
+	lock := l.held[name]
+	delete(l.held, name)
+	l.mu.Unlock()
+
+	lock.dead.Store(true)
+}
+
+func TestScheduler_SingleLeader(t *testing.T) {
+	locker := &fakeLocker{held: map[string]*fakeLock{}}
+
+	var runsA, runsB atomic.Int32
+	a := New(locker, 5*time.Millisecond)
+	a.Register("job", 5*time.Millisecond, func(context.Context) error {
+		runsA.Add(1)
+		return nil
+	})
+	b := New(locker, 5*time.Millisecond)
+	b.Register("job", 5*time.Millisecond, func(context.Context) error {
+		runsB.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, s := range []*Scheduler{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, s.Start(ctx))
+		}()
+	}
+	wg.Wait()
+
+	total := runsA.Load() + runsB.Load()
+	assert.Positive(t, total)
+	assert.True(t, runsA.Load() == 0 || runsB.Load() == 0, "job must run on a single instance")
+	assert.Empty(t, locker.held, "lock must be released on shutdown")
+}
+
+func TestScheduler_Failover(t *testing.T) {
+	locker := &fakeLocker{held: map[string]*fakeLock{}}
+
+	var leaderRuns, followerRuns atomic.Int32
+	leader := New(locker, 5*time.Millisecond)
+	leader.Register("job", 5*time.Millisecond, func(context.Context) error {
+		leaderRuns.Add(1)
+		return nil
+	})
+	follower := New(locker, 5*time.Millisecond)
+	follower.Register("job", 5*time.Millisecond, func(context.Context) error {
+		followerRuns.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leaderCtx, stopLeader := context.WithCancel(ctx)
+	go leader.Start(leaderCtx)
+	require.Eventually(t, func() bool { return leaderRuns.Load() > 0 }, time.Second, time.Millisecond)
+
+	go follower.Start(ctx)
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, followerRuns.Load())
+
+	// Emulate the leader node dying: its session and lock disappear.
+	locker.kill("job")
+	stopLeader()
+
+	assert.Eventually(t, func() bool { return followerRuns.Load() > 0 }, time.Second, time.Millisecond)
+}
@@ -2,33 +2,81 @@ package server
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/auth"
 	"github.com/mrhyman/gophermart/internal/config"
 	"github.com/mrhyman/gophermart/internal/handler"
+	"github.com/mrhyman/gophermart/internal/health"
 	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/metrics"
 	"github.com/mrhyman/gophermart/internal/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Server struct {
-	Instance *http.Server
+	Instance   *http.Server
+	health     *health.Checker
+	drainDelay time.Duration
 }
 
-func New(cfg config.AppConfig, h handler.HTTPHandler) *Server {
+func New(cfg config.AppConfig, h handler.HTTPHandler, checker *health.Checker) (*Server, error) {
+	mux, err := SetupMux(&h, cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Server{
 		Instance: &http.Server{
 			Addr:    cfg.RunAddress,
-			Handler: SetupMux(&h, cfg),
+			Handler: mux,
+		},
+		health:     checker,
+		drainDelay: cfg.ShutdownDrainDelay,
+	}, nil
+}
+
+// NewMetrics creates the admin listener serving Prometheus metrics. It is kept
+// apart from the public API so it is not exposed through the load balancer.
+func NewMetrics(cfg config.AppConfig) *Server {
+	r := chi.NewRouter()
+	r.Handle("/metrics", metrics.Handler())
+
+	return &Server{
+		Instance: &http.Server{
+			Addr:    cfg.MetricsAddress,
+			Handler: r,
 		},
 	}
 }
 
 func (s *Server) Start(ctx context.Context) error {
-	logger.FromContext(ctx).Infof("listening on %s", s.Instance.Addr)
+	log := logger.FromContext(ctx)
+	log.Infof("listening on %s", s.Instance.Addr)
+
+	// Requests start from the application context so they inherit its logger;
+	// cancellation is left to Shutdown so in-flight requests can finish.
+	s.Instance.BaseContext = func(net.Listener) context.Context {
+		return context.WithoutCancel(ctx)
+	}
 
 	go func() {
 		<-ctx.Done()
+
+		// Fail readiness first so load balancers stop sending traffic while
+		// we are still serving.
+		if s.health != nil {
+			s.health.Shutdown()
+		}
+		if s.drainDelay > 0 {
+			log.With("drain_delay", s.drainDelay.String()).Info("readiness failing, draining")
+			time.Sleep(s.drainDelay)
+		}
+
 		s.Instance.Shutdown(context.Background())
 	}()
 
@@ -43,10 +91,46 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.Instance.Shutdown(ctx)
 }
 
-func SetupMux(h *handler.HTTPHandler, cfg config.AppConfig) http.Handler {
+func SetupMux(h *handler.HTTPHandler, cfg config.AppConfig) (http.Handler, error) {
+	r, err := NewRouter(h, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return otelhttp.NewHandler(r, "http.server", otelhttp.WithFilter(func(req *http.Request) bool {
+		return req.URL.Path != "/healthz" && req.URL.Path != "/readyz"
+	})), nil
+}
+
+// NewRouter registers every route of the API. Routes must be described in
+// api/openapi.json.
+func NewRouter(h *handler.HTTPHandler, cfg config.AppConfig) (chi.Router, error) {
 	r := chi.NewRouter()
+	r.Use(middleware.WithRequestID)
+	r.Use(middleware.WithTracing)
+
+	if cfg.OpenAPIValidation {
+		doc, err := api.LoadOpenAPI(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		validator, err := middleware.NewOpenAPIValidator(doc)
+		if err != nil {
+			return nil, err
+		}
+		r.Use(validator.Middleware)
+	}
+
 	publicMW := PublicMiddleware()
-	authMW := AuthMiddleware(cfg)
+	authMW := AuthMiddleware(h.Keyring)
+
+	// Пробы для оркестратора и балансировщика, без логирования
+	r.Get("/healthz", h.Health.Live)
+	r.Get("/readyz", h.Health.Ready)
+
+	// Документация API
+	r.Get("/openapi.json", publicMW(h.Docs.Spec))
+	r.Get("/docs", publicMW(h.Docs.Page))
 
 	// Роуты без авторизации
 	r.Post("/api/user/register", publicMW(h.User.Register))
@@ -54,12 +138,47 @@ func SetupMux(h *handler.HTTPHandler, cfg config.AppConfig) http.Handler {
 
 	// Роуты с авторизацией
 	r.Post("/api/user/orders", authMW(h.Order.UploadOrder))
+	r.Post("/api/user/orders/bulk", authMW(h.Order.UploadOrdersBulk))
 	r.Get("/api/user/orders", authMW(h.Order.GetOrderList))
+	r.Get("/api/user/orders/{number}", authMW(h.Order.GetOrder))
 	r.Get("/api/user/balance", authMW(h.Balance.GetBalance))
 	r.Post("/api/user/balance/withdraw", authMW(h.Balance.Withdraw))
+	r.Post(handler.HoldsPath, authMW(h.Balance.CreateHold))
+	r.Get(handler.HoldsPath+"/{id}", authMW(h.Balance.GetHold))
+	r.Post(handler.HoldsPath+"/{id}/confirm", authMW(h.Balance.ConfirmHold))
+	r.Post(handler.HoldsPath+"/{id}/release", authMW(h.Balance.ReleaseHold))
+	r.Post(handler.TransferPath, authMW(h.Balance.Transfer))
+	r.Get(handler.TransfersPath, authMW(h.Balance.GetTransfers))
 	r.Get("/api/user/withdrawals", authMW(h.Balance.GetWithdrawals))
+	r.Get("/api/user/events", authMW(h.Events.Stream))
+
+	// Вторая версия API: JSON и единые конверты ответов
+	r.Route(handler.V2Prefix, func(r chi.Router) {
+		r.Post("/orders", authMW(h.Order.UploadOrderV2))
+		r.Get("/orders", authMW(h.Order.ListOrdersV2))
+		r.Get("/orders/{number}", authMW(h.Order.GetOrderV2))
+		r.Get("/balance", authMW(h.Balance.GetBalanceV2))
+		r.Get("/withdrawals", authMW(h.Balance.ListWithdrawalsV2))
+	})
+
+	// Админские роуты, доступны только при заданном токене
+	if cfg.AdminToken != "" {
+		adminMW := AdminMiddleware(cfg)
+		r.Get("/api/admin/orders/stale", adminMW(h.Admin.GetStaleOrders))
+		r.Post("/api/admin/orders/{number}/accrual", adminMW(h.Admin.AdjustAccrual))
+		r.Post(handler.ImportsPath, adminMW(h.Admin.ImportOrders))
+		r.Get(handler.ImportsPath+"/{id}", adminMW(h.Admin.GetImport))
+		r.Get(handler.ImportsPath+"/{id}/errors", adminMW(h.Admin.GetImportErrors))
+		r.Post(handler.WebhooksPath, adminMW(h.Admin.CreateWebhook))
+		r.Get(handler.WebhooksPath, adminMW(h.Admin.ListWebhooks))
+		r.Delete(handler.WebhooksPath+"/{id}", adminMW(h.Admin.DeleteWebhook))
+		r.Get(handler.WebhooksPath+"/{id}/deliveries", adminMW(h.Admin.GetWebhookDeliveries))
+		r.Post(handler.WebhooksPath+"/deliveries/{id}/replay", adminMW(h.Admin.ReplayWebhookDelivery))
+		r.Get(handler.WithdrawalsPath, adminMW(h.Admin.ListWithdrawals))
+		r.Post(handler.WithdrawalsPath+"/{id}/refunds", adminMW(h.Admin.RefundWithdrawal))
+	}
 
-	return r
+	return r, nil
 }
 
 func PublicMiddleware() func(http.HandlerFunc) http.HandlerFunc {
@@ -70,9 +189,19 @@ func PublicMiddleware() func(http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func AuthMiddleware(cfg config.AppConfig) func(http.HandlerFunc) http.HandlerFunc {
+func AuthMiddleware(keyring *auth.Keyring) func(http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return middleware.WithKeyring(keyring)(
+			middleware.WithGzip(
+				middleware.WithLogging(h),
+			),
+		)
+	}
+}
+
+func AdminMiddleware(cfg config.AppConfig) func(http.HandlerFunc) http.HandlerFunc {
 	return func(h http.HandlerFunc) http.HandlerFunc {
-		return middleware.WithAuth(cfg.HashKey)(
+		return middleware.WithAdminToken(cfg.AdminToken)(
 			middleware.WithGzip(
 				middleware.WithLogging(h),
 			),
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/config"
+	"github.com/mrhyman/gophermart/internal/handler"
+	"github.com/mrhyman/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	doc, err := api.LoadOpenAPI(context.Background())
+	require.NoError(t, err)
+	return doc
+}
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	doc := loadSpec(t)
+
+	cfg := config.Default()
+	cfg.AdminToken = "admin"
+	cfg.OpenAPIValidation = true
+
+	r, err := NewRouter(handler.New(service.Service{}, nil, nil, nil), cfg)
+	require.NoError(t, err)
+
+	var registered []string
+	err = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered = append(registered, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	assert.Equal(t, documented, registered, "routes in SetupMux and api/openapi.json differ")
+}
+
+func TestDTOsMatchOpenAPI(t *testing.T) {
+	doc := loadSpec(t)
+
+	dtos := map[string]any{
+		"RegisterRequest":             api.RegisterRequest{},
+		"LoginRequest":                api.LoginRequest{},
+		"WithdrawRequest":             api.WithdrawRequest{},
+		"OrderListResponse":           api.OrderListResponse{},
+		"UserBalanceResponse":         api.UserBalanceResponse{},
+		"ExpiringPointsResponse":      api.ExpiringPointsResponse{},
+		"WithdrawalHoldRequest":       api.WithdrawalHoldRequest{},
+		"WithdrawalHoldResponse":      api.WithdrawalHoldResponse{},
+		"WithdrawalListResponse":      api.WithdrawalListResponse{},
+		"StaleOrderResponse":          api.StaleOrderResponse{},
+		"StaleOrdersReportResponse":   api.StaleOrdersReportResponse{},
+		"HealthCheckResponse":         api.HealthCheckResponse{},
+		"HealthResponse":              api.HealthResponse{},
+		"Problem":                     api.Problem{},
+		"ListMeta":                    api.ListMeta{},
+		"OrderUploadRequest":          api.OrderUploadRequest{},
+		"OrderLinks":                  api.OrderLinks{},
+		"OrderResource":               api.OrderResource{},
+		"OrderUploadResult":           api.OrderUploadResult{},
+		"BulkUploadResult":            api.BulkUploadResult{},
+		"BulkUploadResponse":          api.BulkUploadResponse{},
+		"ImportJobLinks":              api.ImportJobLinks{},
+		"ImportJobResponse":           api.ImportJobResponse{},
+		"OrderStatusChangeResponse":   api.OrderStatusChangeResponse{},
+		"OrderDetailResponse":         api.OrderDetailResponse{},
+		"WebhookSubscriptionRequest":  api.WebhookSubscriptionRequest{},
+		"WebhookSubscriptionResponse": api.WebhookSubscriptionResponse{},
+		"WebhookAttemptResponse":      api.WebhookAttemptResponse{},
+		"WebhookDeliveryResponse":     api.WebhookDeliveryResponse{},
+		"AdminWithdrawalResponse":     api.AdminWithdrawalResponse{},
+		"WithdrawalRefundRequest":     api.WithdrawalRefundRequest{},
+		"WithdrawalRefundResponse":    api.WithdrawalRefundResponse{},
+		"PointsTransferRequest":       api.PointsTransferRequest{},
+		"PointsTransferResponse":      api.PointsTransferResponse{},
+		"AccrualAdjustmentRequest":    api.AccrualAdjustmentRequest{},
+		"AccrualAdjustmentResponse":   api.AccrualAdjustmentResponse{},
+	}
+
+	for name := range doc.Components.Schemas {
+		assert.Contains(t, dtos, name, "schema %s has no DTO", name)
+	}
+
+	for name, dto := range dtos {
+		t.Run(name, func(t *testing.T) {
+			ref, ok := doc.Components.Schemas[name]
+			require.True(t, ok, "DTO %s is not described", name)
+			schema := ref.Value
+
+			typ := reflect.TypeOf(dto)
+			var fields, required []string
+			for i := 0; i < typ.NumField(); i++ {
+				tag := typ.Field(i).Tag.Get("json")
+				field, opts, _ := strings.Cut(tag, ",")
+				if field == "" || field == "-" {
+					continue
+				}
+				fields = append(fields, field)
+				if !strings.Contains(opts, "omitempty") {
+					required = append(required, field)
+				}
+			}
+
+			var properties []string
+			for prop := range schema.Properties {
+				properties = append(properties, prop)
+			}
+
+			assert.ElementsMatch(t, fields, properties, "properties")
+			assert.ElementsMatch(t, required, schema.Required, "required properties")
+		})
+	}
+}
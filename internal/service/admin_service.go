@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/tracing"
+)
+
+type AdminService struct {
+	orderRepo  repository.OrderRepository
+	importRepo repository.ImportRepository
+}
+
+func NewAdminService(orderRepo repository.OrderRepository, importRepo repository.ImportRepository) *AdminService {
+	return &AdminService{
+		orderRepo:  orderRepo,
+		importRepo: importRepo,
+	}
+}
+
+// GetStaleOrders returns orders flagged by the stale order sweeper.
+func (s *AdminService) GetStaleOrders(ctx context.Context) (_ []*model.Order, err error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetStaleOrders")
+	defer tracing.End(span, &err)
+
+	return s.orderRepo.GetFlagged(ctx)
+}
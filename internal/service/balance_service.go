@@ -2,29 +2,220 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/mrhyman/gophermart/internal/metrics"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/tracing"
+)
+
+const (
+	// ExpiringSoonWindow is how far ahead the balance lists expiring points.
+	ExpiringSoonWindow = 30 * 24 * time.Hour
+	// maxTransferNoteLength keeps notes short enough for a history line.
+	maxTransferNoteLength = 255
+	// maxIdempotencyKeyLength fits the column of the key.
+	maxIdempotencyKeyLength = 255
 )
 
 type BalanceService struct {
 	repo repository.BalanceRepository
+	// holdTTL is how long a withdrawal hold reserves points.
+	holdTTL time.Duration
+	// transferLimit is how much a user can transfer within 24 hours, in
+	// hundredths of a point. Zero means no limit.
+	transferLimit int
 }
 
-func NewBalanceService(repo repository.BalanceRepository) *BalanceService {
-	return &BalanceService{repo: repo}
+func NewBalanceService(repo repository.BalanceRepository, holdTTL time.Duration, transferLimit int) *BalanceService {
+	return &BalanceService{repo: repo, holdTTL: holdTTL, transferLimit: transferLimit}
 }
 
-func (s *BalanceService) GetUserBalance(ctx context.Context, userID uuid.UUID) (int, int, error) {
+func (s *BalanceService) GetUserBalance(ctx context.Context, userID uuid.UUID) (_ *model.Balance, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetUserBalance")
+	defer tracing.End(span, &err)
+
 	return s.repo.GetUserBalance(ctx, userID)
 }
 
-func (s *BalanceService) Withdraw(ctx context.Context, userID uuid.UUID, orderNumber string, sum int) error {
-	return s.repo.Withdraw(ctx, userID, orderNumber, sum)
+// GetExpiringPoints returns the points of the user expiring within
+// ExpiringSoonWindow, soonest first.
+func (s *BalanceService) GetExpiringPoints(ctx context.Context, userID uuid.UUID) (_ []model.ExpiringPoints, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetExpiringPoints")
+	defer tracing.End(span, &err)
+
+	return s.repo.GetExpiringPoints(ctx, userID, time.Now().Add(ExpiringSoonWindow))
+}
+
+func (s *BalanceService) Withdraw(ctx context.Context, userID uuid.UUID, orderNumber string, sum int) (err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.Withdraw")
+	defer tracing.End(span, &err)
+
+	if sum > model.MaxPointsAmount {
+		return errSumTooLarge()
+	}
+
+	if err := s.repo.Withdraw(ctx, userID, orderNumber, sum); err != nil {
+		return err
+	}
+
+	metrics.AddPointsWithdrawn(sum)
+	return nil
 }
 
-func (s *BalanceService) GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]model.Withdrawal, error) {
+func (s *BalanceService) GetWithdrawals(ctx context.Context, userID uuid.UUID) (_ []model.Withdrawal, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetWithdrawals")
+	defer tracing.End(span, &err)
+
 	return s.repo.GetWithdrawals(ctx, userID)
 }
+
+// CreateHold reserves sum points for the order. They stay in the balance but
+// are no longer available until the hold is confirmed, released or expires.
+func (s *BalanceService) CreateHold(ctx context.Context, userID uuid.UUID, orderNumber string, sum int) (_ *model.WithdrawalHold, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.CreateHold")
+	defer tracing.End(span, &err)
+
+	if err := checkSum(sum); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateHold(ctx, userID, orderNumber, sum, s.holdTTL)
+}
+
+func (s *BalanceService) GetHold(ctx context.Context, userID, holdID uuid.UUID) (_ *model.WithdrawalHold, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetHold")
+	defer tracing.End(span, &err)
+
+	return s.repo.GetHold(ctx, userID, holdID)
+}
+
+// ConfirmHold withdraws the points reserved by the hold.
+func (s *BalanceService) ConfirmHold(ctx context.Context, userID, holdID uuid.UUID) (_ *model.WithdrawalHold, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.ConfirmHold")
+	defer tracing.End(span, &err)
+
+	hold, err := s.repo.ConfirmHold(ctx, userID, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.AddPointsWithdrawn(hold.Sum)
+	return hold, nil
+}
+
+// ReleaseHold makes the points reserved by the hold available again.
+func (s *BalanceService) ReleaseHold(ctx context.Context, userID, holdID uuid.UUID) (_ *model.WithdrawalHold, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.ReleaseHold")
+	defer tracing.End(span, &err)
+
+	return s.repo.ReleaseHold(ctx, userID, holdID)
+}
+
+// GetWithdrawalsByOrder finds the withdrawals paying for a shop order, so
+// support can refund them.
+func (s *BalanceService) GetWithdrawalsByOrder(ctx context.Context, orderNumber string) (_ []model.Withdrawal, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetWithdrawalsByOrder")
+	defer tracing.End(span, &err)
+
+	if orderNumber == "" {
+		return nil, fmt.Errorf("%w: order is required", model.ErrInvalidRequestParams)
+	}
+
+	return s.repo.GetWithdrawalsByOrder(ctx, orderNumber)
+}
+
+// RefundWithdrawal returns sum points of the withdrawal to the user, or all
+// of them not refunded yet when full is set.
+func (s *BalanceService) RefundWithdrawal(
+	ctx context.Context,
+	withdrawalID uuid.UUID,
+	sum int,
+	full bool,
+	reason string,
+) (_ *model.WithdrawalRefund, _ *model.Withdrawal, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.RefundWithdrawal")
+	defer tracing.End(span, &err)
+
+	if full {
+		if sum != 0 {
+			return nil, nil, fmt.Errorf("%w: sum must not be given with full", model.ErrInvalidRequestParams)
+		}
+	} else if err := checkSum(sum); err != nil {
+		return nil, nil, err
+	}
+
+	refund, withdrawal, err := s.repo.RefundWithdrawal(ctx, withdrawalID, sum, full, strings.TrimSpace(reason))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metrics.AddPointsRefunded(refund.Sum)
+	return refund, withdrawal, nil
+}
+
+// Transfer moves sum points of the sender to the user with the recipient
+// login. The boolean is false when the idempotency key was used before and
+// the earlier transfer is returned instead.
+func (s *BalanceService) Transfer(
+	ctx context.Context,
+	senderID uuid.UUID,
+	recipientLogin string,
+	sum int,
+	note, idempotencyKey string,
+) (_ *model.PointsTransfer, _ bool, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.Transfer")
+	defer tracing.End(span, &err)
+
+	note = strings.TrimSpace(note)
+	switch {
+	case idempotencyKey == "" || len(idempotencyKey) > maxIdempotencyKeyLength:
+		return nil, false, fmt.Errorf("%w: idempotency key must be 1 to %d characters", model.ErrInvalidRequestParams, maxIdempotencyKeyLength)
+	case recipientLogin == "":
+		return nil, false, fmt.Errorf("%w: recipient is required", model.ErrInvalidRequestParams)
+	case len(note) > maxTransferNoteLength:
+		return nil, false, fmt.Errorf("%w: note must be at most %d characters", model.ErrInvalidRequestParams, maxTransferNoteLength)
+	}
+	if err := checkSum(sum); err != nil {
+		return nil, false, err
+	}
+
+	transfer, created, err := s.repo.Transfer(ctx, senderID, recipientLogin, sum, note, idempotencyKey, s.transferLimit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		metrics.AddPointsTransferred(sum)
+	}
+	return transfer, created, nil
+}
+
+// GetTransfers returns the transfers the user sent or received, latest first.
+func (s *BalanceService) GetTransfers(ctx context.Context, userID uuid.UUID) (_ []model.PointsTransfer, err error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetTransfers")
+	defer tracing.End(span, &err)
+
+	return s.repo.GetTransfers(ctx, userID)
+}
+
+// checkSum rejects sums, in hundredths, that are not positive or too large
+// to be stored.
+func checkSum(sum int) error {
+	if sum <= 0 {
+		return fmt.Errorf("%w: sum must be positive", model.ErrInvalidRequestParams)
+	}
+	if sum > model.MaxPointsAmount {
+		return errSumTooLarge()
+	}
+	return nil
+}
+
+func errSumTooLarge() error {
+	return fmt.Errorf("%w: sum must not exceed %.2f", model.ErrInvalidRequestParams, float64(model.MaxPointsAmount)/100)
+}
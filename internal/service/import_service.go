@@ -0,0 +1,296 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/tracing"
+	"github.com/mrhyman/gophermart/internal/util"
+)
+
+// importChunkSize is the number of rows imported in one transaction.
+const importChunkSize = 500
+
+// importColumns is the expected layout of the import file. The header line
+// itself is optional.
+var importColumns = []string{"login", "number", "status", "accrual", "uploaded_at"}
+
+const reasonAborted = "not processed: import stopped, re-run the file"
+
+// ImportOrders imports legacy orders from a CSV file with the importColumns
+// layout. Every row is validated first; in apply mode the valid rows are
+// written in chunks of importChunkSize, each in its own transaction. Orders
+// the user already has are skipped, so a file can be imported again after a
+// failure. The job is recorded together with its error report.
+//
+// When a chunk fails the job is still returned, marked as failed, along with
+// the error.
+func (s *AdminService) ImportOrders(ctx context.Context, src io.Reader, mode model.ImportMode) (_ *model.ImportJob, err error) {
+	ctx, span := tracing.Start(ctx, "AdminService.ImportOrders")
+	defer tracing.End(span, &err)
+
+	rows, results, err := parseImportFile(src, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows)+len(results) == 0 {
+		return nil, fmt.Errorf("%w: import file has no rows", model.ErrInvalidRequestParams)
+	}
+
+	job := &model.ImportJob{
+		ID:        uuid.New(),
+		Mode:      mode,
+		Status:    model.ImportStatusRunning,
+		TotalRows: len(rows) + len(results),
+	}
+	if err := s.importRepo.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	var runErr error
+	for start := 0; start < len(rows); start += importChunkSize {
+		chunk := rows[start:min(start+importChunkSize, len(rows))]
+
+		var chunkResults []model.ImportRowResult
+		if mode == model.ImportModeApply {
+			chunkResults, runErr = s.importRepo.Apply(ctx, job.ID, chunk)
+		} else {
+			chunkResults, runErr = s.importRepo.Preview(ctx, chunk)
+		}
+
+		if runErr != nil {
+			runErr = fmt.Errorf("import stopped at line %d: %w", chunk[0].Line, runErr)
+			for _, row := range rows[start:] {
+				results = append(results, model.ImportRowResult{
+					Line:    row.Line,
+					Login:   row.Login,
+					Number:  row.Number,
+					Outcome: model.ImportFailed,
+					Reason:  reasonAborted,
+				})
+			}
+			break
+		}
+
+		results = append(results, chunkResults...)
+	}
+
+	job.Status = model.ImportStatusCompleted
+	if runErr != nil {
+		job.Status = model.ImportStatusFailed
+	}
+
+	slices.SortFunc(results, func(a, b model.ImportRowResult) int { return a.Line - b.Line })
+	for _, res := range results {
+		switch res.Outcome {
+		case model.ImportImported:
+			job.Imported++
+		case model.ImportSkipped:
+			job.Skipped++
+		default:
+			job.Failed++
+		}
+	}
+
+	job.Report, err = buildImportReport(results)
+	if err != nil {
+		return nil, err
+	}
+
+	// The job must be closed even if the client has gone away.
+	if err := s.importRepo.FinishJob(context.WithoutCancel(ctx), job); err != nil {
+		return nil, errors.Join(runErr, err)
+	}
+
+	return job, runErr
+}
+
+// GetImport returns a previously run import job.
+func (s *AdminService) GetImport(ctx context.Context, id uuid.UUID) (_ *model.ImportJob, err error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetImport")
+	defer tracing.End(span, &err)
+
+	return s.importRepo.GetJob(ctx, id)
+}
+
+// parseImportFile reads the import file and validates every row. Rows that
+// can not be imported are returned as failed results; the error is reserved
+// for an unreadable file.
+func parseImportFile(src io.Reader, now time.Time) ([]model.ImportRow, []model.ImportRowResult, error) {
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var (
+		rows   []model.ImportRow
+		failed []model.ImportRowResult
+		first  = true
+	)
+	seen := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			failed = append(failed, model.ImportRowResult{
+				Line:    parseErr.StartLine,
+				Outcome: model.ImportFailed,
+				Reason:  parseErr.Err.Error(),
+			})
+			first = false
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", model.ErrInvalidRequestParams, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if first {
+			first = false
+			if isImportHeader(record) {
+				continue
+			}
+		}
+
+		row, reason := parseImportRecord(line, record, now)
+		if reason == "" {
+			if firstLine, dup := seen[row.Number]; dup {
+				reason = fmt.Sprintf("duplicate order number, first seen on line %d", firstLine)
+			} else {
+				seen[row.Number] = line
+			}
+		}
+
+		if reason != "" {
+			failed = append(failed, model.ImportRowResult{
+				Line:    line,
+				Login:   row.Login,
+				Number:  row.Number,
+				Outcome: model.ImportFailed,
+				Reason:  reason,
+			})
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, failed, nil
+}
+
+func isImportHeader(record []string) bool {
+	return len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), importColumns[0])
+}
+
+// parseImportRecord validates one record. It returns the reason the record
+// is rejected, or an empty string if it is valid.
+func parseImportRecord(line int, record []string, now time.Time) (model.ImportRow, string) {
+	row := model.ImportRow{Line: line}
+
+	if len(record) != len(importColumns) {
+		return row, fmt.Sprintf("expected %d columns, got %d", len(importColumns), len(record))
+	}
+
+	row.Login = strings.TrimSpace(record[0])
+	row.Number = strings.TrimSpace(record[1])
+
+	if row.Login == "" {
+		return row, "login is empty"
+	}
+
+	if !util.ValidateLuhn(row.Number) {
+		return row, model.ErrInvalidOrderNumber.Error()
+	}
+
+	// Imported orders are never sent to the accrual system, so only final
+	// statuses are accepted.
+	row.Status = model.OrderStatus(strings.ToUpper(strings.TrimSpace(record[2])))
+	switch row.Status {
+	case model.OrderStatusInvalid, model.OrderStatusProcessed:
+	case model.OrderStatusNew, model.OrderStatusProcessing:
+		return row, fmt.Sprintf("status %s is not final, only PROCESSED and INVALID orders can be imported", row.Status)
+	default:
+		return row, fmt.Sprintf("unknown status %q", record[2])
+	}
+
+	accrual, err := parseAccrual(strings.TrimSpace(record[3]))
+	if err != nil {
+		return row, fmt.Sprintf("invalid accrual %q", record[3])
+	}
+	if accrual > 0 && row.Status != model.OrderStatusProcessed {
+		return row, "accrual is only allowed for PROCESSED orders"
+	}
+	row.Accrual = accrual
+
+	uploadedAt, err := time.Parse(time.RFC3339, strings.TrimSpace(record[4]))
+	if err != nil {
+		return row, fmt.Sprintf("invalid uploaded_at %q, expected RFC 3339", record[4])
+	}
+	if uploadedAt.After(now) {
+		return row, "uploaded_at is in the future"
+	}
+	row.UploadedAt = uploadedAt
+
+	return row, ""
+}
+
+// parseAccrual converts a non-negative amount with at most two decimals to
+// hundredths. An empty amount is zero.
+func parseAccrual(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	if _, frac, ok := strings.Cut(s, "."); ok && len(frac) > 2 {
+		return 0, fmt.Errorf("more than two decimals in %q", s)
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || math.IsInf(v, 0) || math.IsNaN(v) || v > math.MaxInt32/100 {
+		return 0, fmt.Errorf("accrual %q out of range", s)
+	}
+
+	return int(math.Round(v * 100)), nil
+}
+
+// buildImportReport renders failed rows as CSV.
+func buildImportReport(results []model.ImportRowResult) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"line", "login", "number", "reason"}); err != nil {
+		return "", err
+	}
+
+	for _, res := range results {
+		if res.Outcome != model.ImportFailed {
+			continue
+		}
+		err := w.Write([]string{strconv.Itoa(res.Line), res.Login, res.Number, res.Reason})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	return buf.String(), w.Error()
+}
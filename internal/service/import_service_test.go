@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseImportFile(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	file := strings.Join([]string{
+		"login,number,status,accrual,uploaded_at",
+		"alice,12345678903,PROCESSED,500.5,2024-03-01T10:00:00Z",
+		"bob, 79927398713 ,invalid,,2024-03-02T10:00:00+03:00",
+		"carol,12345678900,PROCESSED,1,2024-03-01T10:00:00Z",
+		"dave,2377225624,DONE,1,2024-03-01T10:00:00Z",
+		"erin,4561261212345467,INVALID,1,2024-03-01T10:00:00Z",
+		"frank,12345678903,PROCESSED,1,2024-03-01T10:00:00Z",
+		"gina,2377225624,PROCESSED,1.005,2024-03-01T10:00:00Z",
+		"hank,2377225624,PROCESSED,-1,2024-03-01T10:00:00Z",
+		"ivan,2377225624,PROCESSED,1,yesterday",
+		"jane,2377225624,PROCESSED,1,2026-03-01T10:00:00Z",
+		",2377225624,PROCESSED,1,2024-03-01T10:00:00Z",
+		"kate,2377225624",
+		"liam,2377225624,NEW,,2024-03-01T10:00:00Z",
+		"mia,2377225624,processing,,2024-03-01T10:00:00Z",
+	}, "\n")
+
+	rows, failed, err := parseImportFile(strings.NewReader(file), now)
+	require.NoError(t, err)
+
+	require.Len(t, rows, 2)
+	assert.Equal(t, model.ImportRow{
+		Line:       2,
+		Login:      "alice",
+		Number:     "12345678903",
+		Status:     model.OrderStatusProcessed,
+		Accrual:    50050,
+		UploadedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	}, rows[0])
+	assert.Equal(t, "79927398713", rows[1].Number)
+	assert.Equal(t, model.OrderStatusInvalid, rows[1].Status)
+	assert.Zero(t, rows[1].Accrual)
+
+	reasons := make(map[int]string, len(failed))
+	for _, res := range failed {
+		assert.Equal(t, model.ImportFailed, res.Outcome)
+		reasons[res.Line] = res.Reason
+	}
+	assert.Equal(t, map[int]string{
+		4:  model.ErrInvalidOrderNumber.Error(),
+		5:  `unknown status "DONE"`,
+		6:  "accrual is only allowed for PROCESSED orders",
+		7:  "duplicate order number, first seen on line 2",
+		8:  `invalid accrual "1.005"`,
+		9:  `invalid accrual "-1"`,
+		10: `invalid uploaded_at "yesterday", expected RFC 3339`,
+		11: "uploaded_at is in the future",
+		12: "login is empty",
+		13: "expected 5 columns, got 2",
+		14: "status NEW is not final, only PROCESSED and INVALID orders can be imported",
+		15: "status PROCESSING is not final, only PROCESSED and INVALID orders can be imported",
+	}, reasons)
+}
+
+func TestAdminService_ImportOrders(t *testing.T) {
+	file := func(n int) string {
+		numbers := []string{"12345678903", "79927398713", "2377225624", "4561261212345467"}
+		var b strings.Builder
+		for i := range n {
+			fmt.Fprintf(&b, "user%d,%s,PROCESSED,10,2024-03-01T10:00:00Z\n", i, numbers[i%len(numbers)])
+		}
+		return b.String()
+	}
+
+	outcome := func(o model.ImportOutcome) func(context.Context, uuid.UUID, []model.ImportRow) ([]model.ImportRowResult, error) {
+		return func(_ context.Context, _ uuid.UUID, rows []model.ImportRow) ([]model.ImportRowResult, error) {
+			results := make([]model.ImportRowResult, len(rows))
+			for i, row := range rows {
+				results[i] = model.ImportRowResult{Line: row.Line, Login: row.Login, Number: row.Number, Outcome: o}
+			}
+			return results, nil
+		}
+	}
+
+	t.Run("dry run previews without writing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockImportRepository(ctrl)
+		svc := NewAdminService(nil, repo)
+
+		repo.EXPECT().CreateJob(gomock.Any(), gomock.Any()).Return(nil)
+		repo.EXPECT().Preview(gomock.Any(), gomock.Len(2)).Return([]model.ImportRowResult{
+			{Line: 1, Outcome: model.ImportImported},
+			{Line: 2, Login: "user1", Number: "79927398713", Outcome: model.ImportFailed, Reason: "unknown login"},
+		}, nil)
+		repo.EXPECT().FinishJob(gomock.Any(), gomock.Any()).Return(nil)
+
+		job, err := svc.ImportOrders(context.Background(), strings.NewReader(file(2)), model.ImportModeDryRun)
+		require.NoError(t, err)
+
+		assert.Equal(t, model.ImportStatusCompleted, job.Status)
+		assert.Equal(t, 2, job.TotalRows)
+		assert.Equal(t, 1, job.Imported)
+		assert.Equal(t, 1, job.Failed)
+		assert.Equal(t, "line,login,number,reason\n2,user1,79927398713,unknown login\n", job.Report)
+	})
+
+	t.Run("apply writes in chunks", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockImportRepository(ctrl)
+		svc := NewAdminService(nil, repo)
+
+		// Only 4 distinct numbers, the rest are duplicates rejected up front.
+		repo.EXPECT().CreateJob(gomock.Any(), gomock.Any()).Return(nil)
+		repo.EXPECT().Apply(gomock.Any(), gomock.Any(), gomock.Len(4)).DoAndReturn(outcome(model.ImportSkipped))
+		repo.EXPECT().FinishJob(gomock.Any(), gomock.Any()).Return(nil)
+
+		job, err := svc.ImportOrders(context.Background(), strings.NewReader(file(6)), model.ImportModeApply)
+		require.NoError(t, err)
+		assert.Equal(t, 4, job.Skipped)
+		assert.Equal(t, 2, job.Failed)
+	})
+
+	t.Run("failed chunk stops the job", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockImportRepository(ctrl)
+		svc := NewAdminService(nil, repo)
+
+		rows := make([]string, 0, importChunkSize+1)
+		for i := range importChunkSize + 1 {
+			rows = append(rows, fmt.Sprintf("user,%s,INVALID,,2024-03-01T10:00:00Z", luhnNumber(i)))
+		}
+
+		dbErr := errors.New("connection reset")
+		repo.EXPECT().CreateJob(gomock.Any(), gomock.Any()).Return(nil)
+		gomock.InOrder(
+			repo.EXPECT().Apply(gomock.Any(), gomock.Any(), gomock.Len(importChunkSize)).DoAndReturn(outcome(model.ImportImported)),
+			repo.EXPECT().Apply(gomock.Any(), gomock.Any(), gomock.Len(1)).Return(nil, dbErr),
+		)
+		repo.EXPECT().FinishJob(gomock.Any(), gomock.Any()).Return(nil)
+
+		job, err := svc.ImportOrders(context.Background(), strings.NewReader(strings.Join(rows, "\n")), model.ImportModeApply)
+		require.ErrorIs(t, err, dbErr)
+		require.NotNil(t, job)
+		assert.Equal(t, model.ImportStatusFailed, job.Status)
+		assert.Equal(t, importChunkSize, job.Imported)
+		assert.Equal(t, 1, job.Failed)
+		assert.Contains(t, job.Report, reasonAborted)
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		svc := NewAdminService(nil, mocks.NewMockImportRepository(gomock.NewController(t)))
+
+		_, err := svc.ImportOrders(context.Background(), strings.NewReader("login,number,status,accrual,uploaded_at\n"), model.ImportModeApply)
+		assert.ErrorIs(t, err, model.ErrInvalidRequestParams)
+	})
+}
+
+// luhnNumber returns a distinct number passing the Luhn check for every i.
+func luhnNumber(i int) string {
+	base := fmt.Sprintf("1%08d", i)
+
+	sum := 0
+	for j := len(base) - 1; j >= 0; j-- {
+		d := int(base[j] - '0')
+		if (len(base)-j)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return fmt.Sprintf("%s%d", base, (10-sum%10)%10)
+}
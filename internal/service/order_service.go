@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/tracing"
 	"github.com/mrhyman/gophermart/internal/util"
 )
 
@@ -18,7 +20,10 @@ func NewOrderService(repo repository.OrderRepository) *OrderService {
 	return &OrderService{repo: repo}
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, userID uuid.UUID, number string) (*model.Order, error) {
+func (s *OrderService) CreateOrder(ctx context.Context, userID uuid.UUID, number string) (_ *model.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CreateOrder")
+	defer tracing.End(span, &err)
+
 	if !util.ValidateLuhn(number) {
 		return nil, model.ErrInvalidOrderNumber
 	}
@@ -39,6 +44,134 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uuid.UUID, number
 	return s.repo.Create(ctx, userID, number)
 }
 
-func (s *OrderService) GetUserOrders(ctx context.Context, userID uuid.UUID) ([]*model.Order, error) {
+// CreateOrders uploads many numbers at once. Luhn is checked for every
+// number, valid ones are inserted in a single batch. Results follow the
+// order of numbers; repeated numbers get the same outcome.
+func (s *OrderService) CreateOrders(ctx context.Context, userID uuid.UUID, numbers []string) (_ []model.UploadResult, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CreateOrders")
+	defer tracing.End(span, &err)
+
+	var valid []string
+	seen := make(map[string]bool, len(numbers))
+	for _, number := range numbers {
+		if seen[number] || !util.ValidateLuhn(number) {
+			continue
+		}
+		seen[number] = true
+		valid = append(valid, number)
+	}
+
+	byNumber := make(map[string]model.UploadResult, len(valid))
+	if len(valid) > 0 {
+		orders, err := s.repo.CreateBatch(ctx, userID, valid)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range orders {
+			byNumber[o.Number] = uploadResult(userID, &o.Order, o.Created)
+		}
+	}
+
+	results := make([]model.UploadResult, 0, len(numbers))
+	for _, number := range numbers {
+		if !seen[number] {
+			results = append(results, model.UploadResult{Number: number, Outcome: model.UploadInvalid})
+			continue
+		}
+
+		res, ok := byNumber[number]
+		if !ok {
+			// Inserted by a concurrent upload after the batch took its snapshot.
+			order, err := s.repo.GetByNumber(ctx, number)
+			if err != nil {
+				return nil, err
+			}
+			res = uploadResult(userID, order, false)
+			byNumber[number] = res
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+func uploadResult(userID uuid.UUID, order *model.Order, created bool) model.UploadResult {
+	switch {
+	case created:
+		return model.UploadResult{Number: order.Number, Outcome: model.UploadAccepted, Order: order}
+	case order.UserID == userID:
+		return model.UploadResult{Number: order.Number, Outcome: model.UploadAlreadyYours, Order: order}
+	default:
+		return model.UploadResult{Number: order.Number, Outcome: model.UploadOwnedByAnotherUser}
+	}
+}
+
+func (s *OrderService) GetUserOrders(ctx context.Context, userID uuid.UUID) (_ []*model.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetUserOrders")
+	defer tracing.End(span, &err)
+
 	return s.repo.GetUserOrders(ctx, userID)
 }
+
+// GetUserOrder returns the order with the given number when it belongs to
+// the user. Orders of other users are reported as not found.
+func (s *OrderService) GetUserOrder(ctx context.Context, userID uuid.UUID, number string) (_ *model.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetUserOrder")
+	defer tracing.End(span, &err)
+
+	order, err := s.repo.GetByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserID != userID {
+		return nil, model.ErrNotFound
+	}
+
+	return order, nil
+}
+
+// GetUserOrderHistory returns the order of the user together with the status
+// transitions observed by the accrual worker, oldest first.
+func (s *OrderService) GetUserOrderHistory(
+	ctx context.Context,
+	userID uuid.UUID,
+	number string,
+) (_ *model.Order, _ []model.OrderStatusChange, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetUserOrderHistory")
+	defer tracing.End(span, &err)
+
+	order, err := s.GetUserOrder(ctx, userID, number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	history, err := s.repo.GetStatusHistory(ctx, order.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return order, history, nil
+}
+
+// AdjustAccrual applies a corrected accrual amount to an already processed
+// order and returns the delta credited to (or debited from) the user.
+func (s *OrderService) AdjustAccrual(ctx context.Context, number string, amount int) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.AdjustAccrual")
+	defer tracing.End(span, &err)
+
+	if amount < 0 {
+		return 0, fmt.Errorf("%w: accrual must not be negative", model.ErrInvalidRequestParams)
+	}
+
+	order, err := s.repo.GetByNumber(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+
+	if order.Status != model.OrderStatusProcessed {
+		return 0, model.ErrAccrualNotCredited
+	}
+
+	return s.repo.AdjustAccrual(ctx, order.ID, amount)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestOrderService_CreateOrders(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+
+	const (
+		newNumber   = "12345678903"
+		mineNumber  = "79927398713"
+		otherNumber = "2377225624"
+		racedNumber = "4561261212345467"
+		badNumber   = "12345678900"
+	)
+
+	t.Run("outcome per number in request order", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		svc := NewOrderService(repo)
+
+		repo.EXPECT().
+			CreateBatch(gomock.Any(), userID, []string{newNumber, mineNumber, otherNumber}).
+			Return([]model.BatchOrder{
+				{Order: model.Order{Number: newNumber, UserID: userID}, Created: true},
+				{Order: model.Order{Number: mineNumber, UserID: userID}},
+				{Order: model.Order{Number: otherNumber, UserID: otherUserID}},
+			}, nil)
+
+		results, err := svc.CreateOrders(context.Background(), userID,
+			[]string{newNumber, badNumber, mineNumber, otherNumber, newNumber})
+		require.NoError(t, err)
+
+		var outcomes []model.UploadOutcome
+		for _, res := range results {
+			outcomes = append(outcomes, res.Outcome)
+		}
+		assert.Equal(t, []model.UploadOutcome{
+			model.UploadAccepted,
+			model.UploadInvalid,
+			model.UploadAlreadyYours,
+			model.UploadOwnedByAnotherUser,
+			model.UploadAccepted,
+		}, outcomes)
+		assert.Nil(t, results[3].Order, "orders of other users must not leak")
+	})
+
+	t.Run("all invalid skips the database", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		svc := NewOrderService(mocks.NewMockOrderRepository(ctrl))
+
+		results, err := svc.CreateOrders(context.Background(), userID, []string{badNumber, "abc"})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.ErrorIs(t, results[0].Err(), model.ErrInvalidOrderNumber)
+	})
+
+	t.Run("number inserted concurrently is looked up", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		svc := NewOrderService(repo)
+
+		repo.EXPECT().CreateBatch(gomock.Any(), userID, []string{racedNumber}).Return(nil, nil)
+		repo.EXPECT().GetByNumber(gomock.Any(), racedNumber).
+			Return(&model.Order{Number: racedNumber, UserID: otherUserID}, nil)
+
+		results, err := svc.CreateOrders(context.Background(), userID, []string{racedNumber})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, model.UploadOwnedByAnotherUser, results[0].Outcome)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		svc := NewOrderService(repo)
+
+		dbErr := errors.New("connection refused")
+		repo.EXPECT().CreateBatch(gomock.Any(), userID, gomock.Any()).Return(nil, dbErr)
+
+		_, err := svc.CreateOrders(context.Background(), userID, []string{newNumber})
+		assert.ErrorIs(t, err, dbErr)
+	})
+}
+
+func TestOrderService_AdjustAccrual(t *testing.T) {
+	const number = "12345678903"
+	orderID := uuid.New()
+
+	t.Run("applies the delta", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		svc := NewOrderService(repo)
+
+		repo.EXPECT().GetByNumber(gomock.Any(), number).
+			Return(&model.Order{ID: orderID, Number: number, Status: model.OrderStatusProcessed, Accrual: 50000}, nil)
+		repo.EXPECT().AdjustAccrual(gomock.Any(), orderID, 45000).Return(-5000, nil)
+
+		delta, err := svc.AdjustAccrual(context.Background(), number, 45000)
+		require.NoError(t, err)
+		assert.Equal(t, -5000, delta)
+	})
+
+	t.Run("order not processed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockOrderRepository(ctrl)
+		svc := NewOrderService(repo)
+
+		repo.EXPECT().GetByNumber(gomock.Any(), number).
+			Return(&model.Order{ID: orderID, Number: number, Status: model.OrderStatusProcessing}, nil)
+
+		_, err := svc.AdjustAccrual(context.Background(), number, 45000)
+		assert.ErrorIs(t, err, model.ErrAccrualNotCredited)
+	})
+
+	t.Run("negative accrual", func(t *testing.T) {
+		svc := NewOrderService(mocks.NewMockOrderRepository(gomock.NewController(t)))
+
+		_, err := svc.AdjustAccrual(context.Background(), number, -1)
+		assert.ErrorIs(t, err, model.ErrInvalidRequestParams)
+	})
+}
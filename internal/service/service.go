@@ -1,17 +1,26 @@
 package service
 
-import "github.com/mrhyman/gophermart/internal/repository"
+import (
+	"math"
+
+	"github.com/mrhyman/gophermart/internal/config"
+	"github.com/mrhyman/gophermart/internal/repository"
+)
 
 type Service struct {
 	User    *UserService
 	Order   *OrderService
 	Balance *BalanceService
+	Admin   *AdminService
+	Webhook *WebhookService
 }
 
-func New(repos *repository.Repos) *Service {
+func New(repos *repository.Repos, cfg config.AppConfig) *Service {
 	return &Service{
 		User:    NewUserService(repos.User),
 		Order:   NewOrderService(repos.Order),
-		Balance: NewBalanceService(repos.Balance),
+		Balance: NewBalanceService(repos.Balance, cfg.WithdrawalHoldTTL, int(math.Round(cfg.TransferDailyLimit*100))),
+		Admin:   NewAdminService(repos.Order, repos.Import),
+		Webhook: NewWebhookService(repos.Webhook),
 	}
 }
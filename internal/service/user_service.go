@@ -7,6 +7,7 @@ import (
 	"github.com/mrhyman/gophermart/internal/auth"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/tracing"
 )
 
 type UserService struct {
@@ -17,7 +18,10 @@ func NewUserService(repo repository.UserRepository) *UserService {
 	return &UserService{repo: repo}
 }
 
-func (s *UserService) Register(ctx context.Context, login, password string) (string, error) {
+func (s *UserService) Register(ctx context.Context, login, password string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer tracing.End(span, &err)
+
 	hash, err := auth.HashPassword(password)
 	if err != nil {
 		return "", err
@@ -37,7 +41,10 @@ func (s *UserService) Register(ctx context.Context, login, password string) (str
 	return user.ID.String(), nil
 }
 
-func (s *UserService) Login(ctx context.Context, login, password string) (string, error) {
+func (s *UserService) Login(ctx context.Context, login, password string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer tracing.End(span, &err)
+
 	dbUser, err := s.repo.GetByLogin(ctx, login)
 	if err != nil {
 		return "", err
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/tracing"
+	"github.com/mrhyman/gophermart/internal/webhook"
+)
+
+const (
+	// minWebhookSecretLength keeps caller chosen secrets from being guessable.
+	minWebhookSecretLength = 16
+	// webhookDeliveriesLimit is the number of latest deliveries listed per
+	// subscription.
+	webhookDeliveriesLimit = 100
+)
+
+type WebhookService struct {
+	repo repository.WebhookRepository
+}
+
+func NewWebhookService(repo repository.WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo}
+}
+
+// CreateSubscription registers a receiver for the given event types. A
+// secret is generated when none is given.
+func (s *WebhookService) CreateSubscription(
+	ctx context.Context,
+	rawURL string,
+	secret string,
+	eventTypes []string,
+) (_ *model.WebhookSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateSubscription")
+	defer tracing.End(span, &err)
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http(s) URL", model.ErrInvalidRequestParams)
+	}
+
+	types, err := model.ParseWebhookEventTypes(eventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case secret == "":
+		if secret, err = webhook.NewSecret(); err != nil {
+			return nil, err
+		}
+	case len(secret) < minWebhookSecretLength:
+		return nil, fmt.Errorf("%w: secret must be at least %d characters", model.ErrInvalidRequestParams, minWebhookSecretLength)
+	}
+
+	sub := &model.WebhookSubscription{
+		ID:         uuid.New(),
+		URL:        u.String(),
+		Secret:     secret,
+		EventTypes: types,
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) (_ []model.WebhookSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListSubscriptions")
+	defer tracing.End(span, &err)
+
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteSubscription")
+	defer tracing.End(span, &err)
+
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+// GetDeliveries returns the latest deliveries of a subscription with their
+// attempts log.
+func (s *WebhookService) GetDeliveries(ctx context.Context, subscriptionID uuid.UUID) (_ []model.WebhookDelivery, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetDeliveries")
+	defer tracing.End(span, &err)
+
+	return s.repo.GetDeliveries(ctx, subscriptionID, webhookDeliveriesLimit)
+}
+
+// ReplayDelivery sends the event of a delivery again, whatever its outcome.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, deliveryID uuid.UUID) (_ *model.WebhookDelivery, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ReplayDelivery")
+	defer tracing.End(span, &err)
+
+	return s.repo.Replay(ctx, deliveryID)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestWebhookService_CreateSubscription(t *testing.T) {
+	t.Run("secret is generated when missing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mocks.NewMockWebhookRepository(ctrl)
+		s := NewWebhookService(repo)
+
+		repo.EXPECT().CreateSubscription(gomock.Any(), gomock.Any()).Return(nil)
+
+		sub, err := s.CreateSubscription(
+			context.Background(),
+			"https://crm.example.com/hooks",
+			"",
+			[]string{"order.processed", "withdrawal.created", "order.processed"},
+		)
+
+		require.NoError(t, err)
+		assert.Len(t, sub.Secret, 64)
+		assert.Equal(t, []model.WebhookEventType{model.WebhookOrderProcessed, model.WebhookWithdrawalCreated}, sub.EventTypes)
+	})
+
+	tests := []struct {
+		name   string
+		url    string
+		secret string
+		types  []string
+	}{
+		{"relative url", "/hooks", "", []string{"order.processed"}},
+		{"non http url", "ftp://crm.example.com", "", []string{"order.processed"}},
+		{"no event types", "https://crm.example.com", "", nil},
+		{"unknown event type", "https://crm.example.com", "", []string{"order.created"}},
+		{"short secret", "https://crm.example.com", "short", []string{"order.processed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := NewWebhookService(mocks.NewMockWebhookRepository(ctrl))
+
+			_, err := s.CreateSubscription(context.Background(), tt.url, tt.secret, tt.types)
+
+			assert.ErrorIs(t, err, model.ErrInvalidRequestParams)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrhyman/gophermart/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("file exporter writes spans", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.TraceExporter = config.TraceExporterFile
+		cfg.TraceFile = filepath.Join(t.TempDir(), "traces.jsonl")
+
+		shutdown, err := Setup(context.Background(), cfg)
+		require.NoError(t, err)
+
+		_, span := Start(context.Background(), "OrderService.CreateOrder")
+		span.End()
+
+		require.NoError(t, shutdown(context.Background()))
+
+		data, err := os.ReadFile(cfg.TraceFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"Name":"OrderService.CreateOrder"`)
+	})
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.TraceExporter = "jaeger"
+
+		_, err := Setup(context.Background(), cfg)
+		assert.Error(t, err)
+	})
+}
+
+func TestFail(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	_, span := tp.Tracer("test").Start(context.Background(), "ok")
+	Fail(span, nil)
+	span.End()
+
+	_, span = tp.Tracer("test").Start(context.Background(), "failed")
+	Fail(span, errors.New("boom"))
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	run := func(name string, fail error) (err error) {
+		_, span := tp.Tracer("test").Start(context.Background(), name)
+		defer End(span, &err)
+
+		return fail
+	}
+
+	require.NoError(t, run("ok", nil))
+	require.Error(t, run("failed", errors.New("boom")))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+	assert.Len(t, spans[1].Events(), 1)
+}
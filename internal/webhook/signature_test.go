@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+	sig := Sign("secret", now, body)
+
+	require.NoError(t, Verify("secret", "1700000000", sig, body, now.Add(time.Minute), 5*time.Minute))
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{"wrong secret", "other", "1700000000", sig, body, ErrInvalidSignature},
+		{"tampered body", "secret", "1700000000", sig, []byte(`{"id":"2"}`), ErrInvalidSignature},
+		{"tampered timestamp", "secret", "1700000001", sig, body, ErrInvalidSignature},
+		{"missing prefix", "secret", "1700000000", sig[len(signaturePrefix):], body, ErrInvalidSignature},
+		{"malformed timestamp", "secret", "soon", sig, body, ErrInvalidSignature},
+		{"old timestamp", "secret", "1699990000", sig, body, ErrTimestampExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, now, 5*time.Minute)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	require.NoError(t, err)
+	b, err := NewSecret()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 2*secretBytes)
+	assert.NotEqual(t, a, b)
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/repository"
+)
+
+// HoldExpiryBatchSize is the number of withdrawal holds expired in one
+// transaction.
+const HoldExpiryBatchSize = 500
+
+// HoldExpirer settles withdrawal holds left unconfirmed past their TTL.
+// Expire is run periodically by the scheduler.
+type HoldExpirer struct {
+	balanceRepo repository.BalanceRepository
+	batchSize   int
+}
+
+func NewHoldExpirer(repo repository.BalanceRepository, batchSize int) *HoldExpirer {
+	return &HoldExpirer{
+		balanceRepo: repo,
+		batchSize:   batchSize,
+	}
+}
+
+func (e *HoldExpirer) Expire(ctx context.Context) error {
+	now := time.Now()
+	total := 0
+	for {
+		expired, err := e.balanceRepo.ExpireHolds(ctx, now, e.batchSize)
+		if err != nil {
+			return err
+		}
+		total += expired
+
+		if expired < e.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.FromContext(ctx).With("event", "withdrawal_holds_expired", "count", total).Info("withdrawal holds expired")
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHoldExpirer_Expire(t *testing.T) {
+	t.Run("expires batches until one is not full", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		balanceRepo := mocks.NewMockBalanceRepository(ctrl)
+		e := NewHoldExpirer(balanceRepo, 2)
+
+		gomock.InOrder(
+			balanceRepo.EXPECT().ExpireHolds(gomock.Any(), gomock.Any(), 2).Return(2, nil),
+			balanceRepo.EXPECT().ExpireHolds(gomock.Any(), gomock.Any(), 2).Return(2, nil),
+			balanceRepo.EXPECT().ExpireHolds(gomock.Any(), gomock.Any(), 2).Return(0, nil),
+		)
+
+		assert.NoError(t, e.Expire(context.Background()))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		balanceRepo := mocks.NewMockBalanceRepository(ctrl)
+		e := NewHoldExpirer(balanceRepo, HoldExpiryBatchSize)
+
+		dbErr := errors.New("connection reset")
+		balanceRepo.EXPECT().ExpireHolds(gomock.Any(), gomock.Any(), HoldExpiryBatchSize).Return(0, dbErr)
+
+		assert.ErrorIs(t, e.Expire(context.Background()), dbErr)
+	})
+}
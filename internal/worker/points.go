@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/metrics"
+	"github.com/mrhyman/gophermart/internal/repository"
+)
+
+// PointsExpiryBatchSize is the number of users whose points expire in one
+// transaction.
+const PointsExpiryBatchSize = 100
+
+// PointsExpirer takes expired lots off user balances. Expire is run
+// periodically by the scheduler.
+type PointsExpirer struct {
+	balanceRepo repository.BalanceRepository
+	batchSize   int
+}
+
+func NewPointsExpirer(repo repository.BalanceRepository, batchSize int) *PointsExpirer {
+	return &PointsExpirer{
+		balanceRepo: repo,
+		batchSize:   batchSize,
+	}
+}
+
+func (e *PointsExpirer) Expire(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	now := time.Now()
+	users, amount, lots := 0, 0, 0
+	for {
+		expirations, err := e.balanceRepo.ExpirePoints(ctx, now, e.batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, exp := range expirations {
+			amount += exp.Amount
+			lots += exp.Lots
+		}
+		users += len(expirations)
+
+		if len(expirations) < e.batchSize {
+			break
+		}
+	}
+
+	if users == 0 {
+		return nil
+	}
+
+	metrics.AddPointsExpired(amount)
+
+	log.With(
+		"event", "points_expired",
+		"users", users,
+		"lots", lots,
+		"amount", amount,
+	).Info("expired points taken off balances")
+
+	return nil
+}
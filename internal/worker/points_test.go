@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPointsExpirer_Expire(t *testing.T) {
+	t.Run("expires batches until one is not full", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		balanceRepo := mocks.NewMockBalanceRepository(ctrl)
+		e := NewPointsExpirer(balanceRepo, 2)
+
+		full := []model.PointsExpiration{
+			{UserID: uuid.New(), Amount: 1000, Lots: 1},
+			{UserID: uuid.New(), Amount: 250, Lots: 2},
+		}
+		last := []model.PointsExpiration{{UserID: uuid.New(), Amount: 50, Lots: 1}}
+
+		var first time.Time
+		gomock.InOrder(
+			balanceRepo.EXPECT().
+				ExpirePoints(gomock.Any(), gomock.Any(), 2).
+				DoAndReturn(func(_ context.Context, now time.Time, _ int) ([]model.PointsExpiration, error) {
+					assert.WithinDuration(t, time.Now(), now, time.Second)
+					first = now
+					return full, nil
+				}),
+			balanceRepo.EXPECT().
+				ExpirePoints(gomock.Any(), gomock.Any(), 2).
+				DoAndReturn(func(_ context.Context, now time.Time, _ int) ([]model.PointsExpiration, error) {
+					assert.Equal(t, first, now, "one run expires up to one point in time")
+					return last, nil
+				}),
+		)
+
+		assert.NoError(t, e.Expire(context.Background()))
+	})
+
+	t.Run("nothing due", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		balanceRepo := mocks.NewMockBalanceRepository(ctrl)
+		e := NewPointsExpirer(balanceRepo, PointsExpiryBatchSize)
+
+		balanceRepo.EXPECT().ExpirePoints(gomock.Any(), gomock.Any(), PointsExpiryBatchSize).Return(nil, nil).Times(1)
+
+		assert.NoError(t, e.Expire(context.Background()))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		balanceRepo := mocks.NewMockBalanceRepository(ctrl)
+		e := NewPointsExpirer(balanceRepo, PointsExpiryBatchSize)
+
+		dbErr := errors.New("connection reset")
+		balanceRepo.EXPECT().ExpirePoints(gomock.Any(), gomock.Any(), PointsExpiryBatchSize).Return(nil, dbErr)
+
+		assert.ErrorIs(t, e.Expire(context.Background()), dbErr)
+	})
+}
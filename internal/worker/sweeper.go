@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/metrics"
+	"github.com/mrhyman/gophermart/internal/repository"
+)
+
+// StaleOrderSweeper flags orders stuck in NEW/PROCESSING for longer than
+// maxAge and optionally expires them. Sweep is run periodically by the scheduler
+// and reports every stale order once, in the run that flagged it.
+type StaleOrderSweeper struct {
+	orderRepo repository.OrderRepository
+	maxAge    time.Duration
+	expire    bool
+}
+
+func NewStaleOrderSweeper(
+	repo repository.OrderRepository,
+	maxAge time.Duration,
+	expire bool,
+) *StaleOrderSweeper {
+	return &StaleOrderSweeper{
+		orderRepo: repo,
+		maxAge:    maxAge,
+		expire:    expire,
+	}
+}
+
+func (s *StaleOrderSweeper) Sweep(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	createdBefore := time.Now().Add(-s.maxAge)
+
+	orders, err := s.orderRepo.FlagStale(ctx, createdBefore)
+	if err != nil {
+		return err
+	}
+
+	if len(orders) > 0 {
+		metrics.AddStaleOrdersFlagged(len(orders))
+
+		numbers := make([]string, 0, len(orders))
+		for _, o := range orders {
+			numbers = append(numbers, o.Number)
+		}
+
+		log.With(
+			"event", "stale_orders_detected",
+			"count", len(orders),
+			"orders", numbers,
+		).Warn("orders stuck in non-terminal status")
+	}
+
+	if !s.expire {
+		return nil
+	}
+
+	// Orders flagged by earlier sweeps are expired as well.
+	expired, err := s.orderRepo.Expire(ctx, createdBefore)
+	if err != nil {
+		return err
+	}
+
+	if expired > 0 {
+		log.With("event", "stale_orders_expired", "count", expired).Warn("stale orders expired")
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStaleOrderSweeper_Sweep(t *testing.T) {
+	staleOrders := []*model.Order{
+		{ID: uuid.New(), Number: "79927398713", Status: model.OrderStatusNew},
+		{ID: uuid.New(), Number: "12345678903", Status: model.OrderStatusProcessing},
+	}
+
+	t.Run("flags stale orders without expiring", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		orderRepo := mocks.NewMockOrderRepository(ctrl)
+		s := NewStaleOrderSweeper(orderRepo, time.Hour, false)
+
+		orderRepo.EXPECT().
+			FlagStale(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, createdBefore time.Time) ([]*model.Order, error) {
+				assert.WithinDuration(t, time.Now().Add(-time.Hour), createdBefore, time.Second)
+				return staleOrders, nil
+			}).
+			Times(1)
+		orderRepo.EXPECT().Expire(gomock.Any(), gomock.Any()).Times(0)
+
+		assert.NoError(t, s.Sweep(context.Background()))
+	})
+
+	t.Run("expires stale orders when enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		orderRepo := mocks.NewMockOrderRepository(ctrl)
+		s := NewStaleOrderSweeper(orderRepo, time.Hour, true)
+
+		var flaggedBefore time.Time
+		orderRepo.EXPECT().
+			FlagStale(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, createdBefore time.Time) ([]*model.Order, error) {
+				flaggedBefore = createdBefore
+				return staleOrders, nil
+			}).
+			Times(1)
+		orderRepo.EXPECT().
+			Expire(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, createdBefore time.Time) (int, error) {
+				assert.Equal(t, flaggedBefore, createdBefore)
+				return 2, nil
+			}).
+			Times(1)
+
+		assert.NoError(t, s.Sweep(context.Background()))
+	})
+
+	t.Run("expires orders flagged by earlier sweeps", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		orderRepo := mocks.NewMockOrderRepository(ctrl)
+		s := NewStaleOrderSweeper(orderRepo, time.Hour, true)
+
+		// Nothing new to flag, but orders left unexpired by a failed sweep or
+		// flagged while expiry was off are still expired.
+		orderRepo.EXPECT().FlagStale(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+		orderRepo.EXPECT().Expire(gomock.Any(), gomock.Any()).Return(3, nil).Times(1)
+
+		assert.NoError(t, s.Sweep(context.Background()))
+	})
+
+	t.Run("nothing to do without stale orders", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		orderRepo := mocks.NewMockOrderRepository(ctrl)
+		s := NewStaleOrderSweeper(orderRepo, time.Hour, false)
+
+		orderRepo.EXPECT().FlagStale(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+
+		assert.NoError(t, s.Sweep(context.Background()))
+	})
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/webhook"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// WebhookBatchSize is the number of deliveries claimed and sent per poll.
+	WebhookBatchSize = 20
+
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+	// webhookResponseLimit bounds how much of a response body is drained so
+	// the connection can be reused.
+	webhookResponseLimit = 4 << 10
+	webhookUserAgent     = "gophermart-webhooks"
+)
+
+// WebhookDispatcher sends the events queued in the webhook outbox. Failed
+// deliveries are retried with exponential backoff until maxAttempts, every
+// attempt is logged.
+type WebhookDispatcher struct {
+	repo         repository.WebhookRepository
+	client       *http.Client
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+}
+
+func NewWebhookDispatcher(
+	repo repository.WebhookRepository,
+	pollInterval time.Duration,
+	timeout time.Duration,
+	batchSize int,
+	maxAttempts int,
+) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:         repo,
+		client:       &http.Client{Timeout: timeout},
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+func (d *WebhookDispatcher) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	log.Info("webhook dispatcher started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("webhook dispatcher stopped")
+			return nil
+		case <-ticker.C:
+			if err := d.dispatchBatch(ctx); err != nil {
+				log.With("err", err.Error()).Error()
+			}
+		}
+	}
+}
+
+func (d *WebhookDispatcher) dispatchBatch(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	tx, err := d.repo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	jobs, err := d.repo.ClaimDueTx(ctx, tx, d.batchSize)
+	if err != nil {
+		return err
+	}
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	attempts := make([]model.WebhookAttempt, len(jobs))
+
+	var g errgroup.Group
+	for i, job := range jobs {
+		g.Go(func() error {
+			attempts[i] = d.send(ctx, job)
+			return nil
+		})
+	}
+	g.Wait()
+
+	// Requests cut short by shutdown are not counted, the deliveries stay
+	// due and are sent again after the restart.
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	now := time.Now()
+	for i, job := range jobs {
+		delivery := d.nextState(attempts[i], now)
+		if err := d.repo.RecordAttemptTx(ctx, tx, delivery, attempts[i]); err != nil {
+			return err
+		}
+
+		if delivery.Status == model.WebhookDeliveryFailed {
+			log.With(
+				"delivery_id", job.DeliveryID,
+				"event", job.EventType,
+				"url", job.URL,
+				"attempts", delivery.Attempts,
+				"err", attempts[i].Error,
+			).Warn("webhook delivery failed")
+		}
+	}
+
+	return tx.Commit()
+}
+
+// send posts the payload signed with the subscription secret. The attempt
+// has no error if the receiver answered with a 2xx status.
+func (d *WebhookDispatcher) send(ctx context.Context, job model.WebhookJob) model.WebhookAttempt {
+	attempt := model.WebhookAttempt{
+		DeliveryID: job.DeliveryID,
+		Attempt:    job.Attempts + 1,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(job.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	start := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(webhook.HeaderID, job.EventID.String())
+	req.Header.Set(webhook.HeaderEvent, string(job.EventType))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(job.Secret, start, job.Payload))
+
+	resp, err := d.client.Do(req)
+	attempt.DurationMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+
+	attempt.StatusCode = &resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+
+	return attempt
+}
+
+// nextState returns the delivery after the attempt: delivered, scheduled
+// for a retry or failed for good.
+func (d *WebhookDispatcher) nextState(attempt model.WebhookAttempt, now time.Time) *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{
+		ID:            attempt.DeliveryID,
+		Status:        model.WebhookDeliveryPending,
+		Attempts:      attempt.Attempt,
+		NextAttemptAt: now,
+	}
+
+	switch {
+	case attempt.Error == "":
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+	case attempt.Attempt >= d.maxAttempts:
+		delivery.Status = model.WebhookDeliveryFailed
+	default:
+		delivery.NextAttemptAt = now.Add(webhookBackoff(attempt.Attempt))
+	}
+
+	return delivery
+}
+
+// webhookBackoff doubles the delay after every failed attempt.
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempt && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxBackoff)
+}
@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDispatcher_send(t *testing.T) {
+	const secret = "s3cret"
+
+	var received http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r.Header.Clone()
+
+		err := webhook.Verify(
+			secret,
+			r.Header.Get(webhook.HeaderTimestamp),
+			r.Header.Get(webhook.HeaderSignature),
+			body,
+			time.Now(),
+			5*time.Minute,
+		)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := NewWebhookDispatcher(nil, time.Second, time.Second, WebhookBatchSize, 3)
+	job := model.WebhookJob{
+		DeliveryID: uuid.New(),
+		EventID:    uuid.New(),
+		EventType:  model.WebhookOrderProcessed,
+		Attempts:   1,
+		Payload:    json.RawMessage(`{"type":"order.processed"}`),
+		URL:        srv.URL,
+		Secret:     secret,
+	}
+
+	t.Run("signed request is accepted", func(t *testing.T) {
+		attempt := d.send(t.Context(), job)
+
+		assert.Empty(t, attempt.Error)
+		require.NotNil(t, attempt.StatusCode)
+		assert.Equal(t, http.StatusNoContent, *attempt.StatusCode)
+		assert.Equal(t, 2, attempt.Attempt)
+		assert.Equal(t, job.EventID.String(), received.Get(webhook.HeaderID))
+		assert.Equal(t, "order.processed", received.Get(webhook.HeaderEvent))
+		assert.Equal(t, "application/json", received.Get("Content-Type"))
+	})
+
+	t.Run("receiver rejecting the signature fails the attempt", func(t *testing.T) {
+		wrong := job
+		wrong.Secret = "other"
+
+		attempt := d.send(t.Context(), wrong)
+
+		require.NotNil(t, attempt.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, *attempt.StatusCode)
+		assert.Equal(t, "unexpected status 401", attempt.Error)
+	})
+
+	t.Run("unreachable receiver", func(t *testing.T) {
+		gone := job
+		gone.URL = "http://127.0.0.1:1"
+
+		attempt := d.send(t.Context(), gone)
+
+		assert.Nil(t, attempt.StatusCode)
+		assert.NotEmpty(t, attempt.Error)
+	})
+}
+
+func TestWebhookDispatcher_nextState(t *testing.T) {
+	d := NewWebhookDispatcher(nil, time.Second, time.Second, WebhookBatchSize, 3)
+	now := time.Now()
+	id := uuid.New()
+
+	t.Run("success", func(t *testing.T) {
+		got := d.nextState(model.WebhookAttempt{DeliveryID: id, Attempt: 1}, now)
+
+		assert.Equal(t, model.WebhookDeliveryDelivered, got.Status)
+		assert.Equal(t, &now, got.DeliveredAt)
+	})
+
+	t.Run("failure is retried later", func(t *testing.T) {
+		got := d.nextState(model.WebhookAttempt{DeliveryID: id, Attempt: 2, Error: "boom"}, now)
+
+		assert.Equal(t, model.WebhookDeliveryPending, got.Status)
+		assert.Equal(t, 2, got.Attempts)
+		assert.Equal(t, now.Add(time.Minute), got.NextAttemptAt)
+		assert.Nil(t, got.DeliveredAt)
+	})
+
+	t.Run("last attempt fails the delivery", func(t *testing.T) {
+		got := d.nextState(model.WebhookAttempt{DeliveryID: id, Attempt: 3, Error: "boom"}, now)
+
+		assert.Equal(t, model.WebhookDeliveryFailed, got.Status)
+	})
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(1))
+	assert.Equal(t, 2*time.Minute, webhookBackoff(3))
+	assert.Equal(t, webhookMaxBackoff, webhookBackoff(20))
+	assert.Equal(t, webhookMaxBackoff, webhookBackoff(1000))
+}
@@ -3,53 +3,155 @@ package worker
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
-	"github.com/mrhyman/gophermart/internal/client"
+	"github.com/mrhyman/gophermart/api"
 	"github.com/mrhyman/gophermart/internal/logger"
+	"github.com/mrhyman/gophermart/internal/metrics"
 	"github.com/mrhyman/gophermart/internal/model"
 	"github.com/mrhyman/gophermart/internal/repository"
+	"github.com/mrhyman/gophermart/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
+// AccrualFetcher looks up order accruals in the accrual system.
+// It must be safe for concurrent use and enforce its own rate limit.
+type AccrualFetcher interface {
+	GetOrderAccrual(ctx context.Context, orderNumber string) (*api.AccrualResponse, error)
+	// Ready reports whether a lookup could be sent now, without waiting for
+	// a pause or an open circuit.
+	Ready() bool
+}
+
 type AccrualWorker struct {
 	orderRepo     repository.OrderRepository
 	userRepo      repository.UserRepository
-	accrualClient *client.AccrualClient
-	pollInterval  time.Duration
-	batchSize     int
-	poolSize      int
+	eventRepo     repository.EventRepository
+	accrualClient AccrualFetcher
+
+	mu           sync.RWMutex
+	settings     workerSettings
+	reconfigured chan struct{}
+	// heartbeat holds the unix nano time of the last finished poll of any worker.
+	heartbeat atomic.Int64
+}
+
+// workerSettings can be changed at runtime with Reconfigure.
+type workerSettings struct {
+	pollInterval time.Duration
+	batchSize    int
+	poolSize     int
+	concurrency  int
+}
+
+type accrualResult struct {
+	// ctx carries the span of the order, which is ended once the result is applied.
+	ctx   context.Context
+	span  trace.Span
+	order *model.Order
+	resp  *api.AccrualResponse
+	err   error
 }
 
 func NewAccrualWorker(
 	repo repository.OrderRepository,
 	userRepo repository.UserRepository,
-	accrualClient *client.AccrualClient,
+	eventRepo repository.EventRepository,
+	accrualClient AccrualFetcher,
 	pollInterval time.Duration,
 	batchSize int,
 	poolSize int,
+	concurrency int,
 ) *AccrualWorker {
-	return &AccrualWorker{
+	w := &AccrualWorker{
 		orderRepo:     repo,
 		userRepo:      userRepo,
+		eventRepo:     eventRepo,
 		accrualClient: accrualClient,
-		pollInterval:  pollInterval,
-		batchSize:     batchSize,
-		poolSize:      poolSize,
+		reconfigured:  make(chan struct{}, 1),
+	}
+	w.Reconfigure(pollInterval, batchSize, poolSize, concurrency)
+
+	return w
+}
+
+// Reconfigure applies new settings to a running worker: the pool is scaled
+// to poolSize and running workers pick up the rest on their next tick.
+func (w *AccrualWorker) Reconfigure(pollInterval time.Duration, batchSize, poolSize, concurrency int) {
+	w.mu.Lock()
+	w.settings = workerSettings{
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		poolSize:     poolSize,
+		concurrency:  max(concurrency, 1),
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.reconfigured <- struct{}{}:
+	default:
 	}
 }
 
+func (w *AccrualWorker) current() workerSettings {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.settings
+}
+
+// LastHeartbeat returns when a worker of the pool last finished a poll.
+func (w *AccrualWorker) LastHeartbeat() time.Time {
+	return time.Unix(0, w.heartbeat.Load())
+}
+
+func (w *AccrualWorker) beat() {
+	w.heartbeat.Store(time.Now().UnixNano())
+}
+
 func (w *AccrualWorker) Start(ctx context.Context) error {
-	g, ctx := errgroup.WithContext(ctx)
+	w.beat()
+
+	var wg sync.WaitGroup
+	var cancels []context.CancelFunc
+
+	scale := func(size int) {
+		for len(cancels) < size {
+			workerCtx, cancel := context.WithCancel(ctx)
+			workerID := len(cancels)
+			cancels = append(cancels, cancel)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.run(workerCtx, workerID)
+			}()
+		}
 
-	for i := 0; i < w.poolSize; i++ {
-		g.Go(func() error {
-			return w.run(ctx, i)
-		})
+		for len(cancels) > size {
+			last := len(cancels) - 1
+			cancels[last]()
+			cancels = cancels[:last]
+		}
 	}
 
-	return g.Wait()
+	scale(w.current().poolSize)
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case <-w.reconfigured:
+			scale(w.current().poolSize)
+		}
+	}
 }
 
 func (w *AccrualWorker) worker(ctx context.Context, workerID int, pollInterval time.Duration, batchSize int) {
@@ -74,7 +176,8 @@ func (w *AccrualWorker) worker(ctx context.Context, workerID int, pollInterval t
 func (w *AccrualWorker) run(ctx context.Context, workerID int) error {
 	log := logger.FromContext(ctx).With("worker_id", workerID)
 
-	ticker := time.NewTicker(w.pollInterval)
+	interval := w.current().pollInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	log.Info("accrual worker started")
@@ -85,9 +188,16 @@ func (w *AccrualWorker) run(ctx context.Context, workerID int) error {
 			log.Info("accrual worker stopped")
 			return nil
 		case <-ticker.C:
-			if err := w.processBatch(ctx, w.batchSize); err != nil {
+			settings := w.current()
+			if settings.pollInterval != interval {
+				interval = settings.pollInterval
+				ticker.Reset(interval)
+			}
+
+			if err := w.processBatch(ctx, settings.batchSize); err != nil {
 				log.With("err", err.Error()).Error()
 			}
+			w.beat()
 		}
 	}
 }
@@ -95,50 +205,216 @@ func (w *AccrualWorker) run(ctx context.Context, workerID int) error {
 func (w *AccrualWorker) processBatch(ctx context.Context, batchSize int) error {
 	log := logger.FromContext(ctx)
 
+	// A batch claimed now would keep its rows locked and a connection idle in
+	// transaction until the accrual system can be asked again.
+	if !w.accrualClient.Ready() {
+		log.Debug("accrual system unavailable, skipping poll")
+		return nil
+	}
+
 	tx, err := w.orderRepo.BeginTx(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	orders, err := w.orderRepo.GetForProcessing(ctx, tx, w.batchSize)
+	orders, err := w.orderRepo.GetForProcessing(ctx, tx, batchSize)
 
 	if err != nil {
 		return err
 	}
 
+	metrics.ObserveBatchSize(len(orders))
+
 	if len(orders) == 0 {
 		return nil
 	}
 
-	for _, order := range orders {
-		if err := w.processOrder(ctx, tx, order); err != nil {
-			log.With("order", order.Number, "err", err.Error()).Error()
+	// Only batches with work are traced, empty polls would drown everything else.
+	ctx, span := tracing.Start(ctx, "AccrualWorker.processBatch", trace.WithAttributes(
+		attribute.Int("batch.size", len(orders)),
+	))
+	defer span.End()
 
-			if errors.Is(err, model.ErrAccrualTooManyRequests) {
-				return err
+	ctx = logger.WithTraceContext(ctx)
+	log = logger.FromContext(ctx)
+
+	results := w.fetchAccruals(ctx, orders)
+	if err := w.recordAttempts(ctx, tx, results); err != nil {
+		for _, res := range results {
+			res.span.End()
+		}
+		tracing.Fail(span, err)
+		return err
+	}
+
+	if err := w.lockUsers(ctx, tx, results); err != nil {
+		for _, res := range results {
+			res.span.End()
+		}
+		tracing.Fail(span, err)
+		return err
+	}
+
+	var (
+		rateLimited bool
+		credited    int
+		finished    []*model.Order
+	)
+	for _, res := range results {
+		err := res.err
+		var outcome processResult
+		if err == nil {
+			outcome, err = w.processOrder(res.ctx, tx, res.order, res.resp)
+		}
+
+		if !errors.Is(err, model.ErrOrderNotRegistered) {
+			tracing.Fail(res.span, err)
+		}
+		res.span.End()
+
+		switch {
+		case err == nil:
+			credited += outcome.credited
+			if outcome.status != res.order.Status && outcome.status.IsTerminal() {
+				finished = append(finished, res.order)
 			}
+		case errors.Is(err, model.ErrOrderNotRegistered):
+			log.With("order", res.order.Number).Debug()
+		case errors.Is(err, model.ErrAccrualTooManyRequests), errors.Is(err, context.Canceled):
+			rateLimited = true
+		case errors.Is(err, model.ErrAccrualCircuitOpen):
+			log.With("order", res.order.Number).Debug(err.Error())
+		default:
+			log.With("order", res.order.Number, "err", err.Error()).Error()
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		tracing.Fail(span, err)
+		return err
+	}
+
+	metrics.AddPointsCredited(credited)
+	for _, order := range finished {
+		metrics.ObserveOrderLag(time.Since(order.CreatedAt))
+	}
+
+	if rateLimited && ctx.Err() == nil {
+		return model.ErrAccrualTooManyRequests
+	}
+
+	return nil
 }
 
-func (w *AccrualWorker) processOrder(ctx context.Context, tx *sqlx.Tx, order *model.Order) error {
-	log := logger.FromContext(ctx)
+// fetchAccruals looks up all orders of a claimed batch concurrently, at most
+// the configured concurrency at a time. Remaining lookups are cancelled as soon
+// as the accrual system asks to slow down. Every result carries an open span
+// for its order that the caller must end.
+func (w *AccrualWorker) fetchAccruals(ctx context.Context, orders []*model.Order) []accrualResult {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	accrualResp, err := w.accrualClient.GetOrderAccrual(ctx, order.Number)
-	if err != nil {
-		if errors.Is(err, model.ErrOrderNotRegistered) {
-			log.With("order", order.Number).Debug()
+	results := make([]accrualResult, len(orders))
+
+	var g errgroup.Group
+	g.SetLimit(w.current().concurrency)
+
+	for i, order := range orders {
+		orderCtx, span := tracing.Start(ctx, "AccrualWorker.processOrder", trace.WithAttributes(
+			attribute.String("order.number", order.Number),
+			attribute.String("order.status", string(order.Status)),
+		))
+		orderCtx = logger.WithTraceContext(orderCtx)
+		results[i] = accrualResult{ctx: orderCtx, span: span, order: order}
+
+		g.Go(func() error {
+			reqCtx := logger.WithinContext(trace.ContextWithSpan(fetchCtx, span), logger.FromContext(orderCtx))
+			resp, err := w.accrualClient.GetOrderAccrual(reqCtx, order.Number)
+			if errors.Is(err, model.ErrAccrualTooManyRequests) {
+				cancel()
+			}
+
+			results[i].resp, results[i].err = resp, err
 			return nil
+		})
+	}
+
+	g.Wait()
+
+	return results
+}
+
+// lockUsers locks the owners of the orders about to change in id order, so
+// the batch cannot deadlock with other transactions changing several users.
+// It runs after the accrual system answered, so no user stays locked while
+// the requests are in flight.
+func (w *AccrualWorker) lockUsers(ctx context.Context, tx *sqlx.Tx, results []accrualResult) error {
+	var userIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, res := range results {
+		if res.err != nil || seen[res.order.UserID] {
+			continue
 		}
+		seen[res.order.UserID] = true
+		userIDs = append(userIDs, res.order.UserID)
+	}
+
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	return w.orderRepo.LockUsersTx(ctx, tx, userIDs)
+}
+
+// recordAttempts counts a lookup for every order the accrual system was
+// actually asked about. Lookups skipped by an open circuit or cancelled
+// before they were sent are not counted.
+func (w *AccrualWorker) recordAttempts(ctx context.Context, tx *sqlx.Tx, results []accrualResult) error {
+	var ids []uuid.UUID
+	for _, res := range results {
+		if errors.Is(res.err, model.ErrAccrualCircuitOpen) || errors.Is(res.err, context.Canceled) {
+			continue
+		}
+		ids = append(ids, res.order.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	attempts, err := w.orderRepo.RecordAttemptsTx(ctx, tx, ids)
+	if err != nil {
 		return err
 	}
 
+	for _, res := range results {
+		if n, ok := attempts[res.order.ID]; ok {
+			res.order.Attempts = n
+		}
+	}
+
+	return nil
+}
+
+// processResult is what applying an accrual response did to an order.
+type processResult struct {
+	status model.OrderStatus
+	// credited is the accrual credited now, zero if it was credited before.
+	credited int
+}
+
+func (w *AccrualWorker) processOrder(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	order *model.Order,
+	accrualResp *api.AccrualResponse,
+) (processResult, error) {
+	log := logger.FromContext(ctx)
+
 	newStatus, err := model.MapAccrualStatusToOrderStatus(accrualResp.Status)
 	if err != nil {
-		return err
+		return processResult{}, err
 	}
 
 	var accrual int
@@ -148,28 +424,84 @@ func (w *AccrualWorker) processOrder(ctx context.Context, tx *sqlx.Tx, order *mo
 
 	log.With("before_update", "accrual", accrual, "status", newStatus).Debug()
 
-	if err := w.updateOrderAndBalance(ctx, tx, order, newStatus, accrual); err != nil {
-		return err
+	credited, err := w.updateOrderAndBalance(ctx, tx, order, newStatus, accrual)
+	if err != nil {
+		return processResult{}, err
 	}
 
 	log.With("order", order.Number, "status", newStatus, "accrual", accrual).Info()
-	return nil
+	return processResult{status: newStatus, credited: credited}, nil
 }
 
+// updateOrderAndBalance moves the order to newStatus, crediting the accrual
+// of processed orders. It returns the amount credited by this call.
 func (w *AccrualWorker) updateOrderAndBalance(
 	ctx context.Context,
 	tx *sqlx.Tx,
 	order *model.Order,
 	newStatus model.OrderStatus,
 	accrual int,
-) error {
+) (int, error) {
 	log := logger.FromContext(ctx)
 
-	if err := w.orderRepo.UpdateStatusTx(ctx, tx, order.ID, newStatus, accrual); err != nil {
-		return err
+	if newStatus == order.Status {
+		return 0, nil
+	}
+
+	if !order.Status.CanTransitionTo(newStatus) {
+		return 0, model.ErrInvalidStatusTransition
+	}
+
+	reported := accrual
+	fresh := false
+	if newStatus == model.OrderStatusProcessed {
+		credited, ok, err := w.userRepo.CreditAccrualTx(ctx, tx, order.ID, order.UserID, accrual)
+		if err != nil {
+			return 0, err
+		}
+		fresh = ok
+
+		if credited != accrual {
+			log.With(
+				"order", order.Number,
+				"credited", credited,
+				"reported", accrual,
+			).Warn("accrual already credited with a different amount, adjust it with POST /api/admin/orders/{number}/accrual")
+			accrual = credited
+		}
 	}
 
 	log.With("inside_update", "accrual", accrual, "status", newStatus).Debug()
 
-	return w.userRepo.AddBalanceTx(ctx, tx, order.UserID, accrual)
+	if err := w.orderRepo.UpdateStatusTx(ctx, tx, order.ID, newStatus, accrual); err != nil {
+		return 0, err
+	}
+
+	change := model.OrderStatusChange{
+		OrderID:   order.ID,
+		OldStatus: order.Status,
+		NewStatus: newStatus,
+		Accrual:   reported,
+		Attempt:   order.Attempts,
+	}
+	if err := w.orderRepo.AddStatusChangeTx(ctx, tx, change); err != nil {
+		return 0, err
+	}
+
+	if err := w.eventRepo.PublishOrderStatusTx(ctx, tx, order.ID); err != nil {
+		return 0, err
+	}
+
+	if newStatus != model.OrderStatusProcessed {
+		return 0, nil
+	}
+
+	if err := w.eventRepo.PublishBalanceTx(ctx, tx, order.UserID); err != nil {
+		return 0, err
+	}
+
+	if !fresh {
+		return 0, nil
+	}
+	return accrual, nil
 }
@@ -0,0 +1,281 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrhyman/gophermart/api"
+	"github.com/mrhyman/gophermart/internal/model"
+	"github.com/mrhyman/gophermart/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestWorker(ctrl *gomock.Controller) (*AccrualWorker, *mocks.MockOrderRepository, *mocks.MockUserRepository, *mocks.MockEventRepository) {
+	orderRepo := mocks.NewMockOrderRepository(ctrl)
+	userRepo := mocks.NewMockUserRepository(ctrl)
+	eventRepo := mocks.NewMockEventRepository(ctrl)
+	w := NewAccrualWorker(orderRepo, userRepo, eventRepo, nil, time.Second, 10, 1, 1)
+	return w, orderRepo, userRepo, eventRepo
+}
+
+func TestAccrualWorker_updateOrderAndBalance(t *testing.T) {
+	t.Run("processed order is credited once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		w, orderRepo, userRepo, eventRepo := newTestWorker(ctrl)
+
+		order := &model.Order{ID: uuid.New(), UserID: uuid.New(), Number: "79927398713", Status: model.OrderStatusProcessing, Attempts: 3}
+
+		userRepo.EXPECT().
+			CreditAccrualTx(gomock.Any(), gomock.Any(), order.ID, order.UserID, 500).
+			Return(500, true, nil).
+			Times(1)
+		orderRepo.EXPECT().
+			UpdateStatusTx(gomock.Any(), gomock.Any(), order.ID, model.OrderStatusProcessed, 500).
+			Return(nil).
+			Times(1)
+		orderRepo.EXPECT().
+			AddStatusChangeTx(gomock.Any(), gomock.Any(), model.OrderStatusChange{
+				OrderID:   order.ID,
+				OldStatus: model.OrderStatusProcessing,
+				NewStatus: model.OrderStatusProcessed,
+				Accrual:   500,
+				Attempt:   3,
+			}).
+			Return(nil).
+			Times(1)
+		eventRepo.EXPECT().PublishOrderStatusTx(gomock.Any(), gomock.Any(), order.ID).Return(nil).Times(1)
+		eventRepo.EXPECT().PublishBalanceTx(gomock.Any(), gomock.Any(), order.UserID).Return(nil).Times(1)
+
+		credited, err := w.updateOrderAndBalance(context.Background(), nil, order, model.OrderStatusProcessed, 500)
+		assert.NoError(t, err)
+		assert.Equal(t, 500, credited)
+	})
+
+	t.Run("already credited order keeps credited amount", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		w, orderRepo, userRepo, eventRepo := newTestWorker(ctrl)
+
+		order := &model.Order{ID: uuid.New(), UserID: uuid.New(), Number: "79927398713", Status: model.OrderStatusNew}
+
+		userRepo.EXPECT().
+			CreditAccrualTx(gomock.Any(), gomock.Any(), order.ID, order.UserID, 700).
+			Return(500, false, nil).
+			Times(1)
+		orderRepo.EXPECT().
+			UpdateStatusTx(gomock.Any(), gomock.Any(), order.ID, model.OrderStatusProcessed, 500).
+			Return(nil).
+			Times(1)
+		// History keeps the amount the accrual system reported.
+		orderRepo.EXPECT().
+			AddStatusChangeTx(gomock.Any(), gomock.Any(), gomock.Cond(func(c model.OrderStatusChange) bool {
+				return c.Accrual == 700
+			})).
+			Return(nil).
+			Times(1)
+		eventRepo.EXPECT().PublishOrderStatusTx(gomock.Any(), gomock.Any(), order.ID).Return(nil).Times(1)
+		eventRepo.EXPECT().PublishBalanceTx(gomock.Any(), gomock.Any(), order.UserID).Return(nil).Times(1)
+
+		// Nothing was credited now, so nothing is counted.
+		credited, err := w.updateOrderAndBalance(context.Background(), nil, order, model.OrderStatusProcessed, 700)
+		assert.NoError(t, err)
+		assert.Zero(t, credited)
+	})
+
+	t.Run("non processed status does not credit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		w, orderRepo, _, eventRepo := newTestWorker(ctrl)
+
+		order := &model.Order{ID: uuid.New(), UserID: uuid.New(), Status: model.OrderStatusNew}
+
+		orderRepo.EXPECT().
+			UpdateStatusTx(gomock.Any(), gomock.Any(), order.ID, model.OrderStatusInvalid, 0).
+			Return(nil).
+			Times(1)
+		orderRepo.EXPECT().
+			AddStatusChangeTx(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil).
+			Times(1)
+		// Only the order changed, the balance did not.
+		eventRepo.EXPECT().PublishOrderStatusTx(gomock.Any(), gomock.Any(), order.ID).Return(nil).Times(1)
+
+		credited, err := w.updateOrderAndBalance(context.Background(), nil, order, model.OrderStatusInvalid, 0)
+		assert.NoError(t, err)
+		assert.Zero(t, credited)
+	})
+
+	t.Run("unchanged status is a no-op", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		w, _, _, _ := newTestWorker(ctrl)
+
+		order := &model.Order{ID: uuid.New(), Status: model.OrderStatusProcessing}
+
+		_, err := w.updateOrderAndBalance(context.Background(), nil, order, model.OrderStatusProcessing, 0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("terminal status can not be left", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		w, _, _, _ := newTestWorker(ctrl)
+
+		order := &model.Order{ID: uuid.New(), Status: model.OrderStatusInvalid}
+
+		_, err := w.updateOrderAndBalance(context.Background(), nil, order, model.OrderStatusProcessed, 500)
+		assert.ErrorIs(t, err, model.ErrInvalidStatusTransition)
+	})
+}
+
+type fakeFetcher struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	paused      bool
+	fn          func(orderNumber string) (*api.AccrualResponse, error)
+}
+
+func (f *fakeFetcher) Ready() bool {
+	return !f.paused
+}
+
+func (f *fakeFetcher) GetOrderAccrual(ctx context.Context, orderNumber string) (*api.AccrualResponse, error) {
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+
+	for {
+		current := f.maxInFlight.Load()
+		if n <= current || f.maxInFlight.CompareAndSwap(current, n) {
+			break
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	return f.fn(orderNumber)
+}
+
+func TestAccrualWorker_fetchAccruals(t *testing.T) {
+	orders := make([]*model.Order, 0, 10)
+	for i := range 10 {
+		orders = append(orders, &model.Order{ID: uuid.New(), Number: fmt.Sprintf("order-%d", i)})
+	}
+
+	t.Run("respects concurrency limit and keeps order", func(t *testing.T) {
+		fetcher := &fakeFetcher{fn: func(number string) (*api.AccrualResponse, error) {
+			return &api.AccrualResponse{Order: number, Status: model.AccrualStatusProcessing}, nil
+		}}
+		w := NewAccrualWorker(nil, nil, nil, fetcher, time.Second, 10, 1, 3)
+
+		results := w.fetchAccruals(context.Background(), orders)
+
+		require.Len(t, results, len(orders))
+		for i, res := range results {
+			require.NoError(t, res.err)
+			assert.Equal(t, orders[i], res.order)
+			assert.Equal(t, orders[i].Number, res.resp.Order)
+		}
+		assert.LessOrEqual(t, fetcher.maxInFlight.Load(), int32(3))
+		assert.Greater(t, fetcher.maxInFlight.Load(), int32(1))
+	})
+
+	t.Run("too many requests cancels remaining lookups", func(t *testing.T) {
+		fetcher := &fakeFetcher{fn: func(number string) (*api.AccrualResponse, error) {
+			return nil, model.ErrAccrualTooManyRequests
+		}}
+		w := NewAccrualWorker(nil, nil, nil, fetcher, time.Second, 10, 1, 1)
+
+		results := w.fetchAccruals(context.Background(), orders)
+
+		assert.ErrorIs(t, results[0].err, model.ErrAccrualTooManyRequests)
+		for _, res := range results[1:] {
+			assert.ErrorIs(t, res.err, context.Canceled)
+		}
+	})
+}
+
+func TestAccrualWorker_processBatch(t *testing.T) {
+	t.Run("paused fetcher claims no batch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		orderRepo := mocks.NewMockOrderRepository(ctrl)
+		w := NewAccrualWorker(orderRepo, nil, nil, &fakeFetcher{paused: true}, time.Second, 10, 1, 1)
+
+		// No BeginTx: the mock fails the test on any call.
+		require.NoError(t, w.processBatch(context.Background(), 10))
+	})
+}
+
+func TestAccrualWorker_recordAttempts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	w, orderRepo, _, _ := newTestWorker(ctrl)
+
+	asked := &model.Order{ID: uuid.New()}
+	notRegistered := &model.Order{ID: uuid.New()}
+	skipped := &model.Order{ID: uuid.New(), Attempts: 2}
+	cancelled := &model.Order{ID: uuid.New()}
+
+	results := []accrualResult{
+		{order: asked, resp: &api.AccrualResponse{}},
+		{order: notRegistered, err: model.ErrOrderNotRegistered},
+		{order: skipped, err: model.ErrAccrualCircuitOpen},
+		{order: cancelled, err: context.Canceled},
+	}
+
+	orderRepo.EXPECT().
+		RecordAttemptsTx(gomock.Any(), gomock.Any(), []uuid.UUID{asked.ID, notRegistered.ID}).
+		Return(map[uuid.UUID]int{asked.ID: 1, notRegistered.ID: 5}, nil)
+
+	require.NoError(t, w.recordAttempts(context.Background(), nil, results))
+	assert.Equal(t, 1, asked.Attempts)
+	assert.Equal(t, 5, notRegistered.Attempts)
+	assert.Equal(t, 2, skipped.Attempts)
+}
+
+func TestAccrualWorker_lockUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	w, orderRepo, _, _ := newTestWorker(ctrl)
+
+	alice, bob := uuid.New(), uuid.New()
+	results := []accrualResult{
+		{order: &model.Order{UserID: alice}, resp: &api.AccrualResponse{}},
+		{order: &model.Order{UserID: bob}, err: model.ErrOrderNotRegistered},
+		{order: &model.Order{UserID: alice}, resp: &api.AccrualResponse{}},
+	}
+
+	orderRepo.EXPECT().LockUsersTx(gomock.Any(), gomock.Any(), []uuid.UUID{alice}).Return(nil)
+
+	require.NoError(t, w.lockUsers(context.Background(), nil, results))
+}
+
+func TestAccrualWorker_Reconfigure(t *testing.T) {
+	fetcher := &fakeFetcher{fn: func(number string) (*api.AccrualResponse, error) {
+		return &api.AccrualResponse{Order: number, Status: model.AccrualStatusProcessing}, nil
+	}}
+	w := NewAccrualWorker(nil, nil, nil, fetcher, time.Second, 10, 1, 5)
+
+	w.Reconfigure(2*time.Second, 20, 4, 1)
+
+	settings := w.current()
+	assert.Equal(t, 2*time.Second, settings.pollInterval)
+	assert.Equal(t, 20, settings.batchSize)
+	assert.Equal(t, 4, settings.poolSize)
+
+	orders := []*model.Order{{Number: "1"}, {Number: "2"}, {Number: "3"}}
+	w.fetchAccruals(context.Background(), orders)
+	assert.Equal(t, int32(1), fetcher.maxInFlight.Load())
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS accrual_adjustments;
+DROP TABLE IF EXISTS accrual_credits;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS accrual_credits (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_accrual_credits_user_id ON accrual_credits(user_id);
+
+CREATE TABLE IF NOT EXISTS accrual_adjustments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES accrual_credits(order_id) ON DELETE CASCADE,
+    old_amount INTEGER NOT NULL,
+    new_amount INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_accrual_adjustments_order_id ON accrual_adjustments(order_id);
+
+INSERT INTO accrual_credits (order_id, user_id, amount)
+SELECT id, user_id, accrual
+FROM orders
+WHERE status = 'PROCESSED' AND accrual > 0
+ON CONFLICT (order_id) DO NOTHING;
@@ -0,0 +1,4 @@
+-- Enum values can not be dropped, EXPIRED stays in order_status.
+DROP INDEX IF EXISTS idx_orders_flagged_at;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS flagged_at;
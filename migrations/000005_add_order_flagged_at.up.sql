@@ -0,0 +1,5 @@
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'EXPIRED';
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS flagged_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_flagged_at ON orders(flagged_at) WHERE flagged_at IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS import_job_id;
+
+DROP TABLE IF EXISTS import_jobs;
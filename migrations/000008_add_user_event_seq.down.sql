@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS user_event_seq;
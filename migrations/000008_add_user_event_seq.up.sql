@@ -0,0 +1,2 @@
+-- Ids of user events pushed through NOTIFY, shared by all replicas.
+CREATE SEQUENCE IF NOT EXISTS user_event_seq;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS events;
+ALTER TABLE users DROP COLUMN IF EXISTS event_seq;
@@ -0,0 +1,16 @@
+-- seq numbers the events of a user. Taking the next number locks the user
+-- row, so events of one user are committed in seq order.
+ALTER TABLE users ADD COLUMN event_seq BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    seq BIGINT NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (user_id, seq)
+);
+
+CREATE INDEX idx_events_unpublished ON events(id) WHERE published_at IS NULL;
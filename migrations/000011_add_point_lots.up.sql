@@ -0,0 +1,34 @@
+-- A lot is one credit of points. Withdrawals consume the oldest lots first,
+-- whatever is left of a lot expires at expires_at, NULL meaning never.
+CREATE TABLE IF NOT EXISTS point_lots (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    amount INTEGER NOT NULL,
+    remaining INTEGER NOT NULL CHECK (remaining >= 0),
+    credited_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_point_lots_open ON point_lots(user_id, credited_at, id) WHERE remaining > 0;
+CREATE INDEX idx_point_lots_due ON point_lots(expires_at) WHERE remaining > 0 AND expires_at IS NOT NULL;
+
+-- Debit entries written when the remainder of a lot expires.
+CREATE TABLE IF NOT EXISTS point_expirations (
+    id BIGSERIAL PRIMARY KEY,
+    lot_id BIGINT NOT NULL REFERENCES point_lots(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_point_expirations_user_id ON point_expirations(user_id);
+
+-- The age of points credited so far is unknown, so the current balances
+-- become lots that never expire.
+INSERT INTO point_lots (user_id, amount, remaining, credited_at)
+SELECT u.id, u.balance, u.balance, COALESCE(MIN(c.created_at), NOW())
+FROM users u
+LEFT JOIN accrual_credits c ON c.user_id = u.id
+WHERE u.balance > 0
+GROUP BY u.id, u.balance;
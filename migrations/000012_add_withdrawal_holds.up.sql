@@ -0,0 +1,15 @@
+-- Points reserved for an order until the withdrawal is confirmed or
+-- released. Only HELD holds before expires_at reduce the available balance.
+CREATE TABLE IF NOT EXISTS withdrawal_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_number VARCHAR(255) NOT NULL,
+    sum INTEGER NOT NULL CHECK (sum > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'HELD',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_withdrawal_holds_user_active ON withdrawal_holds(user_id) WHERE status = 'HELD';
+CREATE INDEX idx_withdrawal_holds_due ON withdrawal_holds(expires_at) WHERE status = 'HELD';
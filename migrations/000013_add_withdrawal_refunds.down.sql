@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_withdraws_order_id;
+DROP TABLE IF EXISTS withdrawal_refunds;
+ALTER TABLE withdraws DROP CONSTRAINT IF EXISTS withdraws_refunded_check;
+ALTER TABLE withdraws DROP COLUMN IF EXISTS refunded;
@@ -0,0 +1,17 @@
+-- Points returned for a withdrawal, e.g. when the shop order paid with them
+-- was cancelled. withdraws.refunded keeps the running total, so a refund
+-- never returns more than was withdrawn.
+ALTER TABLE withdraws ADD COLUMN IF NOT EXISTS refunded INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE withdraws ADD CONSTRAINT withdraws_refunded_check CHECK (refunded >= 0 AND refunded <= sum);
+
+CREATE TABLE IF NOT EXISTS withdrawal_refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    withdrawal_id UUID NOT NULL REFERENCES withdraws(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sum INTEGER NOT NULL CHECK (sum > 0),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_withdrawal_refunds_withdrawal_id ON withdrawal_refunds(withdrawal_id);
+CREATE INDEX idx_withdraws_order_id ON withdraws(order_id);
@@ -0,0 +1,16 @@
+-- Points moved between users. A sender can use an idempotency key once, so
+-- a retried request does not move the points twice.
+CREATE TABLE IF NOT EXISTS point_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sender_id UUID NOT NULL REFERENCES users(id),
+    recipient_id UUID NOT NULL REFERENCES users(id),
+    sum INTEGER NOT NULL CHECK (sum > 0),
+    note TEXT NOT NULL DEFAULT '',
+    idempotency_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (sender_id <> recipient_id),
+    UNIQUE (sender_id, idempotency_key)
+);
+
+CREATE INDEX idx_point_transfers_sender ON point_transfers(sender_id, created_at);
+CREATE INDEX idx_point_transfers_recipient ON point_transfers(recipient_id, created_at);